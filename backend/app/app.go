@@ -0,0 +1,130 @@
+// Package app はリポジトリ・ユースケース・LINE コントローラーを組み立てる。
+// cmd/api・cmd/worker・cmd/scheduler はここから必要なものだけを取り出して使う。
+package app
+
+import (
+	"net/http"
+
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"github.com/sirasu21/Logbook/backend/bot"
+	controllerLine "github.com/sirasu21/Logbook/backend/controller/LINE"
+	controller "github.com/sirasu21/Logbook/backend/controller/web"
+	"github.com/sirasu21/Logbook/backend/db"
+	"github.com/sirasu21/Logbook/backend/models"
+	repositoryLine "github.com/sirasu21/Logbook/backend/repository/LINE"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+	"github.com/sirasu21/Logbook/backend/router"
+	"github.com/sirasu21/Logbook/backend/scheduler"
+	usecaseLine "github.com/sirasu21/Logbook/backend/usecase/LINE"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+	"github.com/sirasu21/Logbook/backend/worker"
+)
+
+type App struct {
+	DB    *gorm.DB
+	Redis *redis.Client
+
+	lineRepo      repositoryLine.LineRepository
+	lineQueueRepo repositoryLine.LineQueueRepository
+
+	user       usecase.UserUsecase
+	audit      usecase.AuditUsecase
+	record     usecase.RecordUsecase
+	workout    usecase.WorkoutUsecase
+	workoutSet usecase.WorkoutSetUsecase
+	exercise   usecase.ExerciseUsecase
+	reminder   usecase.ReminderUsecase
+	settings   usecase.UserSettingsUsecase
+	template   usecase.TemplateUsecase
+	clone      usecase.WorkoutCloneUsecase
+	bodyMetric usecase.BodyMetricUsecase
+	trash      usecase.TrashUsecase
+	analytics  usecase.AnalyticsUsecase
+	calendar   usecase.CalendarUsecase
+	line       usecaseLine.LineUsecase
+
+	Line controllerLine.LineController
+}
+
+// New は DB・Redis・LINE Bot に接続して、どのエントリポイントでも使うものを組み立てる
+func New() *App {
+	gdb := db.InitDB()
+	rd := db.InitRedis()
+	client := bot.InitLineBot()
+
+	userRepo := repository.NewLineAuthRepository(http.DefaultClient, gdb)
+	workoutRepo := repository.NewWorkoutRepository(gdb)
+	workoutSetRepo := repository.NewWorkoutSetRepository(gdb)
+	exerciseRepo := repository.NewExerciseRepository(gdb)
+	reminderRepo := repository.NewReminderRepository(gdb)
+	templateRepo := repository.NewTemplateRepository(gdb)
+	recordRepo := repository.NewPersonalRecordRepository(gdb)
+	settingsRepo := repository.NewUserSettingsRepository(gdb)
+	analyticsRepo := repository.NewAnalyticsRepository(gdb)
+	bodyMetricRepo := repository.NewBodyMetricRepository(gdb)
+	trashRepo := repository.NewTrashRepository(gdb)
+	auditRepo := repository.NewAuditRepository(gdb)
+	lineRepo := repositoryLine.NewLineRepository(rd)
+	lineQueueRepo := repositoryLine.NewLineQueueRepository(rd)
+	restTimerRepo := repositoryLine.NewRestTimerRepository(rd)
+
+	a := &App{DB: gdb, Redis: rd, lineRepo: lineRepo, lineQueueRepo: lineQueueRepo}
+	transactor := repository.NewTransactor(gdb)
+	a.user = usecase.NewUserUsecase(userRepo)
+	a.audit = usecase.NewAuditUsecase(auditRepo, workoutRepo)
+	a.record = usecase.NewRecordUsecase(transactor, recordRepo, workoutSetRepo, exerciseRepo)
+	a.workout = usecase.NewWorkoutUsecase(transactor, workoutRepo, workoutSetRepo, a.record, a.audit)
+	a.workoutSet = usecase.NewWorkoutSetUsecase(transactor, workoutRepo, workoutSetRepo, exerciseRepo, a.record, a.audit)
+	a.exercise = usecase.NewExerciseUsecase(transactor, exerciseRepo, a.audit)
+	a.reminder = usecase.NewReminderUsecase(reminderRepo)
+	a.settings = usecase.NewUserSettingsUsecase(settingsRepo)
+	a.template = usecase.NewTemplateUsecase(transactor, templateRepo, exerciseRepo, workoutSetRepo, a.workout, a.audit)
+	a.clone = usecase.NewWorkoutCloneUsecase(transactor, a.workout, a.workoutSet)
+	a.bodyMetric = usecase.NewBodyMetricUsecase(transactor, bodyMetricRepo, a.audit)
+	a.trash = usecase.NewTrashUsecase(transactor, trashRepo, a.workout, a.workoutSet, a.exercise, a.bodyMetric, a.audit)
+	a.analytics = usecase.NewAnalyticsUsecase(analyticsRepo, exerciseRepo, a.settings)
+	a.calendar = usecase.NewCalendarUsecase(workoutRepo, a.settings)
+	a.line = usecaseLine.NewLineUsecase(lineRepo, lineQueueRepo, restTimerRepo)
+	summary := usecase.NewSummaryUsecase(workoutRepo, exerciseRepo)
+
+	a.Line = controllerLine.NewLineController(client, transactor, a.line, a.exercise, a.workout, a.user, a.workoutSet, summary, a.reminder, a.template, a.clone, a.settings)
+	return a
+}
+
+// Close は DB・Redis の接続を閉じる
+func (a *App) Close() {
+	_ = a.Redis.Close()
+	db.CloseDB(a.DB)
+}
+
+// Router は Web API のコントローラーを組み立ててルーティングする（cmd/api 用）
+func (a *App) Router(cfg models.Config) *echo.Echo {
+	userCtl := controller.NewUserController(cfg, a.user)
+	workoutCtl := controller.NewWorkoutController(cfg, a.workout, a.clone, a.settings)
+	workoutSetCtl := controller.NewWorkoutSetController(a.workoutSet, a.settings)
+	exerciseCtl := controller.NewExerciseController(cfg, a.exercise)
+	bodyCtl := controller.NewBodyMetricController(cfg, a.bodyMetric, a.settings)
+	reminderCtl := controller.NewReminderController(cfg, a.reminder)
+	templateCtl := controller.NewTemplateController(cfg, a.template, a.settings)
+	recordCtl := controller.NewRecordController(cfg, a.record)
+	analyticsCtl := controller.NewAnalyticsController(cfg, a.analytics)
+	calendarCtl := controller.NewCalendarController(cfg, a.calendar)
+	settingsCtl := controller.NewUserSettingsController(cfg, a.settings)
+	trashCtl := controller.NewTrashController(cfg, a.trash, a.settings)
+	auditCtl := controller.NewAuditController(cfg, a.audit)
+
+	return router.NewRouter(cfg, a.DB, userCtl, workoutCtl, workoutSetCtl, exerciseCtl, bodyCtl, reminderCtl, templateCtl, recordCtl, analyticsCtl, calendarCtl, settingsCtl, trashCtl, auditCtl, a.Line)
+}
+
+// Worker は LINE Webhook イベントのワーカープール
+func (a *App) Worker(opts worker.Options) *worker.Pool {
+	return worker.NewPool(a.lineQueueRepo, a.Line.HandleQueuedEvent, opts)
+}
+
+// Scheduler はリマインド・自動終了・休憩タイマー・ゴミ箱掃除のスケジューラー
+func (a *App) Scheduler() *scheduler.Scheduler {
+	return scheduler.New(a.reminder, a.workout, a.Line, a.lineRepo, a.line, a.trash)
+}
@@ -2,51 +2,46 @@
 package main
 
 import (
-	"net/http"
+	"context"
+	"log"
+	"os"
+	_ "time/tzdata" // コンテナに zoneinfo が無くてもユーザーのタイムゾーンを読めるように
 
 	"github.com/joho/godotenv"
 
+	"github.com/sirasu21/Logbook/backend/app"
 	"github.com/sirasu21/Logbook/backend/bot"
-	controllerLine "github.com/sirasu21/Logbook/backend/controller/LINE"
-	controller "github.com/sirasu21/Logbook/backend/controller/web"
-	"github.com/sirasu21/Logbook/backend/db"
-	repositoryLine "github.com/sirasu21/Logbook/backend/repository/LINE"
-	repository "github.com/sirasu21/Logbook/backend/repository/web"
-	"github.com/sirasu21/Logbook/backend/router"
-	usecaseLine "github.com/sirasu21/Logbook/backend/usecase/LINE"
-	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+	"github.com/sirasu21/Logbook/backend/scheduler"
+	"github.com/sirasu21/Logbook/backend/worker"
 )
 
 func main() {
 	_ = godotenv.Load()
 	cfg := bot.LoadConfig()
-	gdb := db.InitDB()
-	client := bot.InitLineBot()
-	rd := db.InitRedis()
-
-	userRepo := repository.NewLineAuthRepository(http.DefaultClient, gdb)
-	workoutRepo := repository.NewWorkoutRepository(gdb)
-	workoutSetRepo := repository.NewWorkoutSetRepository(gdb)
-	exerciseRepo := repository.NewExerciseRepository(gdb)
-	bodyMetricRepo := repository.NewBodyMetricRepository(gdb)
-	lineRepo := repositoryLine.NewLineRepository(rd)
-
-	userUC := usecase.NewUserUsecase(userRepo)
-	workoutUC := usecase.NewWorkoutUsecase(workoutRepo, workoutSetRepo)
-	workoutSetUC := usecase.NewWorkoutSetUsecase(workoutRepo, workoutSetRepo, exerciseRepo)
-	exerciseUC := usecase.NewExerciseUsecase(exerciseRepo)
-	bodyMetricUC := usecase.NewBodyMetricUsecase(bodyMetricRepo)
-	lineUC := usecaseLine.NewLineUsecase(lineRepo)
-
-	userCtl := controller.NewUserController(cfg, userUC)
-	workoutCtl := controller.NewWorkoutController(cfg, workoutUC)
-	workoutSetCtl := controller.NewWorkoutSetController(workoutSetUC)
-	exerciseCtl := controller.NewExerciseController(cfg, exerciseUC)
-	bodyCtl := controller.NewBodyMetricController(cfg, bodyMetricUC)
-
-	lineCtl := controllerLine.NewLineController(client, lineUC, exerciseUC, workoutUC, userUC, workoutSetUC)
-
-	e := router.NewRouter(cfg, gdb, userCtl, workoutCtl, workoutSetCtl, exerciseCtl, bodyCtl, lineCtl)
+	a := app.New()
+	defer a.Close()
+
+	// LINE_WORKER_MODE=external のときは cmd/worker を別プロセスで動かす
+	if os.Getenv("LINE_WORKER_MODE") != "external" {
+		pool := a.Worker(worker.OptionsFromEnv())
+		go func() {
+			if err := pool.Run(context.Background()); err != nil {
+				log.Printf("❌ LINE worker stopped: %v", err)
+			}
+		}()
+	}
+
+	// SCHEDULER_MODE=external のときは cmd/scheduler を別プロセスで動かす（off なら送信しない）
+	if scheduler.EnabledInProcess() {
+		sch := a.Scheduler()
+		go func() {
+			if err := sch.Run(context.Background()); err != nil {
+				log.Printf("❌ scheduler stopped: %v", err)
+			}
+		}()
+	}
+
+	e := a.Router(cfg)
 
 	e.Logger.Fatal(e.Start(cfg.Addr))
 }
@@ -1,15 +1,106 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/sirasu21/Logbook/backend/db"
 	"github.com/sirasu21/Logbook/backend/models"
 )
 
+// 使い方: migrate [up|down [n]|status|redo]（省略時は up）
 func main() {
 	dbConn := db.InitDB()
-	defer fmt.Println("Successfully Migrated")
 	defer db.CloseDB(dbConn)
-	dbConn.AutoMigrate(&models.User{}, &models.Exercise{}, &models.Workout{}, &models.WorkoutSet{}, &models.BodyMetric{})
+
+	m, err := db.NewMigrator(dbConn)
+	if err != nil {
+		log.Fatalf("load migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	cmd := "up"
+	if len(os.Args) > 1 {
+		cmd = os.Args[1]
+	}
+	switch cmd {
+	case "up":
+		done, err := m.Up(ctx)
+		for _, mg := range done {
+			fmt.Printf("applied %04d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			log.Fatalf("migrate up: %v", err)
+		}
+		if len(done) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		if err := syncData(dbConn); err != nil {
+			log.Fatalf("migrate up: %v", err)
+		}
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			if steps, err = strconv.Atoi(os.Args[2]); err != nil || steps < 1 {
+				log.Fatalf("migrate down: steps must be a positive number: %q", os.Args[2])
+			}
+		}
+		done, err := m.Down(ctx, steps)
+		for _, mg := range done {
+			fmt.Printf("reverted %04d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			log.Fatalf("migrate down: %v", err)
+		}
+	case "status":
+		items, err := m.Status(ctx)
+		if err != nil {
+			log.Fatalf("migrate status: %v", err)
+		}
+		for _, st := range items {
+			state := "pending"
+			if st.AppliedAt != nil {
+				state = "applied " + st.AppliedAt.Format(time.RFC3339)
+			}
+			if st.Modified {
+				state += " (modified)"
+			}
+			if st.Up == "" {
+				state += " (file missing)"
+			}
+			fmt.Printf("%04d_%-30s %s\n", st.Version, st.Name, state)
+		}
+	case "redo":
+		mg, err := m.Redo(ctx)
+		if err != nil {
+			log.Fatalf("migrate redo: %v", err)
+		}
+		fmt.Printf("redone %04d_%s\n", mg.Version, mg.Name)
+	default:
+		log.Fatalf("unknown command %q (up, down [n], status, redo)", cmd)
+	}
+}
+
+// syncData はスキーマのあとに流すデータの整備（何度流しても同じ結果になる）。
+// 一度だけ流せばよいデータの移行は db/migrations に番号付きで置く
+func syncData(db *gorm.DB) error {
+	if err := seedMuscleGroups(db); err != nil {
+		return fmt.Errorf("seed muscle groups: %w", err)
+	}
+	return nil
+}
+
+// seedMuscleGroups は部位の一覧を models.MuscleGroups に合わせる
+func seedMuscleGroups(db *gorm.DB) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "region", "position"}),
+	}).Create(&models.MuscleGroups).Error
 }
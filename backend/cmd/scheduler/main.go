@@ -0,0 +1,29 @@
+// LINE のリマインド送信（スケジューラー）だけを起動するエントリポイント
+// （API 側は SCHEDULER_MODE=external で起動する）
+package main
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+	_ "time/tzdata" // コンテナに zoneinfo が無くてもユーザーのタイムゾーンを読めるように
+
+	"github.com/joho/godotenv"
+
+	"github.com/sirasu21/Logbook/backend/app"
+)
+
+func main() {
+	_ = godotenv.Load()
+	a := app.New()
+	defer a.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := a.Scheduler().Run(ctx); err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("scheduler stopped")
+}
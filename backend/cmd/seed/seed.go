@@ -21,6 +21,7 @@ func main() {
 			Type:          models.ExerciseTypeStrength,
 			PrimaryMuscle: stringPtr("背中"),
 			IsActive:      true,
+			Muscles:       muscles(models.MuscleBack, models.MuscleHamstrings, models.MuscleGlutes),
 		},
 		{
 			ID:            "6e1ee985-7bbe-45d6-b4c6-d2f7892ded8d",
@@ -29,6 +30,7 @@ func main() {
 			Type:          models.ExerciseTypeStrength,
 			PrimaryMuscle: stringPtr("胸"),
 			IsActive:      true,
+			Muscles:       muscles(models.MuscleChest, models.MuscleTriceps, models.MuscleShoulders),
 		},
 		{
 			ID:            "b9d17a4f-20ba-4983-88fb-2fefe713e132",
@@ -37,6 +39,7 @@ func main() {
 			Type:          models.ExerciseTypeStrength,
 			PrimaryMuscle: stringPtr("背中"),
 			IsActive:      true,
+			Muscles:       muscles(models.MuscleBack, models.MuscleBiceps),
 		},
 		{
 			ID:            "d2aa8df7-d422-4f87-b103-dd10ef9b1838",
@@ -45,6 +48,7 @@ func main() {
 			Type:          models.ExerciseTypeStrength,
 			PrimaryMuscle: stringPtr("肩"),
 			IsActive:      true,
+			Muscles:       muscles(models.MuscleShoulders, models.MuscleTriceps),
 		},
 		{
 			ID:            "de1ed478-9973-4c7c-b623-f4562f11e29e",
@@ -53,6 +57,7 @@ func main() {
 			Type:          models.ExerciseTypeStrength,
 			PrimaryMuscle: stringPtr("脚"),
 			IsActive:      true,
+			Muscles:       muscles(models.MuscleQuads, models.MuscleGlutes, models.MuscleHamstrings),
 		},
 	}
 
@@ -72,6 +77,15 @@ func main() {
 	fmt.Println("\nSeed completed successfully!")
 }
 
+// muscles は最初の部位を主動筋、残りを協働筋（既定の寄与率）にする
+func muscles(primary string, secondary ...string) []models.ExerciseMuscle {
+	out := []models.ExerciseMuscle{{Muscle: primary, Role: models.MuscleRolePrimary, Contribution: models.DefaultPrimaryContribution}}
+	for _, m := range secondary {
+		out = append(out, models.ExerciseMuscle{Muscle: m, Role: models.MuscleRoleSecondary, Contribution: models.DefaultSecondaryContribution})
+	}
+	return out
+}
+
 // stringPtr はstring値のポインタを返すヘルパー関数
 func stringPtr(s string) *string {
 	return &s
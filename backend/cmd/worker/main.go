@@ -0,0 +1,30 @@
+// LINE Webhook イベントのワーカーだけを起動するエントリポイント
+// （API 側は LINE_WORKER_MODE=external で起動する）
+package main
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+	_ "time/tzdata" // コンテナに zoneinfo が無くてもユーザーのタイムゾーンを読めるように
+
+	"github.com/joho/godotenv"
+
+	"github.com/sirasu21/Logbook/backend/app"
+	"github.com/sirasu21/Logbook/backend/worker"
+)
+
+func main() {
+	_ = godotenv.Load()
+	a := app.New()
+	defer a.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := a.Worker(worker.OptionsFromEnv()).Run(ctx); err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("LINE worker stopped")
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+
+	"github.com/sirasu21/Logbook/backend/lineflow"
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+// 「前回と同じメニュー」での開始
+
+var cloneChoicesList = []struct {
+	Label       string
+	Progression models.Progression
+}{
+	{"前回と同じ", models.ProgressionNone},
+	{"+2.5kg / +5lb", models.ProgressionWeight},
+	{"+1回", models.ProgressionReps},
+}
+
+// cloneChoices は最後に終了したワークアウトを見せて、進め方を選んでもらう
+func (l *lineController) cloneChoices(ctx context.Context, uid string) ([]linebot.SendingMessage, error) {
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	last, err := l.cloneuc.LatestFinished(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, userError("前回のワークアウトがありません。「開始」から記録してください")
+	}
+
+	var buttons []*linebot.QuickReplyButton
+	for _, c := range cloneChoicesList {
+		v := url.Values{"action": {string(lineflow.TriggerCloneWorkout)}}
+		workoutSource{CloneOf: last.ID, Progression: c.Progression}.addTo(v)
+		buttons = append(buttons, linebot.NewQuickReplyButton("", linebot.NewPostbackAction(c.Label, v.Encode(), "", c.Label)))
+	}
+	text := fmt.Sprintf("%s のワークアウトと同じメニューで始めます。\n重量・回数はどうしますか？（+2.5kg（lb で記録したセットは +5lb） / +1回 はウォームアップ以外のセット）", formatClock(last.StartedAt, l.userSettings(ctx, user.ID).Location()))
+	return []linebot.SendingMessage{
+		linebot.NewTextMessage(text).WithQuickReplies(linebot.NewQuickReplyItems(buttons...)),
+	}, nil
+}
+
+// beginClonedWorkout は前回のワークアウトを予定のセットとして複製して開始する
+func (l *lineController) beginClonedWorkout(ctx context.Context, user *models.User, step *lineflow.Step, workoutID string, p models.Progression) ([]linebot.SendingMessage, error) {
+	d, err := l.cloneuc.Clone(ctx, user.ID, workoutID, models.CloneWorkoutInput{Progression: p}, true)
+	if err != nil {
+		if usecase.IsNotFound(err) {
+			return nil, userError("前回のワークアウトが見つかりません")
+		}
+		return nil, err
+	}
+	step.State.WorkoutID = d.Workout.ID
+	return withMenu("add", l.plannedSetsMessage(ctx, user.ID, "前回のメニューで開始しました！", d.Sets)), nil
+}
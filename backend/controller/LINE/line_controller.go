@@ -3,13 +3,13 @@ package controller
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/http"
+	"net/url"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -17,299 +17,311 @@ import (
 
 	"github.com/sirasu21/Logbook/backend/lineflow"
 	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
 	usecaseLine "github.com/sirasu21/Logbook/backend/usecase/LINE"
 	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
 )
 
-type LineWorkoutState struct {
-	State     string    `json:"state"`     // 例: "in_workout"
-	WorkoutID string    `json:"workoutId"` // DBのworkout.id
-	StartedAt time.Time `json:"startedAt"`
-}
-
-type LineWorkoutSet struct {
-	ExerciseID  string    `json:"exerciseId"`
-	Weight      *float64  `json:"weight,omitempty"`
-	Repetitions *int      `json:"repetitions,omitempty"`
-	Sets        *int      `json:"sets,omitempty"` // 必要なら今後追加ボタンで
-	WorkoutID   string    `json:"workoutId"`      // 直近のLINEワークアウト
-	StartedAt   time.Time `json:"startedAt"`
-}
-
-const workoutStateTTL = 2 * time.Hour
-
-func stateKey(lineUserID string) string {
-	return "line:workout:" + lineUserID
-}
-
-func marshalState(st LineWorkoutState) ([]byte, error)     { return json.Marshal(st) }
-func unmarshalState(b []byte, dst *LineWorkoutState) error { return json.Unmarshal(b, dst) }
-
-const stateTTL = 45 * time.Minute
-
 type LineController interface {
 	Webhook(c echo.Context) error
+	HandleQueuedEvent(ctx context.Context, payload []byte) error
+
+	// スケジューラーから呼ばれるプッシュ通知
+	PushTrainingReminder(ctx context.Context, lineUserID string) error
+	PushInactiveNudge(ctx context.Context, lineUserID string, days int) error
+	PushWeeklyRecap(ctx context.Context, lineUserID, userID string) error
+	PushWorkoutAutoClosed(ctx context.Context, w models.AbandonedWorkout) error
+	PushRestOver(ctx context.Context, t models.RestTimer) error
 }
 
 type lineController struct {
 	bot          *linebot.Client
+	tx           repository.Transactor
 	lineuc       usecaseLine.LineUsecase
 	exerciseuc   usecase.ExerciseUsecase
 	workoutuc    usecase.WorkoutUsecase
 	useruc       usecase.UserUsecase
 	workoutSetuc usecase.WorkoutSetUsecase
+	summaryuc    usecase.SummaryUsecase
+	reminderuc   usecase.ReminderUsecase
+	templateuc   usecase.TemplateUsecase
+	cloneuc      usecase.WorkoutCloneUsecase
+	settingsuc   usecase.UserSettingsUsecase
+	flow         *lineflow.Engine
 }
 
-func NewLineController(bot *linebot.Client, lineuc usecaseLine.LineUsecase, exerciseuc usecase.ExerciseUsecase, workoutuc usecase.WorkoutUsecase, useruc usecase.UserUsecase, workoutSetuc usecase.WorkoutSetUsecase) LineController {
-	return &lineController{bot: bot, lineuc: lineuc, exerciseuc: exerciseuc, workoutuc: workoutuc, useruc: useruc, workoutSetuc: workoutSetuc}
+func NewLineController(bot *linebot.Client, tx repository.Transactor, lineuc usecaseLine.LineUsecase, exerciseuc usecase.ExerciseUsecase, workoutuc usecase.WorkoutUsecase, useruc usecase.UserUsecase, workoutSetuc usecase.WorkoutSetUsecase, summaryuc usecase.SummaryUsecase, reminderuc usecase.ReminderUsecase, templateuc usecase.TemplateUsecase, cloneuc usecase.WorkoutCloneUsecase, settingsuc usecase.UserSettingsUsecase) LineController {
+	return &lineController{bot: bot, tx: tx, lineuc: lineuc, exerciseuc: exerciseuc, workoutuc: workoutuc, useruc: useruc, workoutSetuc: workoutSetuc, summaryuc: summaryuc, reminderuc: reminderuc, templateuc: templateuc, cloneuc: cloneuc, settingsuc: settingsuc, flow: lineflow.NewEngine(lineuc)}
 }
 
 func (l *lineController) Webhook(c echo.Context) error {
-	events, err := l.bot.ParseRequest(c.Request())
+	events, err := l.parseWebhook(c.Request())
 	if err != nil {
+		if errors.Is(err, linebot.ErrInvalidSignature) {
+			return c.NoContent(http.StatusBadRequest)
+		}
 		return err
 	}
-	for _, event := range events {
-		switch event.Type {
-		// 初回登録時
-		case linebot.EventTypeFollow:
-			if err := l.CreateUser(event); err != nil {
-				l.replyText(event.ReplyToken, fmt.Sprintf("登録時にエラーが発生しました: %v", err))
-				l.pushStartMenu(event.Source.UserID)
-				continue
-			}
-			l.replyText(event.ReplyToken, "登録しました！「開始」「終了」ボタン（またはメッセージ）でどうぞ💪")
+	// 処理はワーカーに任せてすぐ 200 を返す（LINE の Webhook タイムアウト対策）。
+	// 積めなかった場合は 500 で LINE に再送させる（積み済みのものは webhookEventId で重複排除される）
+	for _, ev := range events {
+		if err := l.lineuc.EnqueueEvent(c.Request().Context(), ev.orderingKey(), ev.Raw); err != nil {
+			log.Printf("❌ LINE イベントのキュー投入失敗 / eventID=%s / err=%v", ev.WebhookEventID, err)
+			return c.NoContent(http.StatusInternalServerError)
+		}
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// handleEvent は 1 イベント分の処理。userError は呼び出し側でユーザーに返信される。
+// eventID は変更履歴のリクエスト ID にする
+func (l *lineController) handleEvent(event *linebot.Event, eventID string) error {
+	ctx := usecase.WithAuditSource(context.Background(), models.AuditSourceLine, eventID)
+	switch event.Type {
+	// 初回登録時
+	case linebot.EventTypeFollow:
+		if err := l.CreateUser(event); err != nil {
+			l.replyText(event.ReplyToken, fmt.Sprintf("登録時にエラーが発生しました: %v", err))
 			l.pushStartMenu(event.Source.UserID)
-		case linebot.EventTypeMessage:
-			l.handleText(event)
-
-		case linebot.EventTypePostback:
-			ctx := context.Background()
-			uid := event.Source.UserID
-			s, _ := lineflow.LoadState(ctx, l.lineuc, uid)
-			switch event.Postback.Data {
-
-
-
-			case "action=start":
-				if err := l.createWorkout(event); err != nil {
-					return err
-				}
-				l.replyText(event.ReplyToken, "ワークアウトを開始しました！")
-				l.pushAddMenu(event.Source.UserID)
-
-			case "action=end":
-				if err := l.endWorkout(event); err != nil {
-					return err
-				}
-				l.replyText(event.ReplyToken, "ワークアウトを終了しました！")
-				l.pushStartMenu(event.Source.UserID)
-			case "action=add":
-				// 最新 LINE 由来ワークアウトIDを取得
-				user, err := l.getOrCreateUser(ctx, uid)
-				if err != nil {
-					return err
-				}
-				wid, err := l.workoutuc.GetLatestLineWorkoutID(ctx, user.ID, true)
-				if err != nil || wid == "" {
-					return errors.New("まず「開始」してください")
-				}
-				s.WorkoutID = wid
-				s.Pending = lineflow.Pending{}
-				s.State = lineflow.StateAddExercise
-				_ = lineflow.SaveState(ctx, l.lineuc, uid, s, stateTTL)
-				l.replyText(event.ReplyToken, "種目IDを送ってください（例: 11111111-....）")
-				l.pushExerciseListMenu(event.Source.UserID)
-			case "action=exercise":
-				s.State = lineflow.StateAddExercise
-				_ = lineflow.SaveState(ctx, l.lineuc, uid, s, stateTTL)
-				l.replyText(event.ReplyToken, "種目IDを送ってください")
-
-			case "action=weight":
-				s.State = lineflow.StateAddWeight
-				_ = lineflow.SaveState(ctx, l.lineuc, uid, s, stateTTL)
-				l.replyText(event.ReplyToken, "重量(kg)を送ってください（例: 60）")
-
-			case "action=count":
-				s.State = lineflow.StateAddCount
-				_ = lineflow.SaveState(ctx, l.lineuc, uid, s, stateTTL)
-				l.replyText(event.ReplyToken, "回数を送ってください（例: 8）")
-
-			case "action=cancel":
-				lineflow.ClearState(ctx, l.lineuc, uid)
-				l.replyText(event.ReplyToken, "キャンセルしました。『追加』からやり直してください")
-				l.pushAddMenu(event.Source.UserID)
-
-
-			default:
-				l.replyText(event.ReplyToken, "未対応の操作です")
-				l.pushStartMenu(event.Source.UserID)
-			}
+			return nil
+		}
+		l.replyText(event.ReplyToken, "登録しました！「開始」「終了」ボタン（またはメッセージ）でどうぞ💪")
+		l.pushStartMenu(event.Source.UserID)
 
+	case linebot.EventTypeMessage:
+		msg, ok := event.Message.(*linebot.TextMessage)
+		if !ok {
+			return nil
 		}
+		s := l.flow.Load(ctx, event.Source.UserID)
+		in, err := lineflow.ClassifyText(s, msg.Text)
+		if err != nil {
+			return userError(err.Error())
+		}
+		return l.dispatch(ctx, event, in)
+
+	case linebot.EventTypePostback:
+		pb, _ := url.ParseQuery(event.Postback.Data)
+		return l.dispatch(ctx, event, lineflow.Input{Trigger: lineflow.Trigger(pb.Get("action")), Params: pb})
 	}
 
 	return nil
 }
 
-func (l *lineController) createWorkout(event *linebot.Event) error {
-	if event.Source == nil || event.Source.UserID == "" {
-		return errors.New("user id not found in event")
+// dispatch は会話エンジンで遷移を決め、副作用を実行してから状態を保存して返信する。
+// 受け付けられない入力は状態を変えずに、今の状態の案内を返す。
+func (l *lineController) dispatch(ctx context.Context, event *linebot.Event, in lineflow.Input) error {
+	uid := event.Source.UserID
+	step, err := l.flow.Begin(ctx, uid, in)
+	var rej *lineflow.Rejection
+	if errors.As(err, &rej) {
+		return userError(rej.Error())
 	}
-	ctx := context.Background()
-
-	user, err := l.getOrCreateUser(ctx, event.Source.UserID)
 	if err != nil {
 		return err
 	}
 
-	in := models.CreateWorkoutInput{
-		StartedAt: time.Now(),
-		Note:      nil,
-	}
-	// 第4引数 isFromLine=true（あなたの実装に合わせて）
-	w, err := l.workoutuc.Create(ctx, user.ID, in, true)
+	msgs, err := l.runStep(ctx, uid, step)
 	if err != nil {
 		return err
 	}
-
-	st := LineWorkoutState{
-		State:     "in_workout",
-		WorkoutID: w.ID,
-		StartedAt: w.StartedAt,
+	if stopsRestTimer(step) {
+		l.stopRestTimer(ctx, uid)
 	}
-
-	if err := l.lineuc.Set(ctx, stateKey(event.Source.UserID), st, workoutStateTTL); err != nil {
-		log.Printf("❌ ワークアウト開始失敗 / userID=%s / err=%v", event.Source.UserID, err)
-		return err
+	// 会話状態は DB の変更が確定してから保存する。保存に失敗しても、
+	// 開始したワークアウトは次の「開始」で終了していないワークアウトとして見つかる
+	if err := l.flow.Commit(ctx, uid, step); err != nil {
+		// 副作用は済んでいるのでリトライはしない
+		log.Printf("❌ LINE 会話状態の保存失敗 / userID=%s / trigger=%s / err=%v", uid, in.Trigger, err)
 	}
-
+	if len(msgs) == 0 {
+		msgs = []linebot.SendingMessage{linebot.NewTextMessage(l.flow.Prompt(step.State.State))}
+	}
+	_, _ = l.bot.ReplyMessage(event.ReplyToken, msgs...).Do()
 	return nil
 }
 
-func (l *lineController) endWorkout(event *linebot.Event) error {
-	if event.Source == nil || event.Source.UserID == "" {
-		return errors.New("user id not found in event")
-	}
-	ctx := context.Background()
-
-	user, err := l.getOrCreateUser(ctx, event.Source.UserID)
-	if err != nil {
-		return err
-	}
+// runStep は遷移ごとの副作用。返したメッセージで返信する（空なら遷移先の案内）
+func (l *lineController) runStep(ctx context.Context, uid string, step *lineflow.Step) ([]linebot.SendingMessage, error) {
+	switch step.Input.Trigger {
+	case lineflow.TriggerStart:
+		return l.startWorkout(ctx, uid, step, workoutSource{})
 
-	key := stateKey(event.Source.UserID)
+	case lineflow.TriggerResume:
+		return l.resumeWorkout(ctx, uid, step)
 
-	if raw, err := l.lineuc.Get(ctx, key); err == nil && raw != "" {
-		var st LineWorkoutState
-		if err := unmarshalState([]byte(raw), &st); err == nil && st.WorkoutID != "" {
-			if _, err := l.workoutuc.End(ctx, st.WorkoutID, user.ID, time.Now()); err != nil {
-				return err
-			}
-			_ = l.lineuc.Del(ctx, key) // 終了に成功したら状態を消す（エラーは握りつぶし）
-			return nil
-		}
-		// 破損していた場合はフォールバックに進む
-	}
+	case lineflow.TriggerCloseAndStart:
+		return l.closeAndStartWorkout(ctx, uid, step)
 
-	// 2) フォールバック：DBの「最新 is_from_line=true で未終了」を閉じる
-	wid, err := l.workoutuc.GetLatestLineWorkoutID(ctx, user.ID, true)
-	if err != nil {
-		return err
-	}
-	if wid == "" {
-		return errors.New("進行中のワークアウトが見つかりません。まずは『開始』してください")
-	}
+	case lineflow.TriggerTemplates:
+		return l.templateChoices(ctx, uid)
 
-	if _, err := l.workoutuc.End(ctx, wid, user.ID, time.Now()); err != nil {
-		return err
-	}
-	_ = l.lineuc.Del(ctx, key) // 念のため掃除（なければ何もしない）
+	case lineflow.TriggerStartTemplate:
+		return l.startFromTemplate(ctx, uid, step)
 
-	return nil
-}
+	case lineflow.TriggerCloneLast:
+		return l.cloneChoices(ctx, uid)
 
-func (l *lineController) handleText(event *linebot.Event) {
-	ctx := context.Background()
-	uid := event.Source.UserID
-	msg, _ := event.Message.(*linebot.TextMessage)
-	text := strings.TrimSpace(msg.Text)
+	case lineflow.TriggerCloneWorkout:
+		return l.startWorkout(ctx, uid, step, sourceFromParams(step.Input.Params))
 
-	s, _ := lineflow.LoadState(ctx, l.lineuc, uid)
-	if s.State == lineflow.StateIdle {
-		l.replyText(event.ReplyToken, "『追加』ボタン → 入力を進めてね")
-		l.pushAddMenu(uid)
-		return
-	}
+	case lineflow.TriggerEnd:
+		if err := l.endWorkout(ctx, uid, step.Before.WorkoutID); err != nil {
+			return nil, err
+		}
+		return withMenu("start", linebot.NewTextMessage("ワークアウトを終了しました！")), nil
 
-	switch s.State {
+	case lineflow.TriggerAdd, lineflow.TriggerExercise:
+		user, err := l.getOrCreateUser(ctx, uid)
+		if err != nil {
+			return nil, err
+		}
+		msgs := []linebot.SendingMessage{linebot.NewTextMessage(l.flow.Prompt(step.State.State))}
+		if picker, err := l.exercisePickerMessage(ctx, user.ID, "", 0); err == nil && picker != nil {
+			msgs = append(msgs, picker)
+		}
+		return msgs, nil
 
-	case lineflow.StateAddExercise:
-		// TODO: UUIDバリデーション（簡易）
-		if len(text) < 8 {
-			l.replyText(event.ReplyToken, "種目IDが短すぎます。正しいIDを送ってください")
-			l.pushAddMenu(uid)
-			return
+	case lineflow.TriggerExercisePage, lineflow.TriggerSearchExercise:
+		user, err := l.getOrCreateUser(ctx, uid)
+		if err != nil {
+			return nil, err
 		}
-		s.Pending.ExerciseID = text
-		s.State = lineflow.StateAddWeight
-		_ = lineflow.SaveState(ctx, l.lineuc, uid, s, stateTTL)
-		l.replyText(event.ReplyToken, "OK! 次は重量(kg)を送ってください（例: 60）")
-
-	case lineflow.StateAddWeight:
-		w, err := strconv.ParseFloat(text, 64)
-		if err != nil || w < 0 {
-			l.replyText(event.ReplyToken, "重量は0以上の数値で送ってください")
-			return
+		q, page := step.Input.Text, 0
+		if step.Input.Trigger == lineflow.TriggerExercisePage {
+			q = step.Input.Params.Get("q")
+			page, _ = strconv.Atoi(step.Input.Params.Get("page"))
 		}
-		s.Pending.Weight = &w
-		s.State = lineflow.StateAddCount
-		_ = lineflow.SaveState(ctx, l.lineuc, uid, s, stateTTL)
-		l.replyText(event.ReplyToken, "OK! 次は回数を送ってください（例: 8）")
-
-	case lineflow.StateAddCount:
-		n, err := strconv.Atoi(text)
-		if err != nil || n <= 0 {
-			l.replyText(event.ReplyToken, "回数は正の整数で送ってください")
-			return
+		picker, err := l.exercisePickerMessage(ctx, user.ID, q, page)
+		if err != nil {
+			return nil, userError("種目一覧の取得に失敗しました")
 		}
-		s.Pending.Repetitions = &n
-
-		if !s.Ready() {
-			_ = lineflow.SaveState(ctx, l.lineuc, uid, s, stateTTL)
-			l.replyText(event.ReplyToken, "まだ情報が足りません。ボタンで続けてください")
-			return
+		if picker == nil {
+			return nil, userError(fmt.Sprintf("「%s」に一致する種目がありません", q))
 		}
+		return []linebot.SendingMessage{picker}, nil
 
-		// ここで DB 登録
+	case lineflow.TriggerPickExercise, lineflow.TriggerWeightText:
+		return []linebot.SendingMessage{linebot.NewTextMessage("OK! 次は" + l.flow.Prompt(step.State.State))}, nil
+
+	case lineflow.TriggerCountText:
+		return l.saveStepSet(ctx, uid, step)
+
+	case lineflow.TriggerQuickEntry:
+		return l.handleQuickEntry(ctx, uid, step)
+
+	case lineflow.TriggerEntryConfirm:
+		return l.confirmQuickEntry(ctx, uid, step)
+
+	case lineflow.TriggerEntryCancel:
+		return []linebot.SendingMessage{linebot.NewTextMessage("入力を取り消しました")}, nil
+
+	case lineflow.TriggerToday, lineflow.TriggerWeek:
 		user, err := l.getOrCreateUser(ctx, uid)
 		if err != nil {
-			l.replyText(event.ReplyToken, "ユーザー解決に失敗しました")
-			l.pushStartMenu(uid)
-			return
+			return nil, err
 		}
-		in := models.WorkoutSetCreateInput{
-			ExerciseID: s.Pending.ExerciseID,
-			SetIndex:   0, // 自動採番なら0
-			Reps:       s.Pending.Repetitions,
-			WeightKg:   (*float32)(nil),
-		}
-		if s.Pending.Weight != nil {
-			tmp := float32(*s.Pending.Weight)
-			in.WeightKg = &tmp
+		return l.summaryMessages(ctx, user.ID, time.Now(), step.Input.Trigger == lineflow.TriggerWeek)
+
+	case lineflow.TriggerSnooze:
+		return l.snoozeReminders(ctx, uid, step.Input.Params.Get("hours"))
+
+	case lineflow.TriggerSettings:
+		return l.settingsMessages(ctx, uid, step.Input.Params.Get("key"))
+
+	case lineflow.TriggerSetSetting:
+		return l.applySetting(ctx, uid, step.Input.Params.Get("key"), step.Input.Params.Get("value"))
+
+	case lineflow.TriggerUndo:
+		return l.undoLastSet(ctx, uid)
+
+	case lineflow.TriggerRepeatLast:
+		return l.repeatLastSet(ctx, uid)
+
+	case lineflow.TriggerEditLast:
+		return l.beginEditLastSet(ctx, uid, step)
+
+	case lineflow.TriggerEditText:
+		return l.applyEditLastSet(ctx, uid, step)
+
+	case lineflow.TriggerRestTimer:
+		return l.startRestTimer(ctx, uid, step.Input.Params.Get("sec"))
+
+	case lineflow.TriggerCancelRest:
+		return l.cancelRestTimer(ctx, uid)
+
+	case lineflow.TriggerNextSet:
+		return l.beginNextSet(ctx, uid, step)
+
+	case lineflow.TriggerCancel:
+		if step.State.WorkoutID == "" {
+			step.State.State = lineflow.StateIdle
+			return withMenu("start", linebot.NewTextMessage("キャンセルしました")), nil
 		}
+		return withMenu("add", linebot.NewTextMessage("キャンセルしました。『追加』からやり直してください")), nil
+	}
+	return nil, nil
+}
 
-		if _, err := l.workoutSetuc.AddSet(ctx, user.ID, s.WorkoutID, in, true); err != nil {
-			l.replyText(event.ReplyToken, "セット登録に失敗しました。『回数』からやり直してください")
+func (l *lineController) createWorkout(ctx context.Context, user *models.User, step *lineflow.Step) error {
+	in := models.CreateWorkoutInput{
+		StartedAt: time.Now(),
+		Note:      nil,
+	}
+	// 第4引数 isFromLine=true（あなたの実装に合わせて）
+	w, err := l.workoutuc.Create(ctx, user.ID, in, true)
+	if err != nil {
+		log.Printf("❌ ワークアウト開始失敗 / userID=%s / err=%v", user.ID, err)
+		return err
+	}
+	step.State.WorkoutID = w.ID
+	return nil
+}
 
-			return
+func (l *lineController) endWorkout(ctx context.Context, uid, workoutID string) error {
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if _, err := l.workoutuc.End(ctx, workoutID, user.ID, nil, time.Now()); err != nil {
+		// Web 側で削除済みなら状態だけ片付ける
+		if usecase.IsNotFound(err) {
+			return nil
 		}
+		return err
+	}
+	return nil
+}
 
-		// 完了 → idle に戻す
-		lineflow.ClearState(ctx, l.lineuc, uid)
-		l.replyText(event.ReplyToken, "セットを登録しました！ 続けて『追加』でどうぞ")
-		l.pushAddMenu(uid)
+// saveStepSet はボタンで入力した 1 セットを登録する
+func (l *lineController) saveStepSet(ctx context.Context, uid string, step *lineflow.Step) ([]linebot.SendingMessage, error) {
+	s := step.State
+	if !s.Ready() {
+		return nil, userError("まだ情報が足りません。ボタンで続けてください")
+	}
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
 	}
+	in := models.WorkoutSetCreateInput{
+		ExerciseID: s.Pending.ExerciseID,
+		SetIndex:   0, // 自動採番なら0
+		Reps:       s.Pending.Repetitions,
+		Weight:     float32Ptr(*s.Pending.Weight),
+		WeightUnit: s.Pending.WeightUnit,
+	}
+	if in.WeightUnit == "" {
+		in.WeightUnit = l.weightUnit(ctx, user.ID)
+	}
+	ws, err := l.workoutSetuc.AddSet(ctx, user.ID, s.WorkoutID, in, true)
+	if err != nil {
+		return nil, userError("セット登録に失敗しました。『回数』からやり直してください")
+	}
+	msgs := []linebot.SendingMessage{linebot.NewTextMessage("セットを登録しました！ 続けて『追加』でどうぞ")}
+	if len(latestImprovements(ws.Records)) > 0 {
+		msgs = withRecordMessage(msgs, l.exerciseName(ctx, user.ID, ws.ExerciseID), ws.Records, in.WeightUnit)
+	}
+	return withQuickReplies(withMenu("add", msgs...), lastSetQuickReplies()), nil
 }
 
 func (l *lineController) CreateUser(event *linebot.Event) error {
@@ -325,6 +337,12 @@ func (l *lineController) getOrCreateUser(ctx context.Context, lineUserID string)
 	if err != nil {
 		return nil, err
 	}
+	return l.ensureUser(ctx, prof)
+}
+
+// ensureUser はプロフィールからユーザーを登録・更新する（LINE への問い合わせはしない）。
+// トランザクションの中ではこちらを使い、プロフィールは inTx の前に取っておく
+func (l *lineController) ensureUser(ctx context.Context, prof *linebot.UserProfileResponse) (*models.User, error) {
 	return l.useruc.EnsureUserFromLineProfile(ctx, prof.UserID, &prof.DisplayName, &prof.PictureURL, nil)
 }
 
@@ -345,34 +363,37 @@ func (l *lineController) pushStartMenu(userID string) {
 	).Do()
 }
 
-func (l *lineController) pushAddMenu(userID string) {
-	container, err := getFlexContainer("add")
+// withMenu は msgs の後ろに assets/flex/<name>.json のメニューを付ける
+func withMenu(name string, msgs ...linebot.SendingMessage) []linebot.SendingMessage {
+	container, err := getFlexContainer(name)
 	if err != nil {
-		return
+		return msgs
 	}
-	_, _ = l.bot.PushMessage(
-		userID,
-		linebot.NewFlexMessage("メニュー", container),
-	).Do()
+	return append(msgs, linebot.NewFlexMessage("メニュー", container))
 }
 
-func (l *lineController) pushExerciseListMenu(userID string) {
-	container, err := getFlexContainer("exercise_list")
-	if err != nil {
-		return
+// withQuickReplies は最後のメッセージに quick reply を付ける（LINE は最後のものだけ表示する）
+func withQuickReplies(msgs []linebot.SendingMessage, items *linebot.QuickReplyItems) []linebot.SendingMessage {
+	if len(msgs) == 0 {
+		return msgs
 	}
-	_, _ = l.bot.PushMessage(
-		userID,
-		linebot.NewFlexMessage("メニュー", container),
-	).Do()
+	msgs[len(msgs)-1] = msgs[len(msgs)-1].WithQuickReplies(items)
+	return msgs
 }
 
+func (l *lineController) exercisePickerMessage(ctx context.Context, userID, q string, page int) (linebot.SendingMessage, error) {
+	container, err := l.buildExercisePicker(ctx, userID, q, page)
+	if err != nil || container == nil {
+		return nil, err
+	}
+	return linebot.NewFlexMessage("種目を選択", container), nil
+}
 
 func getFlexContainer(filename string) (linebot.FlexContainer, error) {
-    path := fmt.Sprintf("assets/flex/%s.json", filename)
-    data, err := ioutil.ReadFile(path)
-    if err != nil {
-        return nil, err
-    }
-    return linebot.UnmarshalFlexMessageJSON(data)
+	path := fmt.Sprintf("assets/flex/%s.json", filename)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return linebot.UnmarshalFlexMessageJSON(data)
 }
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+// 種目ピッカー（DB の種目から Flex カルーセルを組み立てる）
+
+const (
+	exercisesPerBubble = 6
+	// LINE のカルーセルは最大 12 バブル。「次へ」用に 1 つ空けておく
+	bubblesPerPage   = 5
+	exercisesPerPage = exercisesPerBubble * bubblesPerPage
+)
+
+// buildExercisePicker は userID から見える種目を よく使う順 に並べたカルーセルを返す。
+// q が空でなければ名前で絞り込む。該当が無ければ nil を返す。
+func (l *lineController) buildExercisePicker(ctx context.Context, userID, q string, page int) (linebot.FlexContainer, error) {
+	if page < 0 {
+		page = 0
+	}
+	out, err := l.exerciseuc.List(ctx, userID, usecase.ListExercisesInput{
+		Q:            q,
+		ActiveOnly:   true,
+		OrderByUsage: true,
+		Limit:        exercisesPerPage,
+		Offset:       page * exercisesPerPage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Items) == 0 {
+		return nil, nil
+	}
+
+	carousel := &linebot.CarouselContainer{Type: linebot.FlexContainerTypeCarousel}
+	for start := 0; start < len(out.Items); start += exercisesPerBubble {
+		end := start + exercisesPerBubble
+		if end > len(out.Items) {
+			end = len(out.Items)
+		}
+		carousel.Contents = append(carousel.Contents, exerciseBubble(out.Items[start:end], q))
+	}
+
+	if int64((page+1)*exercisesPerPage) < out.Total {
+		v := url.Values{}
+		v.Set("action", "exercise_page")
+		v.Set("page", strconv.Itoa(page+1))
+		if q != "" {
+			v.Set("q", q)
+		}
+		carousel.Contents = append(carousel.Contents, nextPageBubble(v.Encode()))
+	}
+	return carousel, nil
+}
+
+func exerciseBubble(items []models.Exercise, q string) *linebot.BubbleContainer {
+	title := "種目を選択してください"
+	if q != "" {
+		title = fmt.Sprintf("「%s」の検索結果", q)
+	}
+
+	buttons := make([]linebot.FlexComponent, 0, len(items))
+	for _, ex := range items {
+		data := "action=pick_exercise&exerciseId=" + url.QueryEscape(ex.ID)
+		buttons = append(buttons, &linebot.ButtonComponent{
+			Type:   linebot.FlexComponentTypeButton,
+			Style:  linebot.FlexButtonStyleTypePrimary,
+			Height: linebot.FlexButtonHeightTypeSm,
+			Color:  "#0f9bff",
+			Action: linebot.NewPostbackAction(truncateLabel(ex.Name), data, "", ex.Name),
+		})
+	}
+
+	return &linebot.BubbleContainer{
+		Type: linebot.FlexContainerTypeBubble,
+		Size: linebot.FlexBubbleSizeTypeKilo,
+		Header: &linebot.BoxComponent{
+			Type:   linebot.FlexComponentTypeBox,
+			Layout: linebot.FlexBoxLayoutTypeVertical,
+			Contents: []linebot.FlexComponent{
+				&linebot.TextComponent{
+					Type:   linebot.FlexComponentTypeText,
+					Text:   title,
+					Weight: linebot.FlexTextWeightTypeBold,
+					Size:   linebot.FlexTextSizeTypeMd,
+					Color:  "#333333",
+					Wrap:   true,
+				},
+			},
+		},
+		Body: &linebot.BoxComponent{
+			Type:     linebot.FlexComponentTypeBox,
+			Layout:   linebot.FlexBoxLayoutTypeVertical,
+			Spacing:  linebot.FlexComponentSpacingTypeSm,
+			Contents: buttons,
+		},
+		Footer: &linebot.BoxComponent{
+			Type:   linebot.FlexComponentTypeBox,
+			Layout: linebot.FlexBoxLayoutTypeVertical,
+			Contents: []linebot.FlexComponent{
+				&linebot.TextComponent{
+					Type:  linebot.FlexComponentTypeText,
+					Text:  "種目名を送ると検索できます",
+					Size:  linebot.FlexTextSizeTypeXs,
+					Color: "#94a3b8",
+				},
+			},
+		},
+	}
+}
+
+func nextPageBubble(data string) *linebot.BubbleContainer {
+	return &linebot.BubbleContainer{
+		Type: linebot.FlexContainerTypeBubble,
+		Size: linebot.FlexBubbleSizeTypeKilo,
+		Body: &linebot.BoxComponent{
+			Type:   linebot.FlexComponentTypeBox,
+			Layout: linebot.FlexBoxLayoutTypeVertical,
+			Contents: []linebot.FlexComponent{
+				&linebot.ButtonComponent{
+					Type:   linebot.FlexComponentTypeButton,
+					Style:  linebot.FlexButtonStyleTypeSecondary,
+					Action: linebot.NewPostbackAction("次の種目を見る", data, "", "次の種目を見る"),
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,288 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+
+	"github.com/sirasu21/Logbook/backend/lineflow"
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+// 直前のセット（LINE から登録した最新のもの）の取り消し・修正・もう1セット
+
+// latestLineSet は直前のセットと種目名を返す
+func (l *lineController) latestLineSet(ctx context.Context, userID string) (*models.WorkoutSet, string, error) {
+	ws, err := l.workoutSetuc.LatestLineSet(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if ws == nil {
+		return nil, "", userError("LINE から記録したセットがありません")
+	}
+	return ws, l.exerciseName(ctx, userID, ws.ExerciseID), nil
+}
+
+func (l *lineController) exerciseName(ctx context.Context, userID, exerciseID string) string {
+	if ex, err := l.exerciseuc.Get(ctx, userID, exerciseID); err == nil && ex != nil {
+		return ex.Name
+	}
+	return "（削除された種目）"
+}
+
+func (l *lineController) undoLastSet(ctx context.Context, uid string) ([]linebot.SendingMessage, error) {
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	ws, name, err := l.latestLineSet(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	// 表示したセットと違うもの（Web で直した直後など）は取り消さない
+	if err := l.workoutSetuc.DeleteSet(ctx, user.ID, ws.ID, &ws.Version); err != nil {
+		if usecase.IsVersionConflict(err) {
+			return nil, userError("セットが Web で変更されました。もう一度取り消してください")
+		}
+		return nil, err
+	}
+	bubble := setChangeBubble("セットを取り消しました", "#ef4444", name, []setChange{
+		{Label: "取り消したセット", Before: describeWorkoutSet(ws)},
+		{Label: "戻すには", After: fmt.Sprintf("%d 日間は Web のゴミ箱から戻せます", int(models.TrashRetention.Hours()/24))},
+	})
+	return []linebot.SendingMessage{linebot.NewFlexMessage("セットを取り消しました", bubble)}, nil
+}
+
+func (l *lineController) repeatLastSet(ctx context.Context, uid string) ([]linebot.SendingMessage, error) {
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	ws, name, err := l.latestLineSet(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	w, err := l.workoutuc.GetDetail(ctx, user.ID, ws.WorkoutID)
+	if err != nil {
+		return nil, err
+	}
+	if w.Workout.EndedAt != nil {
+		return nil, userError("直前のワークアウトは終了しています。「開始」してから記録してください")
+	}
+
+	weight, unit := ws.EnteredWeight()
+	added, err := l.workoutSetuc.AddSet(ctx, user.ID, ws.WorkoutID, models.WorkoutSetCreateInput{
+		ExerciseID: ws.ExerciseID,
+		Reps:       ws.Reps,
+		Weight:     weight,
+		WeightUnit: unit,
+		RPE:        ws.RPE,
+		IsWarmup:   ws.IsWarmup,
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+	bubble := setChangeBubble("もう1セット記録しました", "#10b981", name, []setChange{
+		{Label: "追加したセット", After: describeWorkoutSet(added)},
+	})
+	msgs := withRecordMessage([]linebot.SendingMessage{linebot.NewFlexMessage("もう1セット記録しました", bubble)}, name, added.Records, l.weightUnit(ctx, user.ID))
+	return withQuickReplies(msgs, lastSetQuickReplies()), nil
+}
+
+// beginEditLastSet は修正するセットを状態に覚えて、今の内容を見せる
+func (l *lineController) beginEditLastSet(ctx context.Context, uid string, step *lineflow.Step) ([]linebot.SendingMessage, error) {
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	ws, name, err := l.latestLineSet(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	step.State.EditSetID = ws.ID
+	text := fmt.Sprintf("直前のセット\n%s %s\n%s", name, describeWorkoutSet(ws), l.flow.Prompt(lineflow.StateEditSet))
+	return []linebot.SendingMessage{
+		linebot.NewTextMessage(text).WithQuickReplies(linebot.NewQuickReplyItems(
+			linebot.NewQuickReplyButton("", linebot.NewPostbackAction("キャンセル", "action=cancel", "", "キャンセル")),
+		)),
+	}, nil
+}
+
+func (l *lineController) applyEditLastSet(ctx context.Context, uid string, step *lineflow.Step) ([]linebot.SendingMessage, error) {
+	edit, err := lineflow.ParseSetEdit(step.Input.Text)
+	if err != nil {
+		return nil, userError(err.Error())
+	}
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	before, err := l.workoutSetuc.GetSet(ctx, user.ID, step.Before.EditSetID)
+	if err != nil {
+		return nil, userError("修正するセットが見つかりません")
+	}
+	name := l.exerciseName(ctx, user.ID, before.ExerciseID)
+
+	unit := l.weightUnit(ctx, user.ID)
+	in := models.WorkoutSetUpdateInput{Reps: edit.Reps}
+	if edit.Weight != nil {
+		in.Weight, in.WeightUnit = float32Ptr(*edit.Weight), edit.Unit
+		if in.WeightUnit == "" {
+			in.WeightUnit = unit
+		}
+	}
+	if edit.RPE != nil {
+		in.RPE = float32Ptr(*edit.RPE)
+	}
+	after, err := l.workoutSetuc.UpdateSet(ctx, user.ID, step.Before.EditSetID, &before.Version, in)
+	if err != nil {
+		if usecase.IsVersionConflict(err) {
+			return nil, userError("セットが Web で変更されました。もう一度修正してください")
+		}
+		return nil, err
+	}
+	if step.State.WorkoutID == "" {
+		step.State.State = lineflow.StateIdle
+	}
+
+	var changes []setChange
+	if b, a := formatEnteredWeight(before), formatEnteredWeight(after); b != a {
+		changes = append(changes, setChange{Label: "重量", Before: b, After: a})
+	}
+	if !equalIntPtr(before.Reps, after.Reps) {
+		changes = append(changes, setChange{Label: "回数", Before: formatReps(before.Reps), After: formatReps(after.Reps)})
+	}
+	if !equalFloat32Ptr(before.RPE, after.RPE) {
+		changes = append(changes, setChange{Label: "RPE", Before: formatRPE(before.RPE), After: formatRPE(after.RPE)})
+	}
+	if len(changes) == 0 {
+		changes = append(changes, setChange{Label: "変更なし", After: describeWorkoutSet(after)})
+	}
+	bubble := setChangeBubble("セットを修正しました", "#3b82f6", name, changes)
+	msgs := withRecordMessage([]linebot.SendingMessage{linebot.NewFlexMessage("セットを修正しました", bubble)}, name, after.Records, unit)
+	return withQuickReplies(msgs, lastSetQuickReplies()), nil
+}
+
+type setChange struct {
+	Label  string
+	Before string // 空なら表示しない
+	After  string // 空なら表示しない
+}
+
+// setChangeBubble は「何がどう変わったか」を見せるカード
+func setChangeBubble(title, color, exerciseName string, changes []setChange) *linebot.BubbleContainer {
+	body := []linebot.FlexComponent{
+		&linebot.TextComponent{
+			Type:   linebot.FlexComponentTypeText,
+			Text:   exerciseName,
+			Weight: linebot.FlexTextWeightTypeBold,
+			Size:   linebot.FlexTextSizeTypeMd,
+			Color:  "#333333",
+			Wrap:   true,
+		},
+	}
+	for _, c := range changes {
+		value := c.After
+		switch {
+		case c.Before != "" && c.After != "":
+			value = c.Before + " → " + c.After
+		case c.Before != "":
+			value = c.Before
+		}
+		body = append(body, summaryRow(c.Label, value))
+	}
+
+	return &linebot.BubbleContainer{
+		Type: linebot.FlexContainerTypeBubble,
+		Size: linebot.FlexBubbleSizeTypeKilo,
+		Header: &linebot.BoxComponent{
+			Type:            linebot.FlexComponentTypeBox,
+			Layout:          linebot.FlexBoxLayoutTypeVertical,
+			BackgroundColor: color,
+			Contents: []linebot.FlexComponent{
+				&linebot.TextComponent{
+					Type:   linebot.FlexComponentTypeText,
+					Text:   title,
+					Weight: linebot.FlexTextWeightTypeBold,
+					Size:   linebot.FlexTextSizeTypeSm,
+					Color:  "#FFFFFF",
+				},
+			},
+		},
+		Body: &linebot.BoxComponent{
+			Type:     linebot.FlexComponentTypeBox,
+			Layout:   linebot.FlexBoxLayoutTypeVertical,
+			Spacing:  linebot.FlexComponentSpacingTypeSm,
+			Contents: body,
+		},
+	}
+}
+
+// lastSetQuickReplies はセット登録後に出す「休憩タイマー / もう1セット / 修正 / 取り消し」
+func lastSetQuickReplies() *linebot.QuickReplyItems {
+	return linebot.NewQuickReplyItems(
+		linebot.NewQuickReplyButton("", linebot.NewPostbackAction("⏱ 休憩", "action=rest_timer", "", "休憩タイマー")),
+		linebot.NewQuickReplyButton("", linebot.NewPostbackAction("もう1セット", "action=repeat_last", "", "もう1セット")),
+		linebot.NewQuickReplyButton("", linebot.NewPostbackAction("修正", "action=edit_last", "", "直前のセットを修正")),
+		linebot.NewQuickReplyButton("", linebot.NewPostbackAction("取り消し", "action=undo", "", "直前のセットを取り消し")),
+	)
+}
+
+// describeWorkoutSet は「60kg × 8回 @8」形式にする（重さは入力した単位）
+func describeWorkoutSet(ws *models.WorkoutSet) string {
+	line := formatReps(ws.Reps)
+	if ws.WeightKg != nil {
+		line = fmt.Sprintf("%s × %s", formatEnteredWeight(ws), line)
+	}
+	if ws.RPE != nil {
+		line += " @" + formatRPE(ws.RPE)
+	}
+	return line
+}
+
+// formatEnteredWeight は入力した単位での「60kg」「135lb」
+func formatEnteredWeight(ws *models.WorkoutSet) string {
+	v, unit := ws.EnteredWeight()
+	if v == nil {
+		return "—"
+	}
+	return fmt.Sprintf("%g%s", *v, unit)
+}
+
+// formatKgIn は kg の値を unit にした「60kg」「132.3lb」
+func formatKgIn(kg *float32, unit models.WeightUnit) string {
+	if kg == nil {
+		return "—"
+	}
+	return fmt.Sprintf("%g%s", models.FromKg(float64(*kg), unit), unit)
+}
+
+func formatReps(v *int) string {
+	if v == nil {
+		return "—"
+	}
+	return fmt.Sprintf("%d回", *v)
+}
+
+func formatRPE(v *float32) string {
+	if v == nil {
+		return "—"
+	}
+	return fmt.Sprintf("%g", *v)
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalFloat32Ptr(a, b *float32) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
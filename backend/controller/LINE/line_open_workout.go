@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+
+	"github.com/sirasu21/Logbook/backend/lineflow"
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+// 終了していないワークアウトの扱い（「開始」時の確認と自動終了の通知）
+
+// workoutSource は開始するワークアウトの元（どちらも空ならまっさら）
+type workoutSource struct {
+	TemplateID  string
+	CloneOf     string // 前回のワークアウト
+	Progression models.Progression
+}
+
+func sourceFromParams(p url.Values) workoutSource {
+	return workoutSource{
+		TemplateID:  p.Get("templateId"),
+		CloneOf:     p.Get("cloneOf"),
+		Progression: models.Progression(p.Get("progression")),
+	}
+}
+
+// addTo は「終了して新しく開始」のポストバックに元を引き継ぐ
+func (s workoutSource) addTo(v url.Values) {
+	if s.TemplateID != "" {
+		v.Set("templateId", s.TemplateID)
+	}
+	if s.CloneOf != "" {
+		v.Set("cloneOf", s.CloneOf)
+	}
+	if s.Progression != models.ProgressionNone {
+		v.Set("progression", string(s.Progression))
+	}
+}
+
+// startWorkout は終了していないワークアウトがなければ src から開始する。
+// あれば二重に開始せず、再開するか閉じて新しく始めるかを聞く
+func (l *lineController) startWorkout(ctx context.Context, uid string, step *lineflow.Step, src workoutSource) ([]linebot.SendingMessage, error) {
+	prof, err := l.bot.GetProfile(uid).Do()
+	if err != nil {
+		return nil, err
+	}
+	return l.inTx(ctx, func(ctx context.Context) ([]linebot.SendingMessage, error) {
+		user, err := l.ensureUser(ctx, prof)
+		if err != nil {
+			return nil, err
+		}
+		open, err := l.workoutuc.OpenLineWorkout(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if open != nil {
+			step.State.State = lineflow.StateIdle
+			return []linebot.SendingMessage{l.openWorkoutMessage(ctx, user.ID, open, src)}, nil
+		}
+		return l.beginWorkout(ctx, user, step, src)
+	})
+}
+
+// inTx は fn の DB 操作（ユーザーの登録・前のワークアウトの終了・ワークアウトと予定のセットの作成）を
+// 1 つのトランザクションで行う。失敗したら何も残らず、会話状態も保存されない。
+// fn の中では LINE などの外部 API を呼ばない（ロックを持ったまま待たないように、先に済ませておく）
+func (l *lineController) inTx(ctx context.Context, fn func(ctx context.Context) ([]linebot.SendingMessage, error)) ([]linebot.SendingMessage, error) {
+	var msgs []linebot.SendingMessage
+	err := l.tx.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		msgs, err = fn(ctx)
+		return err
+	})
+	return msgs, err
+}
+
+// beginWorkout はワークアウトを作って状態に覚える
+func (l *lineController) beginWorkout(ctx context.Context, user *models.User, step *lineflow.Step, src workoutSource) ([]linebot.SendingMessage, error) {
+	switch {
+	case src.TemplateID != "":
+		return l.beginTemplateWorkout(ctx, user, step, src.TemplateID)
+	case src.CloneOf != "":
+		return l.beginClonedWorkout(ctx, user, step, src.CloneOf, src.Progression)
+	}
+	if err := l.createWorkout(ctx, user, step); err != nil {
+		return nil, err
+	}
+	return withMenu("add", linebot.NewTextMessage("ワークアウトを開始しました！")), nil
+}
+
+func (l *lineController) resumeWorkout(ctx context.Context, uid string, step *lineflow.Step) ([]linebot.SendingMessage, error) {
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	d, err := l.workoutuc.GetDetail(ctx, user.ID, step.State.WorkoutID)
+	if err != nil || d.Workout.EndedAt != nil {
+		return nil, userError("このワークアウトはもう終了しています。「開始」で新しく始めてください")
+	}
+	done := 0
+	for _, s := range d.Sets {
+		if !s.IsPlanned {
+			done++
+		}
+	}
+	loc := l.userSettings(ctx, user.ID).Location()
+	text := fmt.Sprintf("%s に開始したワークアウトを再開しました（%dセット記録済み）", formatClock(d.Workout.StartedAt, loc), done)
+	return withMenu("add", linebot.NewTextMessage(text)), nil
+}
+
+// closeAndStartWorkout は残っていたワークアウトを最後のセットの時刻で閉じてから開始する
+func (l *lineController) closeAndStartWorkout(ctx context.Context, uid string, step *lineflow.Step) ([]linebot.SendingMessage, error) {
+	prof, err := l.bot.GetProfile(uid).Do()
+	if err != nil {
+		return nil, err
+	}
+	return l.inTx(ctx, func(ctx context.Context) ([]linebot.SendingMessage, error) {
+		user, err := l.ensureUser(ctx, prof)
+		if err != nil {
+			return nil, err
+		}
+		closed, err := l.workoutuc.Close(ctx, step.Input.Params.Get("workoutId"), user.ID)
+		if err != nil && !usecase.IsNotFound(err) {
+			return nil, err
+		}
+		msgs, err := l.beginWorkout(ctx, user, step, sourceFromParams(step.Input.Params))
+		if err != nil {
+			return nil, err
+		}
+		if closed != nil && closed.EndedAt != nil {
+			loc := l.userSettings(ctx, user.ID).Location()
+			text := fmt.Sprintf("前回のワークアウトを %s で終了しました", formatClock(*closed.EndedAt, loc))
+			msgs = append([]linebot.SendingMessage{linebot.NewTextMessage(text)}, msgs...)
+		}
+		return msgs, nil
+	})
+}
+
+// PushWorkoutAutoClosed は放置されたワークアウトを自動で終了したことを知らせる
+func (l *lineController) PushWorkoutAutoClosed(ctx context.Context, w models.AbandonedWorkout) error {
+	l.flow.Forget(ctx, w.LineUserID, w.ID)
+	loc := l.userSettings(ctx, w.UserID).Location()
+	text := fmt.Sprintf("%s に開始したワークアウトがそのままだったので、%s で終了しました", formatClock(w.StartedAt, loc), formatClock(*w.EndedAt, loc))
+	msg := linebot.NewTextMessage(text).WithQuickReplies(linebot.NewQuickReplyItems(
+		linebot.NewQuickReplyButton("", linebot.NewPostbackAction("開始", "action=start", "", "開始")),
+	))
+	return l.push(w.LineUserID, msg)
+}
+
+func (l *lineController) openWorkoutMessage(ctx context.Context, userID string, w *models.Workout, src workoutSource) linebot.SendingMessage {
+	loc := l.userSettings(ctx, userID).Location()
+	text := fmt.Sprintf("%s に開始したワークアウトが終了していません", formatClock(w.StartedAt, loc))
+	if d, err := l.workoutuc.GetDetail(ctx, userID, w.ID); err == nil {
+		var last time.Time
+		done := 0
+		for _, s := range d.Sets {
+			if s.IsPlanned {
+				continue
+			}
+			done++
+			if s.CreatedAt.After(last) {
+				last = s.CreatedAt
+			}
+		}
+		if done > 0 {
+			text += fmt.Sprintf("（%dセット、最後の記録 %s）", done, formatClock(last, loc))
+		}
+	}
+	text += "\n続きから記録しますか？"
+
+	resume := url.Values{"action": {string(lineflow.TriggerResume)}, "workoutId": {w.ID}}
+	restart := url.Values{"action": {string(lineflow.TriggerCloseAndStart)}, "workoutId": {w.ID}}
+	src.addTo(restart)
+	return linebot.NewTextMessage(text).WithQuickReplies(linebot.NewQuickReplyItems(
+		linebot.NewQuickReplyButton("", linebot.NewPostbackAction("再開", resume.Encode(), "", "再開")),
+		linebot.NewQuickReplyButton("", linebot.NewPostbackAction("終了して新しく開始", restart.Encode(), "", "終了して新しく開始")),
+		linebot.NewQuickReplyButton("", linebot.NewPostbackAction("キャンセル", "action=cancel", "", "キャンセル")),
+	))
+}
+
+// formatClock は loc での「10/14(火) 19:20」形式（今日なら時刻だけ）
+func formatClock(t time.Time, loc *time.Location) string {
+	t = t.In(loc)
+	now := time.Now().In(loc)
+	if t.Year() == now.Year() && t.YearDay() == now.YearDay() {
+		return t.Format("15:04")
+	}
+	return fmt.Sprintf("%d/%d(%s) %s", t.Month(), t.Day(), weekdaysJa[t.Weekday()], t.Format("15:04"))
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+
+	"github.com/sirasu21/Logbook/backend/lineflow"
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+// 「ベンチ 60x8x3」のような自然文でのセット一括登録
+
+// handleQuickEntry は解析済みの入力から種目を解決し、
+// 一意に決まればそのまま登録、曖昧なら確認待ちにして quick reply で候補を出す。
+func (l *lineController) handleQuickEntry(ctx context.Context, uid string, step *lineflow.Step) ([]linebot.SendingMessage, error) {
+	entry := step.Input.Entry
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	cands, err := l.exerciseuc.ResolveByName(ctx, user.ID, lineflow.ExerciseSearchTerms(entry.Exercise))
+	if err != nil {
+		return nil, userError("種目の検索に失敗しました")
+	}
+	if len(cands) == 0 {
+		return nil, userError(fmt.Sprintf("「%s」という種目が見つかりません。『追加』から種目を選んでください", entry.Exercise))
+	}
+
+	// 単位を付けずに送った重さはユーザー設定の単位（確認待ちにもこの単位で残す）
+	unit := l.weightUnit(ctx, user.ID)
+	for i := range entry.Sets {
+		if entry.Sets[i].Weight != nil && entry.Sets[i].Unit == "" {
+			entry.Sets[i].Unit = unit
+		}
+	}
+
+	if len(cands) == 1 && !entry.Ambiguous {
+		msg, err := l.saveQuickEntry(ctx, user.ID, step.State.WorkoutID, cands[0].ID, cands[0].Name, entry)
+		if err != nil {
+			return nil, err
+		}
+		return []linebot.SendingMessage{linebot.NewTextMessage(msg).WithQuickReplies(lastSetQuickReplies())}, nil
+	}
+
+	// 確認待ちとして保存し、候補ボタンを出す
+	draft := &lineflow.Draft{Entry: *entry}
+	for _, ex := range cands {
+		draft.Candidates = append(draft.Candidates, lineflow.DraftCandidate{ExerciseID: ex.ID, Name: ex.Name})
+	}
+	step.State.State = lineflow.StateConfirmEntry
+	step.State.Draft = draft
+
+	items := make([]*linebot.QuickReplyButton, 0, len(draft.Candidates)+1)
+	for _, c := range draft.Candidates {
+		data := "action=entry_confirm&exerciseId=" + url.QueryEscape(c.ExerciseID)
+		items = append(items, linebot.NewQuickReplyButton("", linebot.NewPostbackAction(truncateLabel(c.Name), data, "", c.Name)))
+	}
+	items = append(items, linebot.NewQuickReplyButton("", linebot.NewPostbackAction("キャンセル", "action=entry_cancel", "", "キャンセル")))
+
+	text := fmt.Sprintf("%s\nこの内容で登録しますか？", describeEntry(entry))
+	if len(draft.Candidates) > 1 {
+		text = fmt.Sprintf("%s\n種目を選んでください", describeEntry(entry))
+	}
+	return []linebot.SendingMessage{
+		linebot.NewTextMessage(text).WithQuickReplies(linebot.NewQuickReplyItems(items...)),
+	}, nil
+}
+
+// confirmQuickEntry は quick reply で選ばれた種目で確認待ちの入力を登録する。
+// 候補に含まれているかはエンジンのガードで確認済み。
+func (l *lineController) confirmQuickEntry(ctx context.Context, uid string, step *lineflow.Step) ([]linebot.SendingMessage, error) {
+	c, _ := step.Before.Draft.Candidate(step.Input.Params.Get("exerciseId"))
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	entry := step.Before.Draft.Entry
+	msg, err := l.saveQuickEntry(ctx, user.ID, step.State.WorkoutID, c.ExerciseID, c.Name, &entry)
+	if err != nil {
+		return nil, err
+	}
+	return []linebot.SendingMessage{linebot.NewTextMessage(msg).WithQuickReplies(lastSetQuickReplies())}, nil
+}
+
+// saveQuickEntry は進行中のワークアウトに全セットを登録し、返信文を返す。
+// 全セットを 1 つのトランザクションで登録する（途中で失敗したら 1 セットも残さず、送り直しても二重にならない）。
+// ユーザーは呼び出し側で解決済みなので、ここでは LINE の API を呼ばない
+func (l *lineController) saveQuickEntry(ctx context.Context, userID, workoutID, exerciseID, exerciseName string, entry *lineflow.ParsedEntry) (string, error) {
+	var records []models.PersonalRecord
+	_, err := l.inTx(ctx, func(ctx context.Context) ([]linebot.SendingMessage, error) {
+		records = nil
+		for _, ps := range entry.Sets {
+			in := models.WorkoutSetCreateInput{
+				ExerciseID: exerciseID,
+				Reps:       intPtr(ps.Reps),
+			}
+			if ps.Weight != nil {
+				in.Weight, in.WeightUnit = float32Ptr(*ps.Weight), ps.Unit
+			}
+			if ps.RPE != nil {
+				in.RPE = float32Ptr(*ps.RPE)
+			}
+			ws, err := l.workoutSetuc.AddSet(ctx, userID, workoutID, in, true)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, ws.Records...)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return "", userError("セットの登録に失敗しました（何も登録していません）。もう一度送ってください")
+	}
+
+	entry.Exercise = exerciseName
+	msg := fmt.Sprintf("登録しました！\n%s", describeEntry(entry))
+	if text := recordText(exerciseName, records, l.weightUnit(ctx, userID)); text != "" {
+		msg += "\n\n" + text
+	}
+	return msg, nil
+}
+
+// describeEntry は「ベンチプレス 60kg × 8回 × 3セット」形式の要約を作る（重さは入力した単位）
+func describeEntry(entry *lineflow.ParsedEntry) string {
+	lines := []string{entry.Exercise}
+	for i := 0; i < len(entry.Sets); {
+		ps := entry.Sets[i]
+		n := 1
+		for i+n < len(entry.Sets) && sameParsedSet(entry.Sets[i+n], ps) {
+			n++
+		}
+		line := fmt.Sprintf("%d回", ps.Reps)
+		if ps.Weight != nil {
+			line = fmt.Sprintf("%g%s × %s", *ps.Weight, ps.Unit, line)
+		}
+		if n > 1 {
+			line += fmt.Sprintf(" × %dセット", n)
+		}
+		if ps.RPE != nil {
+			line += fmt.Sprintf(" @%g", *ps.RPE)
+		}
+		lines = append(lines, "・"+line)
+		i += n
+	}
+	return strings.Join(lines, "\n")
+}
+
+func sameParsedSet(a, b lineflow.ParsedSet) bool {
+	return a.Reps == b.Reps && equalFloatPtr(a.Weight, b.Weight) && a.Unit == b.Unit && equalFloatPtr(a.RPE, b.RPE)
+}
+
+func equalFloatPtr(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// LINE のアクションラベルは 20 文字まで
+func truncateLabel(s string) string {
+	r := []rune(s)
+	if len(r) <= 20 {
+		return s
+	}
+	return string(r[:19]) + "…"
+}
+
+func intPtr(v int) *int { return &v }
+
+func float32Ptr(v float64) *float32 {
+	f := float32(v)
+	return &f
+}
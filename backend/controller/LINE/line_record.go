@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+// 自己ベスト更新のお祝い
+
+// recordText は前の記録を上回ったものだけを並べる（無ければ空文字）。重さは unit で出す
+func recordText(exerciseName string, records []models.PersonalRecord, unit models.WeightUnit) string {
+	lines := []string{"🎉 自己ベスト更新！ " + exerciseName}
+	for _, r := range latestImprovements(records) {
+		lines = append(lines, "・"+describeRecord(r, unit))
+	}
+	if len(lines) == 1 {
+		return ""
+	}
+	return strings.Join(lines, "\n")
+}
+
+// withRecordMessage はお祝いがあれば msgs の後ろに足す
+func withRecordMessage(msgs []linebot.SendingMessage, exerciseName string, records []models.PersonalRecord, unit models.WeightUnit) []linebot.SendingMessage {
+	if text := recordText(exerciseName, records, unit); text != "" {
+		msgs = append(msgs, linebot.NewTextMessage(text))
+	}
+	return msgs
+}
+
+// latestImprovements は種類ごと（reps_at_weight は重量ごと）に最後の更新だけ残す。
+// 複数セットをまとめて登録すると同じ種類が何度も更新されるため
+func latestImprovements(records []models.PersonalRecord) []models.PersonalRecord {
+	var out []models.PersonalRecord
+	index := map[string]int{}
+	for _, r := range records {
+		if !r.IsImprovement() {
+			continue
+		}
+		key := string(r.Kind)
+		if r.Kind == models.RecordRepsAtWeight {
+			key += formatKgIn(r.WeightKg, models.WeightUnitKg)
+		}
+		if i, ok := index[key]; ok {
+			out[i] = r
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, r)
+	}
+	return out
+}
+
+func describeRecord(r models.PersonalRecord, unit models.WeightUnit) string {
+	prev := 0.0
+	if r.PreviousValue != nil {
+		prev = *r.PreviousValue
+	}
+	// 記録は kg で持っているので unit に直す
+	w := func(kg float64) string { return fmt.Sprintf("%g%s", models.FromKg(kg, unit), unit) }
+	switch r.Kind {
+	case models.RecordMaxWeight:
+		return fmt.Sprintf("最大重量 %s → %s", w(prev), w(r.Value))
+	case models.RecordRepsAtWeight:
+		return fmt.Sprintf("%s で %g回 → %g回", formatKgIn(r.WeightKg, unit), prev, r.Value)
+	case models.RecordE1RM:
+		return fmt.Sprintf("推定1RM %s → %s", w(prev), w(r.Value))
+	case models.RecordSessionVolume:
+		return fmt.Sprintf("総挙上量 %s → %s", w(prev), w(r.Value))
+	}
+	return string(r.Kind)
+}
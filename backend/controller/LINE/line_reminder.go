@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+// リマインド・声かけ・週次まとめのプッシュ通知（スケジューラーから呼ばれる）
+
+// スヌーズの選択肢（時間）
+var snoozeChoices = []struct {
+	Label string
+	Hours int
+}{
+	{"今日は休む", 24},
+	{"3日休む", 72},
+	{"1週間休む", 168},
+}
+
+func (l *lineController) PushTrainingReminder(ctx context.Context, lineUserID string) error {
+	msg := linebot.NewTextMessage("トレーニングの時間です💪\n準備ができたら「開始」をタップ！").
+		WithQuickReplies(snoozeQuickReplies())
+	return l.push(lineUserID, msg)
+}
+
+func (l *lineController) PushInactiveNudge(ctx context.Context, lineUserID string, days int) error {
+	msg := linebot.NewTextMessage(fmt.Sprintf("%d日トレーニングしていません。軽めのメニューからでも再開しませんか？", days)).
+		WithQuickReplies(snoozeQuickReplies())
+	return l.push(lineUserID, msg)
+}
+
+func (l *lineController) PushWeeklyRecap(ctx context.Context, lineUserID, userID string) error {
+	msgs, err := l.summaryMessages(ctx, userID, time.Now(), true)
+	if err != nil {
+		return err
+	}
+	msgs = append([]linebot.SendingMessage{linebot.NewTextMessage("今週もお疲れさまでした！1週間のまとめです")}, msgs...)
+	return l.push(lineUserID, msgs...)
+}
+
+// snoozeReminders は action=snooze&hours=N で N 時間リマインドを止める
+func (l *lineController) snoozeReminders(ctx context.Context, uid, hours string) ([]linebot.SendingMessage, error) {
+	h, err := strconv.Atoi(hours)
+	if err != nil || h <= 0 {
+		h = 24
+	}
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	until := time.Now().Add(time.Duration(h) * time.Hour)
+	if err := l.reminderuc.Snooze(ctx, user.ID, until); err != nil {
+		return nil, userError("リマインドが設定されていません")
+	}
+	t := until.In(l.userSettings(ctx, user.ID).Location())
+	return []linebot.SendingMessage{
+		linebot.NewTextMessage(fmt.Sprintf("%d/%d %02d:%02d までリマインドをお休みします", t.Month(), t.Day(), t.Hour(), t.Minute())),
+	}, nil
+}
+
+// snoozeQuickReplies は「開始」とスヌーズの選択肢
+func snoozeQuickReplies() *linebot.QuickReplyItems {
+	items := []*linebot.QuickReplyButton{
+		linebot.NewQuickReplyButton("", linebot.NewPostbackAction("開始", "action=start", "", "開始")),
+	}
+	for _, c := range snoozeChoices {
+		data := "action=snooze&hours=" + strconv.Itoa(c.Hours)
+		items = append(items, linebot.NewQuickReplyButton("", linebot.NewPostbackAction(c.Label, data, "", c.Label)))
+	}
+	return linebot.NewQuickReplyItems(items...)
+}
+
+func (l *lineController) push(lineUserID string, msgs ...linebot.SendingMessage) error {
+	_, err := l.bot.PushMessage(lineUserID, msgs...).Do()
+	return err
+}
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+
+	"github.com/sirasu21/Logbook/backend/lineflow"
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+// 休憩タイマー（セットを記録したあとに始めて、終わったらプッシュで知らせる）
+
+// restChoices は休憩の長さの選択肢（秒）
+var restChoices = []int{60, 90, 120, 180, 300}
+
+// startRestTimer は action=rest_timer(&sec=N) で直前のセットのあとの休憩タイマーを始める。
+// sec が無ければセットの restSec → 種目の既定 → 90 秒。休憩の長さはセットの restSec に残す
+func (l *lineController) startRestTimer(ctx context.Context, uid, sec string) ([]linebot.SendingMessage, error) {
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	ws, name, err := l.latestLineSet(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	restSec := l.restSecFor(ctx, user.ID, ws, sec)
+	if restSec < 1 || restSec > models.MaxRestSec {
+		return nil, userError(fmt.Sprintf("休憩は1秒〜%d分で選んでください", models.MaxRestSec/60))
+	}
+
+	weight, unit := ws.EnteredWeight()
+	if _, err := l.lineuc.StartRestTimer(ctx, models.RestTimer{
+		LineUserID: uid,
+		UserID:     user.ID,
+		WorkoutID:  ws.WorkoutID,
+		SetID:      ws.ID,
+		ExerciseID: ws.ExerciseID,
+		Weight:     weight,
+		WeightUnit: unit,
+		Reps:       ws.Reps,
+		RestSec:    restSec,
+	}); err != nil {
+		log.Printf("❌ 休憩タイマーの開始失敗 / userID=%s / err=%v", user.ID, err)
+		return nil, userError("休憩タイマーを開始できませんでした")
+	}
+	if ws.RestSec == nil || *ws.RestSec != restSec {
+		if _, err := l.workoutSetuc.UpdateSet(ctx, user.ID, ws.ID, nil, models.WorkoutSetUpdateInput{RestSec: &restSec}); err != nil {
+			log.Printf("❌ 休憩の長さの保存失敗 / setID=%s / err=%v", ws.ID, err)
+		}
+	}
+
+	text := fmt.Sprintf("⏱ %s の休憩タイマーを開始しました（%s）\n終わったらお知らせします", formatRest(restSec), name)
+	return []linebot.SendingMessage{linebot.NewTextMessage(text).WithQuickReplies(restTimerQuickReplies(restSec))}, nil
+}
+
+// restSecFor は休憩の長さ（sec → セットの restSec → 種目の既定 → DefaultRestSec の順）
+func (l *lineController) restSecFor(ctx context.Context, userID string, ws *models.WorkoutSet, sec string) int {
+	if n, err := strconv.Atoi(sec); err == nil {
+		return n
+	}
+	if ws.RestSec != nil && *ws.RestSec > 0 {
+		return *ws.RestSec
+	}
+	if ex, err := l.exerciseuc.Get(ctx, userID, ws.ExerciseID); err == nil && ex != nil && ex.DefaultRestSec != nil {
+		return *ex.DefaultRestSec
+	}
+	return models.DefaultRestSec
+}
+
+func (l *lineController) cancelRestTimer(ctx context.Context, uid string) ([]linebot.SendingMessage, error) {
+	stopped, err := l.lineuc.CancelRestTimer(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if !stopped {
+		return nil, userError("動いている休憩タイマーはありません")
+	}
+	return []linebot.SendingMessage{linebot.NewTextMessage("休憩タイマーを止めました")}, nil
+}
+
+// stopRestTimer は次のセットが早めに来たとき・ワークアウトを終えたときに黙ってタイマーを止める
+func (l *lineController) stopRestTimer(ctx context.Context, uid string) {
+	if _, err := l.lineuc.CancelRestTimer(ctx, uid); err != nil {
+		log.Printf("❌ 休憩タイマーの停止失敗 / lineUserID=%s / err=%v", uid, err)
+	}
+}
+
+// stopsRestTimer は休憩タイマーを止める操作か（セットの記録・ワークアウトの終了）
+func stopsRestTimer(step *lineflow.Step) bool {
+	switch step.Input.Trigger {
+	case lineflow.TriggerCountText, lineflow.TriggerEntryConfirm, lineflow.TriggerRepeatLast,
+		lineflow.TriggerEnd, lineflow.TriggerCloseAndStart:
+		return true
+	case lineflow.TriggerQuickEntry:
+		// 種目の確認待ちならまだ記録していない
+		return step.State.State != lineflow.StateConfirmEntry
+	}
+	return false
+}
+
+// PushRestOver は休憩の終わりを知らせ、同じ種目・重さで次のセットに進めるようにする
+func (l *lineController) PushRestOver(ctx context.Context, t models.RestTimer) error {
+	name := l.exerciseName(ctx, t.UserID, t.ExerciseID)
+	text := fmt.Sprintf("⏱ %s の休憩が終わりました！\n次のセット: %s", formatRest(t.RestSec), name)
+	if t.Weight != nil {
+		text += fmt.Sprintf(" %g%s", *t.Weight, t.WeightUnit)
+	}
+
+	data := url.Values{"action": {string(lineflow.TriggerNextSet)}, "exerciseId": {t.ExerciseID}}
+	if t.Weight != nil {
+		data.Set("weight", strconv.FormatFloat(float64(*t.Weight), 'f', -1, 32))
+		data.Set("unit", string(t.WeightUnit))
+	}
+	if t.Reps != nil {
+		data.Set("reps", strconv.Itoa(*t.Reps))
+	}
+	msg := linebot.NewTextMessage(text).WithQuickReplies(linebot.NewQuickReplyItems(
+		linebot.NewQuickReplyButton("", linebot.NewPostbackAction("次のセットを記録", data.Encode(), "", "次のセットを記録")),
+		linebot.NewQuickReplyButton("", linebot.NewPostbackAction("同じ内容で記録", "action=repeat_last", "", "もう1セット")),
+	))
+	return l.push(t.LineUserID, msg)
+}
+
+// beginNextSet は休憩のあとの「次のセットを記録」。種目と重さは埋めて、回数（重さが無ければ重さ）を聞く
+func (l *lineController) beginNextSet(ctx context.Context, uid string, step *lineflow.Step) ([]linebot.SendingMessage, error) {
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	p := step.State.Pending
+	text := l.exerciseName(ctx, user.ID, p.ExerciseID)
+	if p.Weight != nil {
+		unit := p.WeightUnit
+		if unit == "" {
+			unit = l.weightUnit(ctx, user.ID)
+		}
+		text += fmt.Sprintf(" %g%s", *p.Weight, unit)
+	}
+	text += "\n" + l.flow.Prompt(step.State.State)
+
+	buttons := []*linebot.QuickReplyButton{}
+	// 前回の回数の前後をそのまま送れるように
+	if reps, err := strconv.Atoi(step.Input.Params.Get("reps")); err == nil && p.Weight != nil {
+		for n := max(reps-2, 1); n <= reps+2; n++ {
+			label := fmt.Sprintf("%d回", n)
+			buttons = append(buttons, linebot.NewQuickReplyButton("", linebot.NewMessageAction(label, strconv.Itoa(n))))
+		}
+	}
+	buttons = append(buttons, linebot.NewQuickReplyButton("", linebot.NewPostbackAction("キャンセル", "action=cancel", "", "キャンセル")))
+	return []linebot.SendingMessage{linebot.NewTextMessage(text).WithQuickReplies(linebot.NewQuickReplyItems(buttons...))}, nil
+}
+
+// restTimerQuickReplies は休憩の長さの変更（今の長さ以外）と停止
+func restTimerQuickReplies(current int) *linebot.QuickReplyItems {
+	var buttons []*linebot.QuickReplyButton
+	for _, sec := range restChoices {
+		if sec == current {
+			continue
+		}
+		label := formatRest(sec) + "に変更"
+		data := fmt.Sprintf("action=%s&sec=%d", lineflow.TriggerRestTimer, sec)
+		buttons = append(buttons, linebot.NewQuickReplyButton("", linebot.NewPostbackAction(label, data, "", label)))
+	}
+	buttons = append(buttons, linebot.NewQuickReplyButton("", linebot.NewPostbackAction("タイマーを止める", "action="+string(lineflow.TriggerCancelRest), "", "タイマーを止める")))
+	return linebot.NewQuickReplyItems(buttons...)
+}
+
+// formatRest は「45秒」「2分」「1分30秒」
+func formatRest(sec int) string {
+	switch {
+	case sec < 60:
+		return fmt.Sprintf("%d秒", sec)
+	case sec%60 == 0:
+		return fmt.Sprintf("%d分", sec/60)
+	}
+	return fmt.Sprintf("%d分%d秒", sec/60, sec%60)
+}
@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+
+	"github.com/sirasu21/Logbook/backend/lineflow"
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+// 設定（タイムゾーン・週の始まり・重さの単位・表示言語）の確認と変更
+
+type settingChoice struct {
+	Label string
+	Value string
+}
+
+// settingItems は LINE から選べる項目と選択肢（quick reply は 13 個まで）。
+// ここにないタイムゾーンは Web の設定から選ぶ
+var settingItems = []struct {
+	Key     string
+	Label   string
+	Choices []settingChoice
+}{
+	{"timezone", "タイムゾーン", []settingChoice{
+		{"日本", "Asia/Tokyo"},
+		{"韓国", "Asia/Seoul"},
+		{"シンガポール", "Asia/Singapore"},
+		{"シドニー", "Australia/Sydney"},
+		{"UTC", "UTC"},
+		{"ロンドン", "Europe/London"},
+		{"ニューヨーク", "America/New_York"},
+		{"ロサンゼルス", "America/Los_Angeles"},
+	}},
+	{"week_start", "週の始まり", []settingChoice{{"月曜", "1"}, {"日曜", "0"}}},
+	{"weight_unit", "重さの単位", []settingChoice{{"kg", "kg"}, {"lb", "lb"}}},
+	{"locale", "表示言語", []settingChoice{{"日本語", "ja-JP"}, {"English", "en-US"}}},
+}
+
+// userSettings はユーザーの設定。読めなければ既定値で続ける
+func (l *lineController) userSettings(ctx context.Context, userID string) *models.UserSettings {
+	s, err := l.settingsuc.Get(ctx, userID)
+	if err != nil {
+		log.Printf("❌ ユーザー設定の取得失敗 / userID=%s / err=%v", userID, err)
+		return models.DefaultUserSettings(userID)
+	}
+	return s
+}
+
+// weightUnit はユーザー設定の重さの単位（単位を付けずに送った重さと、集計の表示に使う）
+func (l *lineController) weightUnit(ctx context.Context, userID string) models.WeightUnit {
+	return l.userSettings(ctx, userID).WeightUnit
+}
+
+// settingsMessages は key が空なら今の設定と項目を、あれば項目の選択肢を出す
+func (l *lineController) settingsMessages(ctx context.Context, uid, key string) ([]linebot.SendingMessage, error) {
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	s := l.userSettings(ctx, user.ID)
+	if key == "" {
+		return []linebot.SendingMessage{
+			linebot.NewTextMessage(settingsText(s) + "\n\n変更する項目を選んでください").WithQuickReplies(settingItemQuickReplies()),
+		}, nil
+	}
+
+	for _, item := range settingItems {
+		if item.Key != key {
+			continue
+		}
+		var buttons []*linebot.QuickReplyButton
+		for _, c := range item.Choices {
+			data := url.Values{"action": {string(lineflow.TriggerSetSetting)}, "key": {key}, "value": {c.Value}}
+			buttons = append(buttons, linebot.NewQuickReplyButton("", linebot.NewPostbackAction(c.Label, data.Encode(), "", c.Label)))
+		}
+		text := fmt.Sprintf("%sを選んでください（今: %s）", item.Label, settingValueLabel(s, key))
+		return []linebot.SendingMessage{
+			linebot.NewTextMessage(text).WithQuickReplies(linebot.NewQuickReplyItems(buttons...)),
+		}, nil
+	}
+	return nil, userError("その設定項目はありません")
+}
+
+// applySetting は action=set_setting&key=...&value=... を保存する
+func (l *lineController) applySetting(ctx context.Context, uid, key, value string) ([]linebot.SendingMessage, error) {
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	var in usecase.SaveUserSettingsInput
+	switch key {
+	case "timezone":
+		in.Timezone = &value
+	case "week_start":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, userError("週の始まりを読み取れませんでした")
+		}
+		in.WeekStart = &n
+	case "weight_unit":
+		u := models.WeightUnit(value)
+		in.WeightUnit = &u
+	case "locale":
+		in.Locale = &value
+	default:
+		return nil, userError("その設定項目はありません")
+	}
+	s, err := l.settingsuc.Save(ctx, user.ID, in)
+	if err != nil {
+		return nil, userError("設定を変更できませんでした: " + err.Error())
+	}
+	return []linebot.SendingMessage{
+		linebot.NewTextMessage("設定を変更しました\n" + settingsText(s)).WithQuickReplies(settingItemQuickReplies()),
+	}, nil
+}
+
+func settingsText(s *models.UserSettings) string {
+	lines := []string{"⚙️ 設定"}
+	for _, item := range settingItems {
+		lines = append(lines, fmt.Sprintf("%s: %s", item.Label, settingValueLabel(s, item.Key)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// settingValueLabel は今の値の表示名（選択肢にない値はそのまま）
+func settingValueLabel(s *models.UserSettings, key string) string {
+	var v string
+	switch key {
+	case "timezone":
+		v = s.Timezone
+	case "week_start":
+		return weekdaysJa[s.WeekStartDay()%7] + "曜"
+	case "weight_unit":
+		v = string(s.WeightUnit)
+	case "locale":
+		v = s.Locale
+	}
+	for _, item := range settingItems {
+		if item.Key != key {
+			continue
+		}
+		for _, c := range item.Choices {
+			if c.Value == v && c.Label != v {
+				return fmt.Sprintf("%s（%s）", c.Label, v)
+			}
+		}
+	}
+	return v
+}
+
+func settingItemQuickReplies() *linebot.QuickReplyItems {
+	var buttons []*linebot.QuickReplyButton
+	for _, item := range settingItems {
+		data := url.Values{"action": {string(lineflow.TriggerSettings)}, "key": {item.Key}}
+		buttons = append(buttons, linebot.NewQuickReplyButton("", linebot.NewPostbackAction(item.Label, data.Encode(), "", item.Label)))
+	}
+	return linebot.NewQuickReplyItems(buttons...)
+}
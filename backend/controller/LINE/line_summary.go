@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+// 「今日の記録」「今週の記録」カード
+
+// カードに並べる種目数の上限（超えた分は「他 N 種目」）
+const summaryMaxExercises = 8
+
+var weekdaysJa = [...]string{"日", "月", "火", "水", "木", "金", "土"}
+
+// summaryMessages は today / week のカードを返す。日付と週の区切りはユーザーの設定に従う。
+// 比較対象は先週の同じ期間（today なら先週の同じ曜日、week なら先週の同じ時点まで）。
+func (l *lineController) summaryMessages(ctx context.Context, userID string, now time.Time, weekly bool) ([]linebot.SendingMessage, error) {
+	settings := l.userSettings(ctx, userID)
+	loc := settings.Location()
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	from, to := today, today.AddDate(0, 0, 1)
+	title := fmt.Sprintf("今日の記録 %d/%d(%s)", now.Month(), now.Day(), weekdaysJa[now.Weekday()])
+	if weekly {
+		from = today.AddDate(0, 0, -((int(now.Weekday()) - settings.WeekStart + 7) % 7))
+		to = now
+		end := from.AddDate(0, 0, 6)
+		title = fmt.Sprintf("今週の記録 %d/%d〜%d/%d", from.Month(), from.Day(), end.Month(), end.Day())
+	}
+
+	cur, err := l.summaryuc.Period(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := l.summaryuc.Period(ctx, userID, from.AddDate(0, 0, -7), to.AddDate(0, 0, -7))
+	if err != nil {
+		return nil, err
+	}
+	streak, err := l.summaryuc.Streak(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	bubble := summaryBubble(title, cur, prev, streak, weekly, settings.WeightUnit)
+	return []linebot.SendingMessage{linebot.NewFlexMessage(title, bubble)}, nil
+}
+
+func summaryBubble(title string, cur, prev *models.PeriodSummary, streak int, weekly bool, unit models.WeightUnit) *linebot.BubbleContainer {
+	body := []linebot.FlexComponent{}
+
+	if len(cur.Exercises) == 0 {
+		body = append(body, summaryText("まだ記録がありません", linebot.FlexTextSizeTypeSm, "#94a3b8"))
+	}
+	for i, ex := range cur.Exercises {
+		if i == summaryMaxExercises {
+			body = append(body, summaryText(fmt.Sprintf("他 %d 種目", len(cur.Exercises)-i), linebot.FlexTextSizeTypeXs, "#94a3b8"))
+			break
+		}
+		name := summaryText(ex.Name, linebot.FlexTextSizeTypeSm, "#333333")
+		name.Weight = linebot.FlexTextWeightTypeBold
+		name.Margin = linebot.FlexComponentMarginTypeMd
+		body = append(body, name)
+		for _, s := range ex.Sets {
+			body = append(body, summaryText("・"+describeSetSummary(s, unit), linebot.FlexTextSizeTypeXs, "#475569"))
+		}
+	}
+
+	prevLabel := "先週の同じ曜日"
+	if weekly {
+		prevLabel = "先週の同じ時点"
+	}
+	stats := []linebot.FlexComponent{
+		&linebot.SeparatorComponent{Type: linebot.FlexComponentTypeSeparator, Margin: linebot.FlexComponentMarginTypeLg},
+		summaryRow("合計ボリューム", fmt.Sprintf("%s%s", formatVolume(models.FromKg(cur.VolumeKg, unit)), unit)),
+		summaryRow("トレーニング時間", formatDuration(cur.Duration)),
+		summaryRow("セット数", fmt.Sprintf("%d（%s %s）", cur.Sets, prevLabel, formatDiff(cur.Sets-prev.Sets))),
+		summaryRow("連続トレーニング", fmt.Sprintf("%d日", streak)),
+	}
+	if weekly {
+		stats = append(stats, summaryRow("トレーニング日数", fmt.Sprintf("%d日", cur.Days)))
+	}
+	body = append(body, stats...)
+
+	return &linebot.BubbleContainer{
+		Type: linebot.FlexContainerTypeBubble,
+		Size: linebot.FlexBubbleSizeTypeMega,
+		Header: &linebot.BoxComponent{
+			Type:            linebot.FlexComponentTypeBox,
+			Layout:          linebot.FlexBoxLayoutTypeVertical,
+			BackgroundColor: "#0ea5e9",
+			Contents: []linebot.FlexComponent{
+				&linebot.TextComponent{
+					Type:   linebot.FlexComponentTypeText,
+					Text:   title,
+					Weight: linebot.FlexTextWeightTypeBold,
+					Size:   linebot.FlexTextSizeTypeMd,
+					Color:  "#FFFFFF",
+				},
+			},
+		},
+		Body: &linebot.BoxComponent{
+			Type:     linebot.FlexComponentTypeBox,
+			Layout:   linebot.FlexBoxLayoutTypeVertical,
+			Spacing:  linebot.FlexComponentSpacingTypeXs,
+			Contents: body,
+		},
+	}
+}
+
+func summaryText(text string, size linebot.FlexTextSizeType, color string) *linebot.TextComponent {
+	return &linebot.TextComponent{
+		Type:  linebot.FlexComponentTypeText,
+		Text:  text,
+		Size:  size,
+		Color: color,
+		Wrap:  true,
+	}
+}
+
+func summaryRow(label, value string) *linebot.BoxComponent {
+	v := summaryText(value, linebot.FlexTextSizeTypeSm, "#333333")
+	v.Align = linebot.FlexComponentAlignTypeEnd
+	v.Weight = linebot.FlexTextWeightTypeBold
+	return &linebot.BoxComponent{
+		Type:   linebot.FlexComponentTypeBox,
+		Layout: linebot.FlexBoxLayoutTypeHorizontal,
+		Margin: linebot.FlexComponentMarginTypeSm,
+		Contents: []linebot.FlexComponent{
+			summaryText(label, linebot.FlexTextSizeTypeSm, "#64748b"),
+			v,
+		},
+	}
+}
+
+// describeSetSummary は「60kg × 8回 × 3セット」形式にする（重さは unit で）
+func describeSetSummary(s models.SetSummary, unit models.WeightUnit) string {
+	line := "—"
+	if s.Reps != nil {
+		line = fmt.Sprintf("%d回", *s.Reps)
+	}
+	if s.WeightKg != nil {
+		line = fmt.Sprintf("%s × %s", formatKgIn(s.WeightKg, unit), line)
+	}
+	if s.Count > 1 {
+		line += fmt.Sprintf(" × %dセット", s.Count)
+	}
+	return line
+}
+
+func formatVolume(v float64) string {
+	n := int64(v + 0.5)
+	s := fmt.Sprint(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+func formatDuration(d time.Duration) string {
+	m := int(d.Minutes())
+	if m < 60 {
+		return fmt.Sprintf("%d分", m)
+	}
+	return fmt.Sprintf("%d時間%02d分", m/60, m%60)
+}
+
+func formatDiff(n int) string {
+	switch {
+	case n > 0:
+		return fmt.Sprintf("+%d", n)
+	case n == 0:
+		return "±0"
+	}
+	return fmt.Sprint(n)
+}
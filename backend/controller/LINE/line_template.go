@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+
+	"github.com/sirasu21/Logbook/backend/lineflow"
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+// テンプレート（Push / Pull / Legs など）からの開始
+
+// quick reply は 13 個まで。最後の 1 つは「そのまま開始」に使う
+const maxTemplateChoices = 12
+
+// templateChoices はテンプレートを「そのまま開始」と並べて出す
+func (l *lineController) templateChoices(ctx context.Context, uid string) ([]linebot.SendingMessage, error) {
+	user, err := l.getOrCreateUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	items, err := l.templateuc.List(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, userError("テンプレートがまだありません。Web の「テンプレート」から作成できます")
+	}
+
+	var buttons []*linebot.QuickReplyButton
+	for i, t := range items {
+		if i == maxTemplateChoices {
+			break
+		}
+		data := fmt.Sprintf("action=%s&templateId=%s", lineflow.TriggerStartTemplate, t.ID)
+		buttons = append(buttons, linebot.NewQuickReplyButton("", linebot.NewPostbackAction(truncateLabel(t.Name), data, "", t.Name)))
+	}
+	buttons = append(buttons, linebot.NewQuickReplyButton("", linebot.NewPostbackAction("そのまま開始", "action=start", "", "開始")))
+	return []linebot.SendingMessage{
+		linebot.NewTextMessage("どのメニューで始めますか？").WithQuickReplies(linebot.NewQuickReplyItems(buttons...)),
+	}, nil
+}
+
+func (l *lineController) startFromTemplate(ctx context.Context, uid string, step *lineflow.Step) ([]linebot.SendingMessage, error) {
+	return l.startWorkout(ctx, uid, step, workoutSource{TemplateID: step.Input.Params.Get("templateId")})
+}
+
+// beginTemplateWorkout はテンプレートから開始して、予定のセットを一覧で見せる
+func (l *lineController) beginTemplateWorkout(ctx context.Context, user *models.User, step *lineflow.Step, templateID string) ([]linebot.SendingMessage, error) {
+	d, err := l.templateuc.Start(ctx, user.ID, templateID, true)
+	if err != nil {
+		if usecase.IsNotFound(err) {
+			return nil, userError("テンプレートが見つかりません")
+		}
+		return nil, err
+	}
+	step.State.WorkoutID = d.Workout.ID
+
+	name := "テンプレート"
+	if d.Workout.Note != nil {
+		name = *d.Workout.Note
+	}
+	return withMenu("add", l.plannedSetsMessage(ctx, user.ID, fmt.Sprintf("「%s」を開始しました！", name), d.Sets)), nil
+}
+
+// plannedSetsMessage は予定のセットを種目ごとに並べたメッセージ
+func (l *lineController) plannedSetsMessage(ctx context.Context, userID, header string, sets []models.WorkoutSet) linebot.SendingMessage {
+	lines := []string{header}
+	unit := l.weightUnit(ctx, userID)
+	for _, p := range groupPlannedSets(sets) {
+		lines = append(lines, fmt.Sprintf("・%s %s", l.exerciseName(ctx, userID, p.exerciseID), describeSetSummary(p.SetSummary, unit)))
+	}
+	lines = append(lines, "記録すると予定のセットが順に埋まります")
+	return linebot.NewTextMessage(strings.Join(lines, "\n"))
+}
+
+type plannedGroup struct {
+	exerciseID string
+	models.SetSummary
+}
+
+// groupPlannedSets は同じ種目・同じ目標が続くセットをまとめる
+func groupPlannedSets(sets []models.WorkoutSet) []plannedGroup {
+	var out []plannedGroup
+	for _, s := range sets {
+		if n := len(out); n > 0 {
+			last := &out[n-1]
+			if last.exerciseID == s.ExerciseID && equalIntPtr(last.Reps, s.Reps) && equalFloat32Ptr(last.WeightKg, s.WeightKg) {
+				last.Count++
+				continue
+			}
+		}
+		out = append(out, plannedGroup{exerciseID: s.ExerciseID, SetSummary: models.SetSummary{Reps: s.Reps, WeightKg: s.WeightKg, Count: 1}})
+	}
+	return out
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+
+	usecaseLine "github.com/sirasu21/Logbook/backend/usecase/LINE"
+)
+
+// webhookEvent は linebot.Event に、SDK(v7) が読み捨てる重複排除用の項目を足したもの
+type webhookEvent struct {
+	*linebot.Event
+	WebhookEventID string
+	IsRedelivery   bool
+	Raw            json.RawMessage // キューに積む元の JSON
+}
+
+type rawWebhookMeta struct {
+	WebhookEventID  string `json:"webhookEventId"`
+	DeliveryContext struct {
+		IsRedelivery bool `json:"isRedelivery"`
+	} `json:"deliveryContext"`
+}
+
+// userError は利用者にそのまま返信して終えるエラー（リトライしても結果が変わらないもの）
+type userError string
+
+func (e userError) Error() string { return string(e) }
+
+// parseWebhook は署名を検証してイベントを取り出す（webhookEventId / isRedelivery 付き）
+func (l *lineController) parseWebhook(r *http.Request) ([]webhookEvent, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	// 署名検証は SDK に任せる
+	if _, err := l.bot.ParseRequest(r); err != nil {
+		return nil, err
+	}
+
+	var req struct {
+		Events []json.RawMessage `json:"events"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	out := make([]webhookEvent, 0, len(req.Events))
+	for _, raw := range req.Events {
+		ev, err := decodeWebhookEvent(raw)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+func decodeWebhookEvent(raw []byte) (webhookEvent, error) {
+	event := &linebot.Event{}
+	if err := event.UnmarshalJSON(raw); err != nil {
+		return webhookEvent{}, err
+	}
+	var meta rawWebhookMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return webhookEvent{}, err
+	}
+	return webhookEvent{
+		Event:          event,
+		WebhookEventID: meta.WebhookEventID,
+		IsRedelivery:   meta.DeliveryContext.IsRedelivery,
+		Raw:            raw,
+	}, nil
+}
+
+// orderingKey は同じ相手のイベントを順番に処理するためのキー
+func (ev webhookEvent) orderingKey() string {
+	if ev.Source == nil {
+		return ""
+	}
+	switch {
+	case ev.Source.UserID != "":
+		return ev.Source.UserID
+	case ev.Source.GroupID != "":
+		return ev.Source.GroupID
+	default:
+		return ev.Source.RoomID
+	}
+}
+
+// HandleQueuedEvent はワーカーから呼ばれる。エラーを返すとワーカーがリトライする
+func (l *lineController) HandleQueuedEvent(ctx context.Context, payload []byte) error {
+	ev, err := decodeWebhookEvent(payload)
+	if err != nil {
+		return err
+	}
+	return l.processEvent(ctx, ev)
+}
+
+// processEvent は webhookEventId で重複を弾いてから 1 イベントを処理する。
+// 処理済みのイベント（再送を含む）は何もしない。
+func (l *lineController) processEvent(ctx context.Context, ev webhookEvent) error {
+	return processOnce(ctx, l.lineuc, ev, func(ev webhookEvent) error {
+		err := l.handleEvent(ev.Event, ev.WebhookEventID)
+		var uerr userError
+		if errors.As(err, &uerr) {
+			l.replyText(ev.ReplyToken, uerr.Error())
+			return nil
+		}
+		return err
+	})
+}
+
+// processOnce は handle が成功したときだけイベントを処理済みにする。
+// 処理中の印は短い TTL で付けるので、途中でワーカーが落ちても再送・引き取りで処理し直せる。
+func processOnce(ctx context.Context, lineuc usecaseLine.LineUsecase, ev webhookEvent, handle func(webhookEvent) error) error {
+	if ev.WebhookEventID == "" {
+		return handle(ev)
+	}
+
+	first, err := lineuc.ClaimEvent(ctx, ev.WebhookEventID)
+	if err != nil {
+		// 処理中（ErrEventInProgress）なら ACK させずに後でやり直す
+		return err
+	}
+	if !first {
+		log.Printf("skip duplicate LINE event / eventID=%s / redelivery=%v", ev.WebhookEventID, ev.IsRedelivery)
+		return nil
+	}
+
+	if err := handle(ev); err != nil {
+		// リトライで再処理できるように戻す
+		_ = lineuc.ReleaseEvent(ctx, ev.WebhookEventID)
+		return err
+	}
+	if err := lineuc.CompleteEvent(ctx, ev.WebhookEventID); err != nil {
+		// 処理自体は済んでいるのでリトライはさせない（処理中の印が切れるまでは再送も弾かれる）
+		log.Printf("❌ LINE event complete failed / eventID=%s / err=%v", ev.WebhookEventID, err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/line/line-bot-sdk-go/linebot"
+
+	repositoryLine "github.com/sirasu21/Logbook/backend/repository/LINE"
+	usecaseLine "github.com/sirasu21/Logbook/backend/usecase/LINE"
+)
+
+const testChannelSecret = "test-channel-secret"
+
+// loadWebhook は testdata の Webhook を署名付きリクエストにして parseWebhook で読む
+func loadWebhook(t *testing.T, l *lineController, name string) []webhookEvent {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte(testChannelSecret))
+	mac.Write(body)
+	req := httptest.NewRequest(http.MethodPost, "/line/webhook", bytes.NewReader(body))
+	req.Header.Set("X-Line-Signature", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+	events, err := l.parseWebhook(req)
+	if err != nil {
+		t.Fatalf("parseWebhook(%s): %v", name, err)
+	}
+	return events
+}
+
+func newWebhookTestController(t *testing.T) *lineController {
+	t.Helper()
+	bot, err := linebot.New(testChannelSecret, "test-channel-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lineuc := usecaseLine.NewLineUsecase(repositoryLine.NewMemoryLineRepository(), nil, nil)
+	return &lineController{bot: bot, lineuc: lineuc}
+}
+
+func TestProcessOnceRedeliveryIsNoop(t *testing.T) {
+	l := newWebhookTestController(t)
+	first := loadWebhook(t, l, "webhook_text_message.json")
+	again := loadWebhook(t, l, "webhook_text_message_redelivery.json")
+	if len(first) != 1 || len(again) != 1 {
+		t.Fatalf("events = %d, %d, want 1, 1", len(first), len(again))
+	}
+	if first[0].WebhookEventID != again[0].WebhookEventID || !again[0].IsRedelivery {
+		t.Fatalf("fixture is not a redelivery: %+v / %+v", first[0], again[0])
+	}
+
+	calls := 0
+	handle := func(webhookEvent) error { calls++; return nil }
+	ctx := context.Background()
+	for _, ev := range append(first, again...) {
+		if err := processOnce(ctx, l.lineuc, ev, handle); err != nil {
+			t.Fatalf("processOnce: %v", err)
+		}
+	}
+	// キューから同じメッセージがもう一度来ても処理しない
+	if err := processOnce(ctx, l.lineuc, first[0], handle); err != nil {
+		t.Fatalf("processOnce: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls)
+	}
+}
+
+func TestProcessOnceRetriesAfterFailure(t *testing.T) {
+	l := newWebhookTestController(t)
+	ev := loadWebhook(t, l, "webhook_text_message.json")[0]
+	redelivery := loadWebhook(t, l, "webhook_text_message_redelivery.json")[0]
+	ctx := context.Background()
+
+	boom := errors.New("db is down")
+	calls := 0
+	failing := func(webhookEvent) error { calls++; return boom }
+	ok := func(webhookEvent) error { calls++; return nil }
+
+	if err := processOnce(ctx, l.lineuc, ev, failing); !errors.Is(err, boom) {
+		t.Fatalf("first attempt err = %v, want %v", err, boom)
+	}
+	// 失敗したイベントは再送で処理し直せる
+	if err := processOnce(ctx, l.lineuc, redelivery, ok); err != nil {
+		t.Fatalf("redelivery: %v", err)
+	}
+	if err := processOnce(ctx, l.lineuc, redelivery, ok); err != nil {
+		t.Fatalf("second redelivery: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("handler calls = %d, want 2", calls)
+	}
+}
+
+func TestProcessOnceInFlightEventIsNotDropped(t *testing.T) {
+	l := newWebhookTestController(t)
+	redelivery := loadWebhook(t, l, "webhook_text_message_redelivery.json")[0]
+	ctx := context.Background()
+
+	// 別のワーカーが処理中（または処理の途中で落ちた）
+	if first, err := l.lineuc.ClaimEvent(ctx, redelivery.WebhookEventID); err != nil || !first {
+		t.Fatalf("ClaimEvent = %v, %v", first, err)
+	}
+
+	calls := 0
+	err := processOnce(ctx, l.lineuc, redelivery, func(webhookEvent) error { calls++; return nil })
+	// 処理済み扱いで ACK されないように、エラーで返してキューに残す
+	if !errors.Is(err, usecaseLine.ErrEventInProgress) {
+		t.Fatalf("err = %v, want ErrEventInProgress", err)
+	}
+	if calls != 0 {
+		t.Fatalf("handler calls = %d, want 0", calls)
+	}
+}
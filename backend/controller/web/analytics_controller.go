@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	echoSession "github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+type AnalyticsController interface {
+	// GET /api/exercises/:id/progress?from=&to=&formula=epley|brzycki|lombardi
+	ExerciseProgress(c echo.Context) error
+	// GET /api/analytics/muscle-volume?week=YYYY-MM-DD&weeks=
+	MuscleVolume(c echo.Context) error
+}
+
+type analyticsController struct {
+	cfg models.Config
+	uc  usecase.AnalyticsUsecase
+}
+
+func NewAnalyticsController(cfg models.Config, uc usecase.AnalyticsUsecase) AnalyticsController {
+	return &analyticsController{cfg: cfg, uc: uc}
+}
+
+func (h *analyticsController) currentUserID(c echo.Context) string {
+	if uid, _ := c.Get("userID").(string); uid != "" {
+		return uid
+	}
+	sess, _ := echoSession.Get("session", c)
+	sub, _ := sess.Values["user_id"].(string)
+	return sub
+}
+
+func (h *analyticsController) ExerciseProgress(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	from, to, ok := parseRange(c)
+	if !ok {
+		return nil
+	}
+	formula := models.E1RMFormula(c.QueryParam("formula"))
+	if formula != "" && !formula.Valid() {
+		return c.String(http.StatusBadRequest, "invalid 'formula' (epley, brzycki, lombardi)")
+	}
+
+	out, err := h.uc.ExerciseProgress(c.Request().Context(), userID, c.Param("id"), usecase.ProgressFilter{
+		From: from, To: to, Formula: formula,
+	})
+	if err != nil {
+		if usecase.IsNotFound(err) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *analyticsController) MuscleVolume(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	var week *time.Time
+	if v := c.QueryParam("week"); v != "" {
+		tp, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "invalid 'week' (YYYY-MM-DD)")
+		}
+		week = &tp
+	}
+	weeks := 1
+	if v := c.QueryParam("weeks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > usecase.MaxMuscleVolumeWeeks {
+			return c.String(http.StatusBadRequest, "invalid 'weeks'")
+		}
+		weeks = n
+	}
+
+	out, err := h.uc.MuscleVolume(c.Request().Context(), userID, week, weeks)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// parseRange は from / to（RFC3339, どちらも省略可）を読む。失敗したら 400 を返して ok=false
+func parseRange(c echo.Context) (from, to *time.Time, ok bool) {
+	if v := c.QueryParam("from"); v != "" {
+		tp, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.String(http.StatusBadRequest, "invalid 'from' (RFC3339)")
+			return nil, nil, false
+		}
+		from = &tp
+	}
+	if v := c.QueryParam("to"); v != "" {
+		tp, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.String(http.StatusBadRequest, "invalid 'to' (RFC3339)")
+			return nil, nil, false
+		}
+		to = &tp
+	}
+	if from != nil && to != nil && from.After(*to) {
+		c.String(http.StatusBadRequest, "'from' must be <= 'to'")
+		return nil, nil, false
+	}
+	return from, to, true
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	echoSession "github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+type AuditController interface {
+	// GET /api/workouts/:id/history ワークアウトとそのセットの変更履歴 ?entityType=&from=&to=&limit=&offset=
+	WorkoutHistory(c echo.Context) error
+	// GET /api/activity 自分のデータの変更履歴 ?entityType=&from=&to=&limit=&offset=
+	Activity(c echo.Context) error
+}
+
+type auditController struct {
+	cfg models.Config
+	uc  usecase.AuditUsecase
+}
+
+func NewAuditController(cfg models.Config, uc usecase.AuditUsecase) AuditController {
+	return &auditController{cfg: cfg, uc: uc}
+}
+
+func (h *auditController) currentUserID(c echo.Context) string {
+	sess, _ := echoSession.Get("session", c)
+	if sub, _ := sess.Values["user_id"].(string); sub != "" {
+		return sub
+	}
+	return ""
+}
+
+func (h *auditController) WorkoutHistory(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	in, err := parseAuditQuery(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	out, err := h.uc.WorkoutHistory(c.Request().Context(), userID, c.Param("id"), in)
+	if err != nil {
+		if usecase.IsNotFound(err) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *auditController) Activity(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	in, err := parseAuditQuery(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	out, err := h.uc.Activity(c.Request().Context(), userID, in)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// parseAuditQuery は entityType・from / to（RFC3339）・limit・offset を読む
+func parseAuditQuery(c echo.Context) (usecase.AuditListInput, error) {
+	in := usecase.AuditListInput{EntityType: c.QueryParam("entityType")}
+	in.Limit, _ = strconv.Atoi(c.QueryParam("limit"))
+	in.Offset, _ = strconv.Atoi(c.QueryParam("offset"))
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &in.From}, {"to", &in.To}} {
+		v := c.QueryParam(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return in, fmt.Errorf("invalid %s", p.name)
+		}
+		*p.dst = &t
+	}
+	return in, nil
+}
@@ -15,17 +15,20 @@ import (
 type BodyMetricController interface {
 	List(c echo.Context) error
 	Create(c echo.Context) error
+	// GET /api/body_metrics/:id（ETag は If-Match 用）
+	Get(c echo.Context) error
 	Update(c echo.Context) error
 	Delete(c echo.Context) error
 }
 
 type bodyMetricController struct {
-	cfg models.Config
-	uc  usecase.BodyMetricUsecase
+	cfg      models.Config
+	uc       usecase.BodyMetricUsecase
+	settings usecase.UserSettingsUsecase
 }
 
-func NewBodyMetricController(cfg models.Config, uc usecase.BodyMetricUsecase) BodyMetricController {
-	return &bodyMetricController{cfg: cfg, uc: uc}
+func NewBodyMetricController(cfg models.Config, uc usecase.BodyMetricUsecase, settings usecase.UserSettingsUsecase) BodyMetricController {
+	return &bodyMetricController{cfg: cfg, uc: uc, settings: settings}
 }
 
 func (h *bodyMetricController) currentUserID(c echo.Context) string {
@@ -74,6 +77,10 @@ func (h *bodyMetricController) List(c echo.Context) error {
 	if err != nil {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
+	unit := preferredWeightUnit(c, h.settings, userID)
+	for i := range out.Items {
+		out.Items[i].Localize(unit)
+	}
 	return c.JSON(http.StatusOK, out)
 }
 
@@ -86,13 +93,33 @@ func (h *bodyMetricController) Create(c echo.Context) error {
 	if err := c.Bind(&in); err != nil {
 		return c.String(http.StatusBadRequest, "invalid body")
 	}
+	unit := preferredWeightUnit(c, h.settings, userID)
+	if in.Weight != nil && in.WeightUnit == "" {
+		in.WeightUnit = unit
+	}
 	m, err := h.uc.Create(c.Request().Context(), userID, in)
 	if err != nil {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
+	m.Localize(unit)
+	setETag(c, m.Version)
 	return c.JSON(http.StatusCreated, m)
 }
 
+func (h *bodyMetricController) Get(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	m, err := h.uc.Get(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return c.NoContent(http.StatusNotFound) // 自分のデータ以外 or 無い
+	}
+	m.Localize(preferredWeightUnit(c, h.settings, userID))
+	setETag(c, m.Version)
+	return c.JSON(http.StatusOK, m)
+}
+
 func (h *bodyMetricController) Update(c echo.Context) error {
 	userID := h.currentUserID(c)
 	if userID == "" {
@@ -103,10 +130,23 @@ func (h *bodyMetricController) Update(c echo.Context) error {
 	if err := c.Bind(&in); err != nil {
 		return c.String(http.StatusBadRequest, "invalid body")
 	}
-	m, err := h.uc.Update(c.Request().Context(), userID, id, in)
+	unit := preferredWeightUnit(c, h.settings, userID)
+	if in.Weight != nil && in.WeightUnit == "" {
+		in.WeightUnit = unit
+	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return preconditionFailed(c)
+	}
+	m, err := h.uc.Update(c.Request().Context(), userID, id, version, in)
 	if err != nil {
+		if usecase.IsVersionConflict(err) {
+			return versionConflict(c, version)
+		}
 		return c.NoContent(http.StatusNotFound) // 自分のデータ以外 or 無い
 	}
+	m.Localize(unit)
+	setETag(c, m.Version)
 	return c.JSON(http.StatusOK, m)
 }
 
@@ -116,7 +156,14 @@ func (h *bodyMetricController) Delete(c echo.Context) error {
 		return c.NoContent(http.StatusUnauthorized)
 	}
 	id := c.Param("id")
-	if err := h.uc.Delete(c.Request().Context(), userID, id); err != nil {
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return preconditionFailed(c)
+	}
+	if err := h.uc.Delete(c.Request().Context(), userID, id, version); err != nil {
+		if usecase.IsVersionConflict(err) {
+			return versionConflict(c, version)
+		}
 		return c.NoContent(http.StatusNotFound)
 	}
 	return c.NoContent(http.StatusNoContent)
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	echoSession "github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+type CalendarController interface {
+	// GET /api/calendar?year=&month=&tz=&target=
+	Month(c echo.Context) error
+}
+
+type calendarController struct {
+	cfg models.Config
+	uc  usecase.CalendarUsecase
+}
+
+func NewCalendarController(cfg models.Config, uc usecase.CalendarUsecase) CalendarController {
+	return &calendarController{cfg: cfg, uc: uc}
+}
+
+func (h *calendarController) currentUserID(c echo.Context) string {
+	if uid, _ := c.Get("userID").(string); uid != "" {
+		return uid
+	}
+	sess, _ := echoSession.Get("session", c)
+	sub, _ := sess.Values["user_id"].(string)
+	return sub
+}
+
+func (h *calendarController) Month(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	q, ok := parseCalendarQuery(c)
+	if !ok {
+		return nil
+	}
+	out, err := h.uc.Month(c.Request().Context(), userID, q, time.Now())
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// parseCalendarQuery は year / month（省略時は tz での今月）・tz（省略時はユーザーの設定）・target を読む。
+// 失敗したら 400 を返して ok=false
+func parseCalendarQuery(c echo.Context) (usecase.CalendarQuery, bool) {
+	q := usecase.CalendarQuery{Timezone: c.QueryParam("tz"), TargetDays: usecase.DefaultWeeklyTargetDays}
+	if q.Timezone != "" {
+		if _, err := time.LoadLocation(q.Timezone); err != nil || q.Timezone == "Local" {
+			c.String(http.StatusBadRequest, "invalid 'tz' (IANA time zone)")
+			return q, false
+		}
+	}
+
+	params := []struct {
+		name     string
+		dst      *int
+		min, max int
+	}{
+		{"year", &q.Year, 2000, 2100},
+		{"month", &q.Month, 1, 12},
+		{"target", &q.TargetDays, 1, 7},
+	}
+	for _, p := range params {
+		v := c.QueryParam(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < p.min || n > p.max {
+			c.String(http.StatusBadRequest, "invalid '"+p.name+"'")
+			return q, false
+		}
+		*p.dst = n
+	}
+	return q, true
+}
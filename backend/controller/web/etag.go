@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// 楽観的排他制御。Workout / WorkoutSet / BodyMetric は version を ETag（"<version>"）と JSON の version で返し、
+// PATCH / DELETE の If-Match が今の version と違えば 412 を返す（If-Match が無ければ確かめない）。
+// If-Match が無くても、読んでから書くまでにほかの経路で変わっていれば 409 を返す
+
+const (
+	headerETag    = "ETag"
+	headerIfMatch = "If-Match"
+)
+
+// setETag は version を ETag ヘッダーに入れる
+func setETag(c echo.Context, version int) {
+	c.Response().Header().Set(headerETag, `"`+strconv.Itoa(version)+`"`)
+}
+
+// ifMatchVersion は If-Match の version。無いか * なら nil。
+// version として読めない値（弱い ETag など）はどの version とも一致しないので ok=false
+func ifMatchVersion(c echo.Context) (version *int, ok bool) {
+	v := strings.TrimSpace(c.Request().Header.Get(headerIfMatch))
+	if v == "" || v == "*" {
+		return nil, true
+	}
+	if len(v) < 2 || !strings.HasPrefix(v, `"`) || !strings.HasSuffix(v, `"`) {
+		return nil, false
+	}
+	n, err := strconv.Atoi(v[1 : len(v)-1])
+	if err != nil || n < 1 {
+		return nil, false
+	}
+	return &n, true
+}
+
+// preconditionFailed は If-Match が合わない（読んだあとに Web か LINE で変わった）ときの 412
+func preconditionFailed(c echo.Context) error {
+	return c.String(http.StatusPreconditionFailed, "resource was modified; reload and retry")
+}
+
+// versionConflict は IsVersionConflict のとき。If-Match を送っていれば 412、送っていなければ 409
+func versionConflict(c echo.Context, version *int) error {
+	if version == nil {
+		return c.String(http.StatusConflict, "resource was modified concurrently; retry")
+	}
+	return preconditionFailed(c)
+}
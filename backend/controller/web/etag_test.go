@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestVersionConflictStatus(t *testing.T) {
+	cases := []struct {
+		name    string
+		ifMatch string
+		want    int
+	}{
+		{name: "stale If-Match", ifMatch: `"1"`, want: http.StatusPreconditionFailed},
+		{name: "no If-Match", want: http.StatusConflict},
+		{name: "wildcard If-Match", ifMatch: "*", want: http.StatusConflict},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/api/workout_sets/s-1", nil)
+			if tc.ifMatch != "" {
+				req.Header.Set(headerIfMatch, tc.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			version, ok := ifMatchVersion(c)
+			if !ok {
+				t.Fatalf("ifMatchVersion(%q) not ok", tc.ifMatch)
+			}
+			if err := versionConflict(c, version); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tc.want {
+				t.Errorf("status = %d, want %d", rec.Code, tc.want)
+			}
+		})
+	}
+}
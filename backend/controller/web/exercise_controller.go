@@ -109,7 +109,10 @@ func (h *exerciseController) Update(c echo.Context) error {
 	}
 	ex, err := h.uc.Update(c.Request().Context(), userID, id, in)
 	if err != nil {
-		return c.NoContent(http.StatusNotFound) // 権限なし or 無い
+		if usecase.IsNotFound(err) {
+			return c.NoContent(http.StatusNotFound) // 権限なし or 無い
+		}
+		return c.String(http.StatusBadRequest, err.Error())
 	}
 	return c.JSON(http.StatusOK, ex)
 }
@@ -121,8 +124,13 @@ func (h *exerciseController) Delete(c echo.Context) error {
 		return c.NoContent(http.StatusUnauthorized)
 	}
 	id := c.Param("id")
-	if err := h.uc.Delete(c.Request().Context(), userID, id); err != nil {
+	archived, err := h.uc.Delete(c.Request().Context(), userID, id)
+	if err != nil {
 		return c.NoContent(http.StatusNotFound)
 	}
+	// 過去のセットで使っている種目は消さずに isActive=false にする
+	if archived != nil {
+		return c.JSON(http.StatusOK, archived)
+	}
 	return c.NoContent(http.StatusNoContent)
 }
\ No newline at end of file
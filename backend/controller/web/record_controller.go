@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"net/http"
+
+	echoSession "github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+type RecordController interface {
+	// GET /api/exercises/:id/records 種目の自己ベスト更新履歴
+	ListByExercise(c echo.Context) error
+	// GET /api/records 種目・種類ごとの今の自己ベスト
+	ListCurrent(c echo.Context) error
+}
+
+type recordController struct {
+	cfg models.Config
+	uc  usecase.RecordUsecase
+}
+
+func NewRecordController(cfg models.Config, uc usecase.RecordUsecase) RecordController {
+	return &recordController{cfg: cfg, uc: uc}
+}
+
+func (h *recordController) currentUserID(c echo.Context) string {
+	if uid, _ := c.Get("userID").(string); uid != "" {
+		return uid
+	}
+	sess, _ := echoSession.Get("session", c)
+	sub, _ := sess.Values["user_id"].(string)
+	return sub
+}
+
+func (h *recordController) ListByExercise(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	items, err := h.uc.ListByExercise(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		if usecase.IsNotFound(err) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"items": items})
+}
+
+func (h *recordController) ListCurrent(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	items, err := h.uc.ListCurrent(c.Request().Context(), userID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"items": items})
+}
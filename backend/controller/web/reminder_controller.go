@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"net/http"
+
+	echoSession "github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+type ReminderController interface {
+	Get(c echo.Context) error
+	Save(c echo.Context) error
+	Delete(c echo.Context) error
+}
+
+type reminderController struct {
+	cfg models.Config
+	uc  usecase.ReminderUsecase
+}
+
+func NewReminderController(cfg models.Config, uc usecase.ReminderUsecase) ReminderController {
+	return &reminderController{cfg: cfg, uc: uc}
+}
+
+func (h *reminderController) currentUserID(c echo.Context) string {
+	if uid, _ := c.Get("userID").(string); uid != "" {
+		return uid
+	}
+	sess, _ := echoSession.Get("session", c)
+	sub, _ := sess.Values["user_id"].(string)
+	return sub
+}
+
+func (h *reminderController) Get(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	out, err := h.uc.Get(c.Request().Context(), userID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *reminderController) Save(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	var in usecase.SaveReminderInput
+	if err := c.Bind(&in); err != nil {
+		return c.String(http.StatusBadRequest, "invalid body")
+	}
+	out, err := h.uc.Save(c.Request().Context(), userID, in)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *reminderController) Delete(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	if err := h.uc.Delete(c.Request().Context(), userID); err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"net/http"
+
+	echoSession "github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+type TemplateController interface {
+	List(c echo.Context) error
+	Get(c echo.Context) error
+	Create(c echo.Context) error
+	Update(c echo.Context) error
+	Delete(c echo.Context) error
+	Start(c echo.Context) error
+}
+
+type templateController struct {
+	cfg      models.Config
+	uc       usecase.TemplateUsecase
+	settings usecase.UserSettingsUsecase
+}
+
+func NewTemplateController(cfg models.Config, uc usecase.TemplateUsecase, settings usecase.UserSettingsUsecase) TemplateController {
+	return &templateController{cfg: cfg, uc: uc, settings: settings}
+}
+
+func (h *templateController) currentUserID(c echo.Context) string {
+	if uid, _ := c.Get("userID").(string); uid != "" {
+		return uid
+	}
+	sess, _ := echoSession.Get("session", c)
+	sub, _ := sess.Values["user_id"].(string)
+	return sub
+}
+
+func (h *templateController) List(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	items, err := h.uc.List(c.Request().Context(), userID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"items": items})
+}
+
+func (h *templateController) Get(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	t, err := h.uc.Get(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		if usecase.IsNotFound(err) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, t)
+}
+
+func (h *templateController) Create(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	var in models.CreateTemplateInput
+	if err := c.Bind(&in); err != nil {
+		return c.String(http.StatusBadRequest, "invalid body")
+	}
+	t, err := h.uc.Create(c.Request().Context(), userID, in)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusCreated, t)
+}
+
+func (h *templateController) Update(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	var in models.UpdateTemplateInput
+	if err := c.Bind(&in); err != nil {
+		return c.String(http.StatusBadRequest, "invalid body")
+	}
+	t, err := h.uc.Update(c.Request().Context(), userID, c.Param("id"), in)
+	if err != nil {
+		if usecase.IsNotFound(err) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusOK, t)
+}
+
+func (h *templateController) Delete(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	if err := h.uc.Delete(c.Request().Context(), userID, c.Param("id")); err != nil {
+		if usecase.IsNotFound(err) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Start はテンプレートからワークアウトを作る（未実施のセット付きの WorkoutDetail を返す）
+func (h *templateController) Start(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	detail, err := h.uc.Start(c.Request().Context(), userID, c.Param("id"), false)
+	if err != nil {
+		if usecase.IsNotFound(err) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	detail.Localize(preferredWeightUnit(c, h.settings, userID))
+	return c.JSON(http.StatusCreated, detail)
+}
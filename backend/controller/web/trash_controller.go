@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	echoSession "github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+type TrashController interface {
+	// GET /api/trash ゴミ箱の中身 ?type=&limit=&offset=
+	List(c echo.Context) error
+	// POST /api/trash/:type/:id/restore ゴミ箱から戻す
+	Restore(c echo.Context) error
+}
+
+type trashController struct {
+	cfg      models.Config
+	uc       usecase.TrashUsecase
+	settings usecase.UserSettingsUsecase
+}
+
+func NewTrashController(cfg models.Config, uc usecase.TrashUsecase, settings usecase.UserSettingsUsecase) TrashController {
+	return &trashController{cfg: cfg, uc: uc, settings: settings}
+}
+
+func (h *trashController) currentUserID(c echo.Context) string {
+	sess, _ := echoSession.Get("session", c)
+	if sub, _ := sess.Values["user_id"].(string); sub != "" {
+		return sub
+	}
+	return ""
+}
+
+func (h *trashController) List(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+
+	out, err := h.uc.List(c.Request().Context(), userID, usecase.TrashListInput{
+		Type:   c.QueryParam("type"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	unit := preferredWeightUnit(c, h.settings, userID)
+	for _, it := range out.Items {
+		if it.Set != nil {
+			it.Set.Localize(unit)
+		}
+		if it.BodyMetric != nil {
+			it.BodyMetric.Localize(unit)
+		}
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *trashController) Restore(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	typ := models.TrashType(c.Param("type"))
+	if !typ.Valid() {
+		return c.String(http.StatusBadRequest, "type must be one of workout, set, exercise, body_metric")
+	}
+	restored, err := h.uc.Restore(c.Request().Context(), userID, typ, c.Param("id"))
+	if err != nil {
+		if usecase.IsNotFound(err) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		if errors.Is(err, usecase.ErrWorkoutInTrash) {
+			return c.String(http.StatusConflict, err.Error())
+		}
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	unit := preferredWeightUnit(c, h.settings, userID)
+	switch v := restored.(type) {
+	case *models.WorkoutSet:
+		v.Localize(unit)
+	case *models.BodyMetric:
+		v.Localize(unit)
+	}
+	return c.JSON(http.StatusOK, restored)
+}
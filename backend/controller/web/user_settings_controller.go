@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"net/http"
+
+	echoSession "github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+type UserSettingsController interface {
+	// GET /api/me/settings
+	Get(c echo.Context) error
+	// PUT /api/me/settings
+	Save(c echo.Context) error
+}
+
+type userSettingsController struct {
+	cfg models.Config
+	uc  usecase.UserSettingsUsecase
+}
+
+func NewUserSettingsController(cfg models.Config, uc usecase.UserSettingsUsecase) UserSettingsController {
+	return &userSettingsController{cfg: cfg, uc: uc}
+}
+
+func (h *userSettingsController) currentUserID(c echo.Context) string {
+	if uid, _ := c.Get("userID").(string); uid != "" {
+		return uid
+	}
+	sess, _ := echoSession.Get("session", c)
+	sub, _ := sess.Values["user_id"].(string)
+	return sub
+}
+
+func (h *userSettingsController) Get(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	out, err := h.uc.Get(c.Request().Context(), userID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func (h *userSettingsController) Save(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	var in usecase.SaveUserSettingsInput
+	if err := c.Bind(&in); err != nil {
+		return c.String(http.StatusBadRequest, "invalid body")
+	}
+	out, err := h.uc.Save(c.Request().Context(), userID, in)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// preferredWeightUnit はユーザー設定の重さの単位。読めなければ既定の単位
+func preferredWeightUnit(c echo.Context, settings usecase.UserSettingsUsecase, userID string) models.WeightUnit {
+	s, err := settings.Get(c.Request().Context(), userID)
+	if err != nil {
+		return models.DefaultWeightUnit
+	}
+	return s.WeightUnit
+}
@@ -12,16 +12,21 @@ import (
 
 type WorkoutSetController interface {
 	AddSet(c echo.Context) error
+	// GET /api/workout_sets/:setId（ETag は If-Match 用）
+	GetSet(c echo.Context) error
 	UpdateSet(c echo.Context) error
 	DeleteSet(c echo.Context) error
+	// PUT /api/workouts/:id/sets/order
+	ReorderSets(c echo.Context) error
 }
 
 type workoutSetController struct {
-	uc usecase.WorkoutSetUsecase
+	uc       usecase.WorkoutSetUsecase
+	settings usecase.UserSettingsUsecase
 }
 
-func NewWorkoutSetController(uc usecase.WorkoutSetUsecase) WorkoutSetController {
-	return &workoutSetController{uc: uc}
+func NewWorkoutSetController(uc usecase.WorkoutSetUsecase, settings usecase.UserSettingsUsecase) WorkoutSetController {
+	return &workoutSetController{uc: uc, settings: settings}
 }
 
 func (h *workoutSetController) currentUserID(c echo.Context) string {
@@ -48,15 +53,44 @@ func (h *workoutSetController) AddSet(c echo.Context) error {
 	if err := c.Bind(&in); err != nil {
 		return c.String(http.StatusBadRequest, "invalid body")
 	}
+	unit := preferredWeightUnit(c, h.settings, userID)
+	if in.Weight != nil && in.WeightUnit == "" {
+		in.WeightUnit = unit
+	}
 
 	ws, err := h.uc.AddSet(c.Request().Context(), userID, workoutID, in, false)
 	if err != nil {
-		// 将来的にエラー種別で 400/403/404/500 を出し分け
+		if usecase.IsNotFound(err) {
+			return c.String(http.StatusNotFound, err.Error()) // 種目が無い・ゴミ箱・ほかのユーザーの独自種目
+		}
+		// 将来的にエラー種別で 400/403/500 を出し分け
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
+	ws.Localize(unit)
+	setETag(c, ws.Version)
 	return c.JSON(http.StatusCreated, ws)
 }
 
+func (h *workoutSetController) GetSet(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	setID := c.Param("setId")
+	if setID == "" {
+		return c.String(http.StatusBadRequest, "missing setId")
+	}
+
+	ws, err := h.uc.GetSet(c.Request().Context(), userID, setID)
+	if err != nil {
+		return c.String(http.StatusNotFound, "not found") // 無い or 自分のワークアウト以外
+	}
+	ws.Localize(preferredWeightUnit(c, h.settings, userID))
+	setETag(c, ws.Version)
+	return c.JSON(http.StatusOK, ws)
+}
+
 func (h *workoutSetController) UpdateSet(c echo.Context) error {
 	userID := h.currentUserID(c)
 	if userID == "" {
@@ -72,11 +106,25 @@ func (h *workoutSetController) UpdateSet(c echo.Context) error {
 	if err := c.Bind(&in); err != nil {
 		return c.String(http.StatusBadRequest, "invalid body")
 	}
+	unit := preferredWeightUnit(c, h.settings, userID)
+	if in.Weight != nil && in.WeightUnit == "" {
+		in.WeightUnit = unit
+	}
+
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return preconditionFailed(c)
+	}
 
-	ws, err := h.uc.UpdateSet(c.Request().Context(), userID, setID, in)
+	ws, err := h.uc.UpdateSet(c.Request().Context(), userID, setID, version, in)
 	if err != nil {
+		if usecase.IsVersionConflict(err) {
+			return versionConflict(c, version)
+		}
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
+	ws.Localize(unit)
+	setETag(c, ws.Version)
 	return c.JSON(http.StatusOK, ws)
 }
 
@@ -91,8 +139,40 @@ func (h *workoutSetController) DeleteSet(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "missing setId")
 	}
 
-	if err := h.uc.DeleteSet(c.Request().Context(), userID, setID); err != nil {
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return preconditionFailed(c)
+	}
+
+	if err := h.uc.DeleteSet(c.Request().Context(), userID, setID, version); err != nil {
+		if usecase.IsVersionConflict(err) {
+			return versionConflict(c, version)
+		}
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 	return c.NoContent(http.StatusNoContent)
 }
+
+func (h *workoutSetController) ReorderSets(c echo.Context) error {
+	userID := h.currentUserID(c)
+	if userID == "" {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	workoutID := c.Param("id")
+	if workoutID == "" {
+		return c.String(http.StatusBadRequest, "missing id")
+	}
+
+	var in models.SetOrderInput
+	if err := c.Bind(&in); err != nil {
+		return c.String(http.StatusBadRequest, "invalid body")
+	}
+
+	out, err := h.uc.ReorderSets(c.Request().Context(), userID, workoutID, in)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	out.Localize(preferredWeightUnit(c, h.settings, userID))
+	return c.JSON(http.StatusOK, out)
+}
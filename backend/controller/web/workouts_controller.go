@@ -19,15 +19,18 @@ type WorkoutController interface {
 	GetWorkoutDetail(c echo.Context) error
 	UpdateWorkout(c echo.Context) error
 	DeleteWorkout(c echo.Context) error
+	CloneWorkout(c echo.Context) error
 }
 
 type workoutController struct {
-	cfg models.Config
-	uc  usecase.WorkoutUsecase
+	cfg      models.Config
+	uc       usecase.WorkoutUsecase
+	cloneuc  usecase.WorkoutCloneUsecase
+	settings usecase.UserSettingsUsecase
 }
 
-func NewWorkoutController(cfg models.Config, uc usecase.WorkoutUsecase) WorkoutController {
-	return &workoutController{cfg: cfg, uc: uc}
+func NewWorkoutController(cfg models.Config, uc usecase.WorkoutUsecase, cloneuc usecase.WorkoutCloneUsecase, settings usecase.UserSettingsUsecase) WorkoutController {
+	return &workoutController{cfg: cfg, uc: uc, cloneuc: cloneuc, settings: settings}
 }
 
 func (h *workoutController) currentUserID(c echo.Context) string {
@@ -55,6 +58,7 @@ func (h *workoutController) CreateWorkout(c echo.Context) error {
 		// 将来はエラー種別で 400/500 を出し分け
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
+	setETag(c, w.Version)
 	return c.JSON(http.StatusCreated, w) // 201
 }
 
@@ -79,11 +83,19 @@ func (h *workoutController) EndWorkout(c echo.Context) error {
 	if in.EndedAt.IsZero() {
 		in.EndedAt = time.Now()
 	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return preconditionFailed(c)
+	}
 
-	w, err := h.uc.End(c.Request().Context(), workoutID, userID, in.EndedAt)
+	w, err := h.uc.End(c.Request().Context(), workoutID, userID, version, in.EndedAt)
 	if err != nil {
+		if usecase.IsVersionConflict(err) {
+			return versionConflict(c, version)
+		}
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
+	setETag(c, w.Version)
 	return c.JSON(http.StatusOK, w)
 }
 
@@ -133,6 +145,9 @@ func (h *workoutController) GetWorkoutDetail(c echo.Context) error {
 		// gorm.ErrRecordNotFound や forbidden 相当なら 404/403 に振り分けてもOK
 		return c.String(http.StatusNotFound, "not found")
 	}
+	detail.Localize(preferredWeightUnit(c, h.settings, userID))
+	// ワークアウト自体の version（セットの version はそれぞれの version を使う）
+	setETag(c, detail.Workout.Version)
 	return c.JSON(http.StatusOK, detail)
 }
 
@@ -149,13 +164,21 @@ func (h *workoutController) UpdateWorkout(c echo.Context) error {
 	if err := c.Bind(&in); err != nil {
 		return c.String(http.StatusBadRequest, "invalid body")
 	}
-	w, err := h.uc.Update(c.Request().Context(), workoutID, userID, in)
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return preconditionFailed(c)
+	}
+	w, err := h.uc.Update(c.Request().Context(), workoutID, userID, version, in)
 	if err != nil {
 		if usecase.IsNotFound(err) {
 			return c.NoContent(http.StatusNotFound)
 		}
+		if usecase.IsVersionConflict(err) {
+			return versionConflict(c, version)
+		}
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
+	setETag(c, w.Version)
 	return c.JSON(http.StatusOK, w)
 }
 
@@ -168,15 +191,49 @@ func (h *workoutController) DeleteWorkout(c echo.Context) error {
 	if !ok {
 		return nil
 	}
-	if err := h.uc.Delete(c.Request().Context(), workoutID, userID); err != nil {
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return preconditionFailed(c)
+	}
+	if err := h.uc.Delete(c.Request().Context(), workoutID, userID, version); err != nil {
 		if usecase.IsNotFound(err) {
 			return c.NoContent(http.StatusNotFound)
 		}
+		if usecase.IsVersionConflict(err) {
+			return versionConflict(c, version)
+		}
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 	return c.NoContent(http.StatusNoContent)
 }
 
+// CloneWorkout は前回の種目とセットを予定のセットにした新しいワークアウトを作る
+func (h *workoutController) CloneWorkout(c echo.Context) error {
+	userID, ok := h.requireUserID(c)
+	if !ok {
+		return nil
+	}
+	workoutID, ok := requirePathID(c, "id", "missing id")
+	if !ok {
+		return nil
+	}
+	var in models.CloneWorkoutInput
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&in); err != nil {
+			return c.String(http.StatusBadRequest, "invalid body")
+		}
+	}
+	detail, err := h.cloneuc.Clone(c.Request().Context(), userID, workoutID, in, false)
+	if err != nil {
+		if usecase.IsNotFound(err) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	detail.Localize(preferredWeightUnit(c, h.settings, userID))
+	return c.JSON(http.StatusCreated, detail)
+}
+
 // Shared helpers ------------------------------------------------------------
 
 func (h *workoutController) requireUserID(c echo.Context) (string, bool) {
@@ -21,7 +21,8 @@ func InitDB() *gorm.DB {
 	url := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", os.Getenv("POSTGRES_USER"),
 		os.Getenv("POSTGRES_PW"), os.Getenv("POSTGRES_HOST"),
 		os.Getenv("POSTGRES_PORT"), os.Getenv("POSTGRES_DB"))
-	db, err := gorm.Open(postgres.Open(url), &gorm.Config{})
+	// 一意制約・外部キーの違反は gorm.ErrDuplicatedKey / gorm.ErrForeignKeyViolated で返す
+	db, err := gorm.Open(postgres.Open(url), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatalln(err)
 	}
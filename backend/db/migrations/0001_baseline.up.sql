@@ -0,0 +1,193 @@
+-- これまで AutoMigrate で作っていたスキーマ。
+-- AutoMigrate で作った DB にもそのまま流せるように IF NOT EXISTS で書き、あとから足した列は ADD COLUMN で揃える
+
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+CREATE TABLE IF NOT EXISTS users (
+	id           uuid DEFAULT gen_random_uuid(),
+	line_user_id varchar(128) NOT NULL,
+	name         varchar(100),
+	picture_url  varchar(2048),
+	email        varchar(255),
+	created_at   timestamptz,
+	updated_at   timestamptz,
+	PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_line_user_id ON users (line_user_id);
+
+CREATE TABLE IF NOT EXISTS exercises (
+	id               uuid DEFAULT gen_random_uuid(),
+	owner_user_id    uuid,
+	name             varchar(64) NOT NULL,
+	type             text NOT NULL,
+	primary_muscle   varchar(64),
+	is_active        boolean NOT NULL DEFAULT true,
+	default_rest_sec bigint,
+	created_at       timestamptz,
+	updated_at       timestamptz,
+	PRIMARY KEY (id)
+);
+ALTER TABLE exercises ADD COLUMN IF NOT EXISTS default_rest_sec bigint;
+CREATE INDEX IF NOT EXISTS idx_exercises_owner_user_id ON exercises (owner_user_id);
+
+CREATE TABLE IF NOT EXISTS workouts (
+	id           uuid DEFAULT gen_random_uuid(),
+	user_id      uuid NOT NULL,
+	started_at   timestamptz NOT NULL,
+	ended_at     timestamptz,
+	note         text,
+	is_from_line boolean NOT NULL DEFAULT false,
+	created_at   timestamptz,
+	updated_at   timestamptz,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_workouts_user_id ON workouts (user_id);
+
+CREATE TABLE IF NOT EXISTS workout_sets (
+	id           uuid DEFAULT gen_random_uuid(),
+	workout_id   uuid NOT NULL,
+	exercise_id  uuid NOT NULL,
+	set_index    bigint NOT NULL,
+	reps         bigint,
+	weight_kg    decimal,
+	rpe          decimal,
+	weight_value decimal,
+	weight_unit  varchar(2),
+	duration_sec bigint,
+	distance_m   decimal,
+	rest_sec     bigint,
+	is_warmup    boolean NOT NULL DEFAULT false,
+	note         text,
+	is_planned   boolean NOT NULL DEFAULT false,
+	group_type   varchar(16) NOT NULL DEFAULT 'straight',
+	group_id     uuid,
+	group_order  bigint NOT NULL DEFAULT 0,
+	created_at   timestamptz,
+	updated_at   timestamptz,
+	is_from_line boolean NOT NULL DEFAULT false,
+	PRIMARY KEY (id)
+);
+ALTER TABLE workout_sets
+	ADD COLUMN IF NOT EXISTS weight_value decimal,
+	ADD COLUMN IF NOT EXISTS weight_unit  varchar(2),
+	ADD COLUMN IF NOT EXISTS is_planned   boolean NOT NULL DEFAULT false,
+	ADD COLUMN IF NOT EXISTS group_type   varchar(16) NOT NULL DEFAULT 'straight',
+	ADD COLUMN IF NOT EXISTS group_id     uuid,
+	ADD COLUMN IF NOT EXISTS group_order  bigint NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_workout_order ON workout_sets (workout_id, set_index);
+CREATE INDEX IF NOT EXISTS idx_workout_sets_exercise_id ON workout_sets (exercise_id);
+CREATE INDEX IF NOT EXISTS idx_workout_sets_group_id ON workout_sets (group_id);
+
+CREATE TABLE IF NOT EXISTS body_metrics (
+	id           uuid DEFAULT gen_random_uuid(),
+	user_id      uuid NOT NULL,
+	measured_at  timestamptz NOT NULL,
+	weight_kg    decimal NOT NULL,
+	weight_value decimal,
+	weight_unit  varchar(2),
+	body_fat_pct decimal,
+	note         text,
+	created_at   timestamptz,
+	updated_at   timestamptz,
+	PRIMARY KEY (id)
+);
+ALTER TABLE body_metrics
+	ADD COLUMN IF NOT EXISTS weight_value decimal,
+	ADD COLUMN IF NOT EXISTS weight_unit  varchar(2);
+CREATE INDEX IF NOT EXISTS idx_body_metrics_user_id ON body_metrics (user_id);
+CREATE INDEX IF NOT EXISTS idx_body_metrics_measured_at ON body_metrics (measured_at);
+
+CREATE TABLE IF NOT EXISTS reminder_preferences (
+	id               uuid DEFAULT gen_random_uuid(),
+	user_id          uuid NOT NULL,
+	enabled          boolean NOT NULL DEFAULT true,
+	weekdays         bigint NOT NULL DEFAULT 0,
+	remind_at        varchar(5) NOT NULL DEFAULT '19:00',
+	inactive_days    bigint NOT NULL DEFAULT 3,
+	weekly_recap     boolean NOT NULL DEFAULT true,
+	auto_close_hours bigint NOT NULL DEFAULT 6,
+	snoozed_until    timestamptz,
+	last_reminded_at timestamptz,
+	last_nudged_at   timestamptz,
+	last_recap_at    timestamptz,
+	created_at       timestamptz,
+	updated_at       timestamptz,
+	PRIMARY KEY (id)
+);
+ALTER TABLE reminder_preferences ADD COLUMN IF NOT EXISTS auto_close_hours bigint NOT NULL DEFAULT 6;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reminder_preferences_user_id ON reminder_preferences (user_id);
+
+CREATE TABLE IF NOT EXISTS workout_templates (
+	id         uuid DEFAULT gen_random_uuid(),
+	user_id    uuid NOT NULL,
+	name       varchar(64) NOT NULL,
+	note       text,
+	created_at timestamptz,
+	updated_at timestamptz,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_workout_templates_user_id ON workout_templates (user_id);
+
+CREATE TABLE IF NOT EXISTS template_exercises (
+	id               uuid DEFAULT gen_random_uuid(),
+	template_id      uuid NOT NULL,
+	exercise_id      uuid NOT NULL,
+	position         bigint NOT NULL,
+	target_sets      bigint NOT NULL DEFAULT 1,
+	target_reps      bigint,
+	target_weight_kg decimal,
+	target_rpe       decimal,
+	rest_sec         bigint,
+	note             text,
+	created_at       timestamptz,
+	updated_at       timestamptz,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_template_exercises_template_id ON template_exercises (template_id);
+
+CREATE TABLE IF NOT EXISTS personal_records (
+	id             uuid DEFAULT gen_random_uuid(),
+	user_id        uuid NOT NULL,
+	exercise_id    uuid NOT NULL,
+	kind           varchar(32) NOT NULL,
+	weight_kg      decimal,
+	reps           bigint,
+	value          decimal NOT NULL,
+	previous_value decimal,
+	workout_id     uuid NOT NULL,
+	set_id         uuid,
+	achieved_at    timestamptz NOT NULL,
+	created_at     timestamptz,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_record_exercise ON personal_records (user_id, exercise_id);
+CREATE INDEX IF NOT EXISTS idx_personal_records_set_id ON personal_records (set_id);
+
+CREATE TABLE IF NOT EXISTS muscle_groups (
+	key      varchar(32),
+	name     varchar(32) NOT NULL,
+	region   varchar(16) NOT NULL,
+	position bigint NOT NULL,
+	PRIMARY KEY (key)
+);
+
+CREATE TABLE IF NOT EXISTS exercise_muscles (
+	exercise_id  uuid,
+	muscle       varchar(32),
+	role         varchar(16) NOT NULL,
+	contribution decimal NOT NULL DEFAULT 1,
+	PRIMARY KEY (exercise_id, muscle)
+);
+
+CREATE TABLE IF NOT EXISTS user_settings (
+	id          uuid DEFAULT gen_random_uuid(),
+	user_id     uuid NOT NULL,
+	timezone    varchar(64) NOT NULL DEFAULT 'Asia/Tokyo',
+	locale      varchar(16) NOT NULL DEFAULT 'ja-JP',
+	week_start  bigint NOT NULL DEFAULT 1,
+	weight_unit varchar(2) NOT NULL DEFAULT 'kg',
+	created_at  timestamptz,
+	updated_at  timestamptz,
+	PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_settings_user_id ON user_settings (user_id);
@@ -0,0 +1,3 @@
+-- ずらした名前は戻さない
+DROP INDEX IF EXISTS idx_exercises_owner_name;
+DROP INDEX IF EXISTS idx_exercises_global_name;
@@ -0,0 +1,23 @@
+-- 種目名の一意制約。グローバル種目（owner_user_id IS NULL）は名前で、独自種目はユーザーと名前で一意にする。
+-- すでに重なっている名前は、あとから作ったほうに「 (2)」「 (3)」… を付けてずらす
+
+WITH dup AS (
+	SELECT id, ROW_NUMBER() OVER (PARTITION BY name ORDER BY created_at, id) AS n
+	FROM exercises
+	WHERE owner_user_id IS NULL
+)
+UPDATE exercises e SET name = LEFT(e.name, 58) || ' (' || dup.n || ')'
+FROM dup
+WHERE e.id = dup.id AND dup.n > 1;
+
+WITH dup AS (
+	SELECT id, ROW_NUMBER() OVER (PARTITION BY owner_user_id, name ORDER BY created_at, id) AS n
+	FROM exercises
+	WHERE owner_user_id IS NOT NULL
+)
+UPDATE exercises e SET name = LEFT(e.name, 58) || ' (' || dup.n || ')'
+FROM dup
+WHERE e.id = dup.id AND dup.n > 1;
+
+CREATE UNIQUE INDEX idx_exercises_global_name ON exercises (name) WHERE owner_user_id IS NULL;
+CREATE UNIQUE INDEX idx_exercises_owner_name ON exercises (owner_user_id, name) WHERE owner_user_id IS NOT NULL;
@@ -0,0 +1,15 @@
+ALTER TABLE personal_records DROP CONSTRAINT IF EXISTS fk_personal_records_set;
+ALTER TABLE personal_records DROP CONSTRAINT IF EXISTS fk_personal_records_workout;
+ALTER TABLE personal_records DROP CONSTRAINT IF EXISTS fk_personal_records_exercise;
+ALTER TABLE personal_records DROP CONSTRAINT IF EXISTS fk_personal_records_user;
+ALTER TABLE exercise_muscles DROP CONSTRAINT IF EXISTS fk_exercise_muscles_exercise;
+ALTER TABLE template_exercises DROP CONSTRAINT IF EXISTS fk_template_exercises_exercise;
+ALTER TABLE template_exercises DROP CONSTRAINT IF EXISTS fk_template_exercises_template;
+ALTER TABLE workout_templates DROP CONSTRAINT IF EXISTS fk_workout_templates_user;
+ALTER TABLE user_settings DROP CONSTRAINT IF EXISTS fk_user_settings_user;
+ALTER TABLE reminder_preferences DROP CONSTRAINT IF EXISTS fk_reminder_preferences_user;
+ALTER TABLE body_metrics DROP CONSTRAINT IF EXISTS fk_body_metrics_user;
+ALTER TABLE exercises DROP CONSTRAINT IF EXISTS fk_exercises_owner;
+ALTER TABLE workouts DROP CONSTRAINT IF EXISTS fk_workouts_user;
+ALTER TABLE workout_sets DROP CONSTRAINT IF EXISTS fk_workout_sets_exercise;
+ALTER TABLE workout_sets DROP CONSTRAINT IF EXISTS fk_workout_sets_workout;
@@ -0,0 +1,72 @@
+-- 外部キー。AutoMigrate が関連から作った制約（fk_workouts_sets など。ON DELETE なし）は張り直す。
+-- 参照先の消えた行（過去に消したワークアウトのセットなど）は先に片付ける
+
+ALTER TABLE workout_sets DROP CONSTRAINT IF EXISTS fk_workouts_sets;
+ALTER TABLE template_exercises DROP CONSTRAINT IF EXISTS fk_workout_templates_exercises;
+ALTER TABLE exercise_muscles DROP CONSTRAINT IF EXISTS fk_exercises_muscles;
+
+DELETE FROM workouts w WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = w.user_id);
+DELETE FROM workout_sets ws WHERE NOT EXISTS (SELECT 1 FROM workouts w WHERE w.id = ws.workout_id);
+DELETE FROM exercises e WHERE e.owner_user_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = e.owner_user_id);
+DELETE FROM body_metrics b WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = b.user_id);
+DELETE FROM reminder_preferences r WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = r.user_id);
+DELETE FROM user_settings s WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = s.user_id);
+DELETE FROM workout_templates t WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = t.user_id);
+DELETE FROM template_exercises te WHERE NOT EXISTS (SELECT 1 FROM workout_templates t WHERE t.id = te.template_id)
+	OR NOT EXISTS (SELECT 1 FROM exercises e WHERE e.id = te.exercise_id);
+DELETE FROM exercise_muscles em WHERE NOT EXISTS (SELECT 1 FROM exercises e WHERE e.id = em.exercise_id);
+DELETE FROM personal_records pr WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = pr.user_id)
+	OR NOT EXISTS (SELECT 1 FROM exercises e WHERE e.id = pr.exercise_id)
+	OR NOT EXISTS (SELECT 1 FROM workouts w WHERE w.id = pr.workout_id)
+	OR (pr.set_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM workout_sets ws WHERE ws.id = pr.set_id));
+
+-- ワークアウトを消したらセットも消える
+ALTER TABLE workout_sets ADD CONSTRAINT fk_workout_sets_workout
+	FOREIGN KEY (workout_id) REFERENCES workouts (id) ON DELETE CASCADE;
+-- セットで使っている種目は消せない（隠すときは is_active = false）。
+-- RESTRICT だとユーザーを消したときの CASCADE（種目とセットを同じ文で消す）でも止まるので NO ACTION にする。
+-- 種目が消えたまま残っているセットは消さずに、検証だけ後回しにする
+ALTER TABLE workout_sets ADD CONSTRAINT fk_workout_sets_exercise
+	FOREIGN KEY (exercise_id) REFERENCES exercises (id) ON DELETE NO ACTION NOT VALID;
+DO $$
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM workout_sets ws WHERE NOT EXISTS (SELECT 1 FROM exercises e WHERE e.id = ws.exercise_id)
+	) THEN
+		ALTER TABLE workout_sets VALIDATE CONSTRAINT fk_workout_sets_exercise;
+	ELSE
+		RAISE NOTICE 'fk_workout_sets_exercise: workout_sets has rows whose exercise is missing; left NOT VALID';
+	END IF;
+END $$;
+
+-- ユーザーを消したらそのユーザーのデータはすべて消える
+ALTER TABLE workouts ADD CONSTRAINT fk_workouts_user
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE exercises ADD CONSTRAINT fk_exercises_owner
+	FOREIGN KEY (owner_user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE body_metrics ADD CONSTRAINT fk_body_metrics_user
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE reminder_preferences ADD CONSTRAINT fk_reminder_preferences_user
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE user_settings ADD CONSTRAINT fk_user_settings_user
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE workout_templates ADD CONSTRAINT fk_workout_templates_user
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+-- テンプレートの行は種目を消したらその行だけ外れる
+ALTER TABLE template_exercises ADD CONSTRAINT fk_template_exercises_template
+	FOREIGN KEY (template_id) REFERENCES workout_templates (id) ON DELETE CASCADE;
+ALTER TABLE template_exercises ADD CONSTRAINT fk_template_exercises_exercise
+	FOREIGN KEY (exercise_id) REFERENCES exercises (id) ON DELETE CASCADE;
+ALTER TABLE exercise_muscles ADD CONSTRAINT fk_exercise_muscles_exercise
+	FOREIGN KEY (exercise_id) REFERENCES exercises (id) ON DELETE CASCADE;
+
+-- 自己ベストは元のセット・ワークアウトと一緒に消える（残りは記録の再計算で作り直す）
+ALTER TABLE personal_records ADD CONSTRAINT fk_personal_records_user
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE personal_records ADD CONSTRAINT fk_personal_records_exercise
+	FOREIGN KEY (exercise_id) REFERENCES exercises (id) ON DELETE CASCADE;
+ALTER TABLE personal_records ADD CONSTRAINT fk_personal_records_workout
+	FOREIGN KEY (workout_id) REFERENCES workouts (id) ON DELETE CASCADE;
+ALTER TABLE personal_records ADD CONSTRAINT fk_personal_records_set
+	FOREIGN KEY (set_id) REFERENCES workout_sets (id) ON DELETE CASCADE;
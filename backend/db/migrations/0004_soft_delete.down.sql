@@ -0,0 +1,18 @@
+-- ゴミ箱の中身は消す。セットやテンプレートで使っている種目だけは消さず、名前をずらして isActive=false で残す
+DELETE FROM workout_sets WHERE deleted_at IS NOT NULL;
+DELETE FROM workouts WHERE deleted_at IS NOT NULL;
+DELETE FROM body_metrics WHERE deleted_at IS NOT NULL;
+DELETE FROM exercises e WHERE deleted_at IS NOT NULL
+	AND NOT EXISTS (SELECT 1 FROM workout_sets ws WHERE ws.exercise_id = e.id)
+	AND NOT EXISTS (SELECT 1 FROM template_exercises te WHERE te.exercise_id = e.id);
+UPDATE exercises SET name = LEFT(name, 54) || ' (deleted)', is_active = false WHERE deleted_at IS NOT NULL;
+
+DROP INDEX idx_exercises_owner_name;
+DROP INDEX idx_exercises_global_name;
+CREATE UNIQUE INDEX idx_exercises_global_name ON exercises (name) WHERE owner_user_id IS NULL;
+CREATE UNIQUE INDEX idx_exercises_owner_name ON exercises (owner_user_id, name) WHERE owner_user_id IS NOT NULL;
+
+ALTER TABLE body_metrics DROP COLUMN deleted_at;
+ALTER TABLE exercises DROP COLUMN deleted_at;
+ALTER TABLE workout_sets DROP COLUMN deleted_at;
+ALTER TABLE workouts DROP COLUMN deleted_at;
@@ -0,0 +1,17 @@
+-- ゴミ箱。消したワークアウト・セット・種目・体重記録は deleted_at を入れて残し、保存期間を過ぎたらスケジューラーが消す
+
+ALTER TABLE workouts ADD COLUMN deleted_at timestamptz;
+ALTER TABLE workout_sets ADD COLUMN deleted_at timestamptz;
+ALTER TABLE exercises ADD COLUMN deleted_at timestamptz;
+ALTER TABLE body_metrics ADD COLUMN deleted_at timestamptz;
+
+CREATE INDEX idx_workouts_deleted_at ON workouts (deleted_at);
+CREATE INDEX idx_workout_sets_deleted_at ON workout_sets (deleted_at);
+CREATE INDEX idx_exercises_deleted_at ON exercises (deleted_at);
+CREATE INDEX idx_body_metrics_deleted_at ON body_metrics (deleted_at);
+
+-- ゴミ箱の種目と同じ名前で作り直せるように、名前の一意制約は残っている種目だけにする
+DROP INDEX idx_exercises_global_name;
+DROP INDEX idx_exercises_owner_name;
+CREATE UNIQUE INDEX idx_exercises_global_name ON exercises (name) WHERE owner_user_id IS NULL AND deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_exercises_owner_name ON exercises (owner_user_id, name) WHERE owner_user_id IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,31 @@
+-- 変更履歴。ワークアウト・セット・種目・体重記録・テンプレートの作成・更新・削除・復元を、変更と同じトランザクションで追記する。
+-- 持ち主が退会しても残すため users への外部キーは張らない
+
+CREATE TABLE audit_logs (
+	id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id uuid NOT NULL,
+	actor_user_id uuid,
+	source varchar(16) NOT NULL,
+	request_id varchar(64),
+	entity_type varchar(32) NOT NULL,
+	entity_id uuid NOT NULL,
+	workout_id uuid,
+	action varchar(16) NOT NULL,
+	changes jsonb NOT NULL DEFAULT '{}',
+	created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_audit_logs_user_created ON audit_logs (user_id, created_at DESC);
+CREATE INDEX idx_audit_logs_workout_created ON audit_logs (workout_id, created_at DESC) WHERE workout_id IS NOT NULL;
+CREATE INDEX idx_audit_logs_entity ON audit_logs (entity_type, entity_id);
+
+-- 追記のみ。アプリの不具合や手作業で書き換えられないように、更新と削除は DB で止める
+CREATE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_append_only
+	BEFORE UPDATE OR DELETE ON audit_logs
+	FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
@@ -0,0 +1,3 @@
+ALTER TABLE body_metrics DROP COLUMN version;
+ALTER TABLE workout_sets DROP COLUMN version;
+ALTER TABLE workouts DROP COLUMN version;
@@ -0,0 +1,5 @@
+-- 楽観的排他制御。Web と LINE が同じ行を同時に書き換えても上書きしないよう、更新は version を比べて行い 1 上げる
+
+ALTER TABLE workouts ADD COLUMN version integer NOT NULL DEFAULT 1;
+ALTER TABLE workout_sets ADD COLUMN version integer NOT NULL DEFAULT 1;
+ALTER TABLE body_metrics ADD COLUMN version integer NOT NULL DEFAULT 1;
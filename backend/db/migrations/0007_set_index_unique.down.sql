@@ -0,0 +1,2 @@
+DROP INDEX idx_workout_sets_live_set_index;
+ALTER TABLE workout_sets DROP CONSTRAINT chk_workout_sets_set_index;
@@ -0,0 +1,14 @@
+-- ワークアウト内のセットの並び。残っているセットの set_index は 1 から重ならないようにする
+-- （ゴミ箱のセットは戻すときの位置として元の番号を残すので対象外）
+
+-- 既存の重なり・0 以下の番号を直してから制約を付ける
+UPDATE workout_sets SET set_index = 1 WHERE set_index < 1 AND deleted_at IS NOT NULL;
+UPDATE workout_sets ws SET set_index = n.rn
+FROM (
+	SELECT id, ROW_NUMBER() OVER (PARTITION BY workout_id ORDER BY set_index, created_at, id) AS rn
+	FROM workout_sets WHERE deleted_at IS NULL
+) n
+WHERE ws.id = n.id AND ws.set_index <> n.rn;
+
+ALTER TABLE workout_sets ADD CONSTRAINT chk_workout_sets_set_index CHECK (set_index >= 1);
+CREATE UNIQUE INDEX idx_workout_sets_live_set_index ON workout_sets (workout_id, set_index) WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+-- データの移行なので戻さない（入れた部位は種目の編集で直せる）。0008 より前へ戻せるよう何もしない文だけ流す
+SELECT 1;
@@ -0,0 +1,33 @@
+-- 部位が未設定の種目に、自由入力だった primary_muscle から部位を入れる。
+-- 対応表は models.MusclesFromLabel（muscleLabels）と同じ。部位がある種目と、対応の無い入力には触らない
+
+WITH labels (label, muscle, role, contribution) AS (
+	SELECT l.label, l.muscle, 'primary', 1.0
+	FROM (VALUES
+		('胸', 'chest'), ('大胸筋', 'chest'), ('chest', 'chest'), ('pecs', 'chest'),
+		('背中', 'back'), ('広背筋', 'back'), ('back', 'back'), ('lats', 'back'),
+		('肩', 'shoulders'), ('三角筋', 'shoulders'), ('shoulders', 'shoulders'), ('delts', 'shoulders'),
+		('二頭', 'biceps'), ('上腕二頭筋', 'biceps'), ('biceps', 'biceps'),
+		('三頭', 'triceps'), ('上腕三頭筋', 'triceps'), ('triceps', 'triceps'),
+		('前腕', 'forearms'), ('forearms', 'forearms'),
+		('腹', 'abs'), ('腹筋', 'abs'), ('体幹', 'abs'), ('abs', 'abs'), ('core', 'abs'),
+		('大腿四頭筋', 'quads'), ('quads', 'quads'),
+		('ハムストリングス', 'hamstrings'), ('ハム', 'hamstrings'), ('hamstrings', 'hamstrings'),
+		('尻', 'glutes'), ('お尻', 'glutes'), ('臀部', 'glutes'), ('glutes', 'glutes'),
+		('ふくらはぎ', 'calves'), ('カーフ', 'calves'), ('calves', 'calves')
+	) AS l (label, muscle)
+	-- まとめた呼び方は部位に割り振る
+	UNION ALL
+	SELECT l.label, m.muscle, m.role, m.contribution
+	FROM (VALUES ('腕'), ('arms')) AS l (label)
+	CROSS JOIN (VALUES ('biceps', 'primary', 0.5), ('triceps', 'primary', 0.5)) AS m (muscle, role, contribution)
+	UNION ALL
+	SELECT l.label, m.muscle, m.role, m.contribution
+	FROM (VALUES ('脚'), ('足'), ('legs')) AS l (label)
+	CROSS JOIN (VALUES ('quads', 'primary', 1.0), ('glutes', 'secondary', 0.5), ('hamstrings', 'secondary', 0.5)) AS m (muscle, role, contribution)
+)
+INSERT INTO exercise_muscles (exercise_id, muscle, role, contribution)
+SELECT e.id, labels.muscle, labels.role, labels.contribution
+FROM exercises e
+JOIN labels ON labels.label = LOWER(BTRIM(e.primary_muscle, E' \t\r\n　'))
+WHERE NOT EXISTS (SELECT 1 FROM exercise_muscles em WHERE em.exercise_id = e.id);
@@ -0,0 +1,330 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// スキーマのマイグレーション。migrations/ の NNNN_名前.up.sql / NNNN_名前.down.sql を番号順に流し、
+// 流したものは schema_migrations に記録する（中身のチェックサム付き）
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey は同時に 1 つだけ流すための advisory lock のキー
+const migrationLockKey = "schema_migrations"
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string // 空なら戻せない
+	Checksum string // Up の sha256
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time // 未適用なら nil
+	Modified  bool       // 適用後に up.sql が書き換えられた
+}
+
+// schemaMigration は schema_migrations の行
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// LoadMigrations は dir の *.up.sql / *.down.sql を番号順に読む
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		file := e.Name()
+		if e.IsDir() || !strings.HasSuffix(file, ".sql") {
+			continue
+		}
+		base := strings.TrimSuffix(file, ".sql")
+		var down bool
+		switch {
+		case strings.HasSuffix(base, ".up"):
+			base = strings.TrimSuffix(base, ".up")
+		case strings.HasSuffix(base, ".down"):
+			base, down = strings.TrimSuffix(base, ".down"), true
+		default:
+			return nil, fmt.Errorf("migration %s: must end with .up.sql or .down.sql", file)
+		}
+		num, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: must be named NNNN_name", file)
+		}
+		b, err := fs.ReadFile(fsys, path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d: names differ (%s, %s)", version, m.Name, name)
+		}
+		if down {
+			m.Down = string(b)
+		} else {
+			m.Up = string(b)
+			sum := sha256.Sum256(b)
+			m.Checksum = hex.EncodeToString(sum[:])
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up.sql", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator は埋め込みの migrations/ を使う
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	ms, err := LoadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: ms}, nil
+}
+
+// Up は未適用のマイグレーションをすべて流す。流したものを返す
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+			if err := m.apply(conn, mg); err != nil {
+				return err
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down は新しいほうから steps 件戻す。戻したものを返す
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; !ok {
+				continue
+			}
+			if err := m.revert(conn, mg, applied[mg.Version]); err != nil {
+				return err
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Redo は最後に適用したマイグレーションを戻して流し直す（up.sql を書き換えた直後の開発用）
+func (m *Migrator) Redo(ctx context.Context) (*Migration, error) {
+	var done *Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			row, ok := applied[mg.Version]
+			if !ok {
+				continue
+			}
+			// 戻すのは今の down.sql なので、up.sql が変わっていても流し直せる
+			row.Checksum = mg.Checksum
+			if err := m.revert(conn, mg, row); err != nil {
+				return err
+			}
+			if err := m.apply(conn, mg); err != nil {
+				return err
+			}
+			done = &mg
+			return nil
+		}
+		return errors.New("no applied migration to redo")
+	})
+	return done, err
+}
+
+// Status はマイグレーションごとの適用状況（DB にだけ記録があるものも含む）
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn := m.db.WithContext(ctx)
+	if err := m.ensureTable(conn); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(conn)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		st := MigrationStatus{Migration: mg}
+		if row, ok := applied[mg.Version]; ok {
+			at := row.AppliedAt
+			st.AppliedAt = &at
+			st.Modified = row.Checksum != mg.Checksum
+			delete(applied, mg.Version)
+		}
+		out = append(out, st)
+	}
+	for _, row := range applied {
+		at := row.AppliedAt
+		out = append(out, MigrationStatus{Migration: Migration{Version: row.Version, Name: row.Name}, AppliedAt: &at})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// locked は advisory lock を取った 1 本の接続で fn を実行する。
+// 別のインスタンスが流していれば終わるまで待つ
+func (m *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var ok bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(hashtext(?))", migrationLockKey).Scan(&ok).Error; err != nil {
+			return err
+		}
+		if !ok {
+			log.Println("migrate: another instance is migrating, waiting for the lock")
+			if err := conn.Exec("SELECT pg_advisory_lock(hashtext(?))", migrationLockKey).Error; err != nil {
+				return err
+			}
+		}
+		defer func() {
+			// ctx が切れていてもロックは返す
+			if err := conn.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(hashtext(?))", migrationLockKey).Error; err != nil {
+				log.Printf("migrate: unlock: %v", err)
+			}
+		}()
+		if err := m.ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func (m *Migrator) ensureTable(conn *gorm.DB) error {
+	return conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    bigint PRIMARY KEY,
+	name       text NOT NULL,
+	checksum   text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`).Error
+}
+
+func (m *Migrator) applied(conn *gorm.DB) (map[int]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := conn.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[int]schemaMigration, len(rows))
+	for _, r := range rows {
+		out[r.Version] = r
+	}
+	return out, nil
+}
+
+// verify は適用済みのファイルが書き換えられていないか、ファイルが消えていないかを見る
+func (m *Migrator) verify(applied map[int]schemaMigration) error {
+	known := make(map[int]bool, len(m.migrations))
+	for _, mg := range m.migrations {
+		known[mg.Version] = true
+		if row, ok := applied[mg.Version]; ok && row.Checksum != mg.Checksum {
+			return fmt.Errorf("migration %d_%s was modified after it was applied (checksum mismatch)", mg.Version, mg.Name)
+		}
+	}
+	for v, row := range applied {
+		if !known[v] {
+			return fmt.Errorf("migration %d_%s is applied but its file is missing", v, row.Name)
+		}
+	}
+	return nil
+}
+
+// apply は 1 件を 1 トランザクションで流して記録する
+func (m *Migrator) apply(conn *gorm.DB, mg Migration) error {
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(mg.Up).Error; err != nil {
+			return err
+		}
+		return tx.Create(&schemaMigration{Version: mg.Version, Name: mg.Name, Checksum: mg.Checksum, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s up: %w", mg.Version, mg.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) revert(conn *gorm.DB, mg Migration, row schemaMigration) error {
+	if mg.Down == "" {
+		return fmt.Errorf("migration %d_%s cannot be reverted (no down.sql)", mg.Version, mg.Name)
+	}
+	if row.Checksum != mg.Checksum {
+		return fmt.Errorf("migration %d_%s was modified after it was applied (checksum mismatch)", mg.Version, mg.Name)
+	}
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(mg.Down).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&schemaMigration{}, mg.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s down: %w", mg.Version, mg.Name, err)
+	}
+	return nil
+}
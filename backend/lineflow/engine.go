@@ -0,0 +1,485 @@
+// engine.go
+package lineflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+// LINE の会話エンジン。
+// 状態・遷移・ガード・状態ごとのプロンプトをここで宣言し、
+// コントローラーは Begin → 副作用（DB 登録など）→ Commit の順に呼ぶ。
+// 遷移の判定（Machine.Next）は純粋関数なので LINE / Redis なしで確認できる（Engine は MemoryStore で動かせる）。
+
+type Trigger string
+
+const (
+	TriggerStart          Trigger = "start"
+	TriggerEnd            Trigger = "end"
+	TriggerAdd            Trigger = "add"
+	TriggerExercise       Trigger = "exercise"
+	TriggerExercisePage   Trigger = "exercise_page"
+	TriggerPickExercise   Trigger = "pick_exercise"
+	TriggerWeight         Trigger = "weight"
+	TriggerCount          Trigger = "count"
+	TriggerCancel         Trigger = "cancel"
+	TriggerEntryConfirm   Trigger = "entry_confirm"
+	TriggerEntryCancel    Trigger = "entry_cancel"
+	TriggerToday          Trigger = "today"
+	TriggerWeek           Trigger = "week"
+	TriggerSnooze         Trigger = "snooze"
+	TriggerSettings       Trigger = "settings"        // 設定の確認・項目ごとの選択肢
+	TriggerSetSetting     Trigger = "set_setting"     // 設定の変更
+	TriggerResume         Trigger = "resume"          // 終了していないワークアウトを再開
+	TriggerCloseAndStart  Trigger = "close_and_start" // 終了していないワークアウトを閉じて新しく開始
+	TriggerTemplates      Trigger = "templates"       // テンプレートの一覧
+	TriggerStartTemplate  Trigger = "start_template"  // テンプレートから開始
+	TriggerCloneLast      Trigger = "clone_last"      // 前回のワークアウトの進め方を選ぶ
+	TriggerCloneWorkout   Trigger = "clone_workout"   // 前回のワークアウトを複製して開始
+	TriggerUndo           Trigger = "undo"
+	TriggerEditLast       Trigger = "edit_last"
+	TriggerRepeatLast     Trigger = "repeat_last"
+	TriggerEditText       Trigger = "edit_text"       // 修正内容のテキスト
+	TriggerRestTimer      Trigger = "rest_timer"      // 直前のセットのあとの休憩タイマーを始める
+	TriggerCancelRest     Trigger = "cancel_rest"     // 休憩タイマーを止める
+	TriggerNextSet        Trigger = "next_set"        // 休憩のあと、同じ種目・重さで次のセットを入力する
+	TriggerSearchExercise Trigger = "search_exercise" // 種目選択中のテキスト
+	TriggerWeightText     Trigger = "weight_text"     // 重量入力中のテキスト
+	TriggerCountText      Trigger = "count_text"      // 回数入力中のテキスト
+	TriggerQuickEntry     Trigger = "quick_entry"     // 「ベンチ 60x8x3」形式のテキスト
+	TriggerText           Trigger = "text"            // どれにも当てはまらないテキスト
+)
+
+// Input はポストバック or テキストを正規化したもの
+type Input struct {
+	Trigger Trigger
+	Params  url.Values   // ポストバックのパラメータ
+	Text    string       // テキストメッセージ
+	Entry   *ParsedEntry // TriggerQuickEntry のときだけ
+}
+
+// Guard は遷移できるか判定する。エラーの文言はそのまま利用者に返す
+type Guard func(s LineWorkoutState, in Input) error
+
+type Transition struct {
+	On   Trigger
+	From []State // 空ならどの状態からでも
+	To   State   // 空なら状態を変えない
+	// 処理結果によって To の代わりに遷移してよい状態
+	Alt   []State
+	Guard Guard
+	// Apply は遷移後の状態に入力を反映する（副作用なし）
+	Apply func(s *LineWorkoutState, in Input)
+}
+
+func (t *Transition) from(st State) bool {
+	if len(t.From) == 0 {
+		return true
+	}
+	for _, f := range t.From {
+		if f == st {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows は t のあとに st へ遷移してよいか
+func (t *Transition) Allows(before, st State) bool {
+	if t.To == "" {
+		return st == before
+	}
+	if st == t.To {
+		return true
+	}
+	for _, a := range t.Alt {
+		if a == st {
+			return true
+		}
+	}
+	return false
+}
+
+// Rejection は入力を受け付けなかったときのエラー。
+// 状態は変えずに、理由と今の状態のプロンプトを返す。
+type Rejection struct {
+	State  State
+	Reason string
+	Prompt string
+}
+
+func (r *Rejection) Error() string {
+	if r.Reason == "" {
+		return r.Prompt
+	}
+	return r.Reason + "\n" + r.Prompt
+}
+
+type Machine struct {
+	prompts     map[State]string
+	transitions []Transition
+}
+
+// NewMachine は Logbook の LINE 会話の定義を返す
+func NewMachine() *Machine {
+	return &Machine{
+		prompts: map[State]string{
+			StateIdle:         "「開始」でワークアウトを始めましょう💪",
+			StateInWorkout:    "『追加』で種目を選ぶか、「ベンチ 60x8x3」のように送ると記録できます。終わったら『終了』を押してください",
+			StateAddExercise:  "種目を選ぶか、種目名を送ってください（「ベンチ 60x8x3」のように送ると一括で記録できます）",
+			StateAddWeight:    "重量を送ってください（例: 60 / 60kg / 135lb。単位を省くと設定の単位）",
+			StateAddCount:     "回数を送ってください（例: 8）",
+			StateConfirmEntry: "表示された候補から種目を選ぶか、『キャンセル』を押してください",
+			StateEditSet:      "修正後の内容を送ってください（例: 60kg / 8回 / 60x8）",
+		},
+		transitions: []Transition{
+			// 終了していないワークアウトがあれば開始せず、再開するか閉じるかを聞く（アイドルのまま）
+			{On: TriggerStart, To: StateInWorkout, Alt: []State{StateIdle}, Apply: resetState},
+			{
+				On: TriggerResume, To: StateInWorkout,
+				Guard: requireParam("workoutId"),
+				Apply: func(s *LineWorkoutState, in Input) {
+					*s = LineWorkoutState{State: s.State, WorkoutID: in.Params.Get("workoutId")}
+				},
+			},
+			{On: TriggerCloseAndStart, To: StateInWorkout, Guard: requireParam("workoutId"), Apply: resetState},
+			// テンプレートからの開始も、終了していないワークアウトがあれば聞く
+			{On: TriggerTemplates},
+			{On: TriggerStartTemplate, To: StateInWorkout, Alt: []State{StateIdle}, Guard: requireParam("templateId"), Apply: resetState},
+			{On: TriggerCloneLast},
+			{On: TriggerCloneWorkout, To: StateInWorkout, Alt: []State{StateIdle}, Guard: requireParam("cloneOf"), Apply: resetState},
+			{On: TriggerEnd, To: StateIdle, Guard: requireWorkout, Apply: resetState},
+			{On: TriggerAdd, To: StateAddExercise, Guard: requireWorkout},
+			{On: TriggerExercise, To: StateAddExercise, Guard: requireWorkout},
+			{On: TriggerExercisePage},
+			{On: TriggerSearchExercise, From: []State{StateAddExercise}},
+			{
+				On: TriggerPickExercise, To: StateAddWeight,
+				Guard: all(requireWorkout, requireParam("exerciseId")),
+				Apply: func(s *LineWorkoutState, in Input) {
+					s.Pending = Pending{ExerciseID: in.Params.Get("exerciseId")}
+				},
+			},
+			{On: TriggerWeight, From: []State{StateAddWeight, StateAddCount}, To: StateAddWeight, Guard: requireExercise},
+			{On: TriggerCount, From: []State{StateAddWeight, StateAddCount}, To: StateAddCount, Guard: requireWeight},
+			{
+				On: TriggerWeightText, From: []State{StateAddWeight}, To: StateAddCount,
+				Guard: func(_ LineWorkoutState, in Input) error {
+					if _, _, ok := ParseWeightText(in.Text); !ok {
+						return errors.New("重量は0以上の数値で送ってください（例: 60 / 135lb）")
+					}
+					return nil
+				},
+				Apply: func(s *LineWorkoutState, in Input) {
+					w, unit, _ := ParseWeightText(in.Text)
+					s.Pending.Weight, s.Pending.WeightUnit = &w, unit
+				},
+			},
+			{
+				On: TriggerCountText, From: []State{StateAddCount}, To: StateInWorkout,
+				Guard: func(s LineWorkoutState, in Input) error {
+					if _, ok := ParseCountText(in.Text); !ok {
+						return errors.New("回数は正の整数で送ってください")
+					}
+					return requireWeight(s, in)
+				},
+				Apply: func(s *LineWorkoutState, in Input) {
+					n, _ := ParseCountText(in.Text)
+					s.Pending.Repetitions = &n
+				},
+			},
+			{
+				On:    TriggerQuickEntry,
+				From:  []State{StateIdle, StateInWorkout, StateAddExercise, StateConfirmEntry},
+				To:    StateInWorkout,
+				Alt:   []State{StateConfirmEntry},
+				Guard: requireWorkout,
+			},
+			{On: TriggerEntryConfirm, From: []State{StateConfirmEntry}, To: StateInWorkout, Guard: requireCandidate},
+			{On: TriggerEntryCancel, From: []State{StateConfirmEntry}, To: StateInWorkout},
+			// ワークアウト外ならアイドルに戻る
+			{On: TriggerCancel, To: StateInWorkout, Alt: []State{StateIdle}},
+			// 直前のセット（LINE から登録した最新のもの）の取り消し・修正・もう1セット
+			{On: TriggerUndo},
+			{On: TriggerRepeatLast},
+			{On: TriggerEditLast, To: StateEditSet},
+			{
+				On: TriggerEditText, From: []State{StateEditSet}, To: StateInWorkout, Alt: []State{StateIdle},
+				Guard: func(s LineWorkoutState, in Input) error {
+					if s.EditSetID == "" {
+						return errors.New("修正するセットが見つかりません")
+					}
+					_, err := ParseSetEdit(in.Text)
+					return err
+				},
+			},
+			// 休憩タイマーは状態を変えない。終わったら同じ種目・重さで回数の入力に進む（重さが無ければ重さから）
+			{On: TriggerRestTimer},
+			{On: TriggerCancelRest},
+			{
+				On: TriggerNextSet, To: StateAddCount, Alt: []State{StateAddWeight},
+				Guard: all(requireWorkout, requireParam("exerciseId")),
+				Apply: func(s *LineWorkoutState, in Input) {
+					s.Pending = Pending{ExerciseID: in.Params.Get("exerciseId")}
+					w, err := strconv.ParseFloat(in.Params.Get("weight"), 64)
+					if err != nil || w < 0 {
+						s.State = StateAddWeight
+						return
+					}
+					s.Pending.Weight = &w
+					if unit := models.WeightUnit(in.Params.Get("unit")); unit.Valid() {
+						s.Pending.WeightUnit = unit
+					}
+				},
+			},
+			// 記録の確認は入力の途中でもできる（状態は変えない）
+			{On: TriggerToday},
+			{On: TriggerWeek},
+			{On: TriggerSnooze},
+			// 設定（タイムゾーン・週の始まりなど）も状態は変えない
+			{On: TriggerSettings},
+			{On: TriggerSetSetting, Guard: requireParam("key")},
+		},
+	}
+}
+
+// Prompt は状態ごとの案内文
+func (m *Machine) Prompt(st State) string {
+	return m.prompts[st]
+}
+
+// Next は s に in を与えたときの遷移先を返す。受け付けられない入力は *Rejection。
+// 返す状態は Apply 済み。入力途中の情報は Commit で入力系の状態のときだけ残す。
+func (m *Machine) Next(s LineWorkoutState, in Input) (LineWorkoutState, *Transition, error) {
+	known := false
+	for i := range m.transitions {
+		t := &m.transitions[i]
+		if t.On != in.Trigger {
+			continue
+		}
+		known = true
+		if !t.from(s.State) {
+			continue
+		}
+		if t.Guard != nil {
+			if err := t.Guard(s, in); err != nil {
+				return s, nil, m.reject(s.State, err.Error())
+			}
+		}
+
+		next := s
+		if t.To != "" {
+			next.State = t.To
+		}
+		if t.Apply != nil {
+			t.Apply(&next, in)
+		}
+		return next, t, nil
+	}
+
+	switch {
+	case !known && in.Trigger != TriggerText:
+		return s, nil, m.reject(s.State, "未対応の操作です")
+	case in.Trigger == TriggerText:
+		return s, nil, m.reject(s.State, "")
+	default:
+		return s, nil, m.reject(s.State, "いまはその操作はできません")
+	}
+}
+
+func (m *Machine) reject(st State, reason string) *Rejection {
+	return &Rejection{State: st, Reason: reason, Prompt: m.Prompt(st)}
+}
+
+// Step は 1 回の入力で選ばれた遷移。
+// State は処理結果に応じて Transition.Alt の状態に書き換えてよい。
+type Step struct {
+	Before     LineWorkoutState
+	State      LineWorkoutState
+	Input      Input
+	Transition *Transition
+}
+
+type Engine struct {
+	machine *Machine
+	store   Store
+}
+
+func NewEngine(store Store) *Engine {
+	return &Engine{machine: NewMachine(), store: store}
+}
+
+func (e *Engine) Prompt(st State) string { return e.machine.Prompt(st) }
+
+func (e *Engine) Load(ctx context.Context, lineUID string) LineWorkoutState {
+	s, _ := LoadState(ctx, e.store, lineUID)
+	return s
+}
+
+// Begin は現在の状態を読み、in による遷移を決める（まだ保存はしない）
+func (e *Engine) Begin(ctx context.Context, lineUID string, in Input) (*Step, error) {
+	s := e.Load(ctx, lineUID)
+	next, t, err := e.machine.Next(s, in)
+	if err != nil {
+		return nil, err
+	}
+	return &Step{Before: s, State: next, Input: in, Transition: t}, nil
+}
+
+// Commit は副作用が成功したあとに遷移後の状態を保存する
+func (e *Engine) Commit(ctx context.Context, lineUID string, step *Step) error {
+	st := step.State
+	if !step.Transition.Allows(step.Before.State, st.State) {
+		return fmt.Errorf("lineflow: %s から %s への遷移は宣言されていません（%s）", step.Before.State, st.State, step.Input.Trigger)
+	}
+	switch st.State {
+	case StateAddExercise, StateAddWeight, StateAddCount:
+	default:
+		st.Pending = Pending{}
+	}
+	if st.State != StateConfirmEntry {
+		st.Draft = nil
+	}
+	if st.State != StateEditSet {
+		st.EditSetID = ""
+	}
+	if st.State == StateIdle && st.WorkoutID == "" {
+		ClearState(ctx, e.store, lineUID)
+		return nil
+	}
+	return SaveState(ctx, e.store, lineUID, st)
+}
+
+// Forget はワークアウトが会話の外（自動終了・Web）で終了されたとき、状態がそれを指していれば片付ける
+func (e *Engine) Forget(ctx context.Context, lineUID, workoutID string) {
+	if s := e.Load(ctx, lineUID); s.WorkoutID == workoutID {
+		ClearState(ctx, e.store, lineUID)
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ClassifyText はテキストメッセージを今の状態に応じた Input にする
+func ClassifyText(s LineWorkoutState, text string) (Input, error) {
+	text = strings.TrimSpace(text)
+
+	// 「ベンチ 60x8x3」のような一括入力は重量・回数の入力中以外なら受け付ける
+	switch s.State {
+	case StateIdle, StateInWorkout, StateAddExercise, StateConfirmEntry:
+		entry, err := ParseSetText(text)
+		if err == nil {
+			return Input{Trigger: TriggerQuickEntry, Text: text, Entry: entry}, nil
+		}
+		if !errors.Is(err, ErrNotSetEntry) {
+			return Input{}, err
+		}
+	}
+
+	switch s.State {
+	case StateAddExercise:
+		// 種目ID 以外は種目名の検索として扱う
+		if uuidPattern.MatchString(text) {
+			return Input{Trigger: TriggerPickExercise, Text: text, Params: url.Values{"exerciseId": {text}}}, nil
+		}
+		return Input{Trigger: TriggerSearchExercise, Text: text}, nil
+	case StateAddWeight:
+		return Input{Trigger: TriggerWeightText, Text: text}, nil
+	case StateAddCount:
+		return Input{Trigger: TriggerCountText, Text: text}, nil
+	case StateEditSet:
+		return Input{Trigger: TriggerEditText, Text: text}, nil
+	}
+	return Input{Trigger: TriggerText, Text: text}, nil
+}
+
+// ParseWeightText は「60」「60kg」「135 lbs」「６０」を重さと単位にする（単位が無ければ ""）
+func ParseWeightText(text string) (float64, models.WeightUnit, bool) {
+	t := strings.ToLower(textReplacer.Replace(toHalfWidthDigits(strings.TrimSpace(text))))
+	m := reWeightText.FindStringSubmatch(strings.ReplaceAll(t, " ", ""))
+	if m == nil {
+		return 0, "", false
+	}
+	w, err := strconv.ParseFloat(m[1], 64)
+	unit := parseUnit(m[2])
+	if err != nil || w < 0 || tooHeavy(w, unit) {
+		return 0, "", false
+	}
+	return w, unit, true
+}
+
+// ParseCountText は「8」「8回」を回数にする
+func ParseCountText(text string) (int, bool) {
+	t := strings.TrimSuffix(toHalfWidthDigits(strings.TrimSpace(text)), "回")
+	n, err := strconv.Atoi(strings.TrimSpace(t))
+	if err != nil || n <= 0 || n > maxParsedReps {
+		return 0, false
+	}
+	return n, true
+}
+
+// guards ----------------------------------------------------------------------
+
+func all(guards ...Guard) Guard {
+	return func(s LineWorkoutState, in Input) error {
+		for _, g := range guards {
+			if err := g(s, in); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func requireWorkout(s LineWorkoutState, _ Input) error {
+	if s.WorkoutID == "" {
+		return errors.New("まず「開始」してください")
+	}
+	return nil
+}
+
+func requireParam(name string) Guard {
+	return func(_ LineWorkoutState, in Input) error {
+		if in.Params.Get(name) == "" {
+			return errors.New("操作の内容を読み取れませんでした")
+		}
+		return nil
+	}
+}
+
+func requireExercise(s LineWorkoutState, _ Input) error {
+	if s.Pending.ExerciseID == "" {
+		return errors.New("先に種目を選んでください")
+	}
+	return nil
+}
+
+func requireWeight(s LineWorkoutState, in Input) error {
+	if err := requireExercise(s, in); err != nil {
+		return err
+	}
+	if s.Pending.Weight == nil {
+		return errors.New("先に重量を送ってください")
+	}
+	return nil
+}
+
+func requireCandidate(s LineWorkoutState, in Input) error {
+	if s.Draft == nil {
+		return errors.New("確認待ちの入力がありません。もう一度送ってください")
+	}
+	if _, ok := s.Draft.Candidate(in.Params.Get("exerciseId")); !ok {
+		return errors.New("選択された種目が見つかりません")
+	}
+	return nil
+}
+
+func resetState(s *LineWorkoutState, _ Input) {
+	*s = LineWorkoutState{State: s.State}
+}
@@ -0,0 +1,209 @@
+package lineflow
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+const testWorkoutID = "w-1"
+const testExerciseID = "0b6c7a3e-2f1d-4c5b-9a8e-7d6f5e4c3b2a"
+
+func params(kv ...string) url.Values {
+	v := url.Values{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		v.Set(kv[i], kv[i+1])
+	}
+	return v
+}
+
+func TestMachineNext(t *testing.T) {
+	w := 60.0
+	inWorkout := LineWorkoutState{State: StateInWorkout, WorkoutID: testWorkoutID}
+	picked := LineWorkoutState{State: StateAddWeight, WorkoutID: testWorkoutID, Pending: Pending{ExerciseID: testExerciseID}}
+	weighed := LineWorkoutState{State: StateAddCount, WorkoutID: testWorkoutID, Pending: Pending{ExerciseID: testExerciseID, Weight: &w}}
+
+	cases := []struct {
+		name       string
+		from       LineWorkoutState
+		in         Input
+		want       State
+		wantReason string // 空でなければ Rejection になる
+		rejected   bool
+	}{
+		{name: "start from idle", from: LineWorkoutState{State: StateIdle}, in: Input{Trigger: TriggerStart}, want: StateInWorkout},
+		{name: "add without workout", from: LineWorkoutState{State: StateIdle}, in: Input{Trigger: TriggerAdd}, rejected: true, wantReason: "まず「開始」してください"},
+		{name: "add in workout", from: inWorkout, in: Input{Trigger: TriggerAdd}, want: StateAddExercise},
+		{name: "pick exercise", from: LineWorkoutState{State: StateAddExercise, WorkoutID: testWorkoutID}, in: Input{Trigger: TriggerPickExercise, Params: params("exerciseId", testExerciseID)}, want: StateAddWeight},
+		{name: "weight text", from: picked, in: Input{Trigger: TriggerWeightText, Text: "60kg"}, want: StateAddCount},
+		{name: "bad weight text", from: picked, in: Input{Trigger: TriggerWeightText, Text: "重い"}, rejected: true, wantReason: "重量は0以上の数値で送ってください（例: 60 / 135lb）"},
+		{name: "count text", from: weighed, in: Input{Trigger: TriggerCountText, Text: "8回"}, want: StateInWorkout},
+		{name: "count in wrong state", from: inWorkout, in: Input{Trigger: TriggerCountText, Text: "8"}, rejected: true, wantReason: "いまはその操作はできません"},
+		{name: "unknown postback", from: inWorkout, in: Input{Trigger: "dance"}, rejected: true, wantReason: "未対応の操作です"},
+		{name: "free text re-prompts", from: inWorkout, in: Input{Trigger: TriggerText, Text: "こんにちは"}, rejected: true},
+		{name: "end resets", from: weighed, in: Input{Trigger: TriggerEnd}, want: StateIdle},
+	}
+
+	m := NewMachine()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			next, tr, err := m.Next(tc.from, tc.in)
+			if tc.rejected {
+				var rej *Rejection
+				if !errors.As(err, &rej) {
+					t.Fatalf("err = %v, want *Rejection", err)
+				}
+				// 状態は変えず、今の状態のプロンプトで聞き直す
+				if rej.State != tc.from.State || rej.Prompt != m.Prompt(tc.from.State) || rej.Reason != tc.wantReason {
+					t.Errorf("rejection = %+v", rej)
+				}
+				if next.State != tc.from.State {
+					t.Errorf("state = %s, want unchanged %s", next.State, tc.from.State)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Next: %v", err)
+			}
+			if tr == nil || next.State != tc.want {
+				t.Errorf("state = %s, want %s", next.State, tc.want)
+			}
+		})
+	}
+}
+
+// step は Begin → Commit を 1 回行う
+func step(t *testing.T, e *Engine, uid string, in Input) LineWorkoutState {
+	t.Helper()
+	ctx := context.Background()
+	st, err := e.Begin(ctx, uid, in)
+	if err != nil {
+		t.Fatalf("Begin(%s): %v", in.Trigger, err)
+	}
+	if in.Trigger == TriggerStart {
+		st.State.WorkoutID = testWorkoutID // コントローラーがワークアウトを作った
+	}
+	if err := e.Commit(ctx, uid, st); err != nil {
+		t.Fatalf("Commit(%s): %v", in.Trigger, err)
+	}
+	return e.Load(ctx, uid)
+}
+
+func TestEngineAddSetFlow(t *testing.T) {
+	ctx := context.Background()
+	e := NewEngine(NewMemoryStore())
+	const uid = "U1"
+
+	steps := []struct {
+		in   Input
+		want State
+	}{
+		{Input{Trigger: TriggerStart}, StateInWorkout},
+		{Input{Trigger: TriggerAdd}, StateAddExercise},
+		{Input{Trigger: TriggerPickExercise, Params: params("exerciseId", testExerciseID)}, StateAddWeight},
+		{Input{Trigger: TriggerWeightText, Text: "60"}, StateAddCount},
+	}
+	var s LineWorkoutState
+	for _, st := range steps {
+		if s = step(t, e, uid, st.in); s.State != st.want {
+			t.Fatalf("after %s: state = %s, want %s", st.in.Trigger, s.State, st.want)
+		}
+	}
+	if s.Pending.ExerciseID != testExerciseID || s.Pending.Weight == nil || *s.Pending.Weight != 60 {
+		t.Fatalf("pending = %+v", s.Pending)
+	}
+
+	// 受け付けない入力は保存されている状態を変えない
+	if _, err := e.Begin(ctx, uid, Input{Trigger: TriggerCountText, Text: "0"}); err == nil {
+		t.Fatal("Begin with 0 reps: want rejection")
+	}
+	if got := e.Load(ctx, uid); got.State != StateAddCount || got.Pending.Weight == nil {
+		t.Fatalf("state after rejection = %+v", got)
+	}
+
+	s = step(t, e, uid, Input{Trigger: TriggerCountText, Text: "8"})
+	if s.State != StateInWorkout || s.WorkoutID != testWorkoutID {
+		t.Fatalf("after count: %+v", s)
+	}
+	if s.Pending != (Pending{}) {
+		t.Errorf("Commit kept pending input: %+v", s.Pending)
+	}
+
+	// 終了でアイドルに戻り、保存していた状態も消える
+	s = step(t, e, uid, Input{Trigger: TriggerEnd})
+	if s.State != StateIdle || s.WorkoutID != "" {
+		t.Fatalf("after end: %+v", s)
+	}
+	if raw, _ := e.store.Get(ctx, redisKey(uid)); raw != "" {
+		t.Errorf("state was not cleared: %s", raw)
+	}
+}
+
+func TestEngineCommitClearsTransientState(t *testing.T) {
+	w := 60.0
+	draft := &Draft{Candidates: []DraftCandidate{{ExerciseID: testExerciseID, Name: "ベンチプレス"}}}
+	cases := []struct {
+		name  string
+		saved LineWorkoutState
+		in    Input
+		want  State
+	}{
+		{
+			name:  "confirm entry clears draft",
+			saved: LineWorkoutState{State: StateConfirmEntry, WorkoutID: testWorkoutID, Draft: draft},
+			in:    Input{Trigger: TriggerEntryConfirm, Params: params("exerciseId", testExerciseID)},
+			want:  StateInWorkout,
+		},
+		{
+			name:  "edit text clears edit target",
+			saved: LineWorkoutState{State: StateEditSet, WorkoutID: testWorkoutID, EditSetID: "s-1"},
+			in:    Input{Trigger: TriggerEditText, Text: "60x8"},
+			want:  StateInWorkout,
+		},
+		{
+			name:  "cancel clears pending",
+			saved: LineWorkoutState{State: StateAddCount, WorkoutID: testWorkoutID, Pending: Pending{ExerciseID: testExerciseID, Weight: &w}},
+			in:    Input{Trigger: TriggerCancel},
+			want:  StateInWorkout,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			e := NewEngine(NewMemoryStore())
+			if err := SaveState(ctx, e.store, "U1", tc.saved); err != nil {
+				t.Fatal(err)
+			}
+			s := step(t, e, "U1", tc.in)
+			if s.State != tc.want {
+				t.Fatalf("state = %s, want %s", s.State, tc.want)
+			}
+			if s.Pending != (Pending{}) || s.Draft != nil || s.EditSetID != "" {
+				t.Errorf("Commit kept transient state: pending=%+v draft=%+v editSetID=%q", s.Pending, s.Draft, s.EditSetID)
+			}
+			if s.WorkoutID != testWorkoutID {
+				t.Errorf("workoutID = %q, want %q", s.WorkoutID, testWorkoutID)
+			}
+		})
+	}
+}
+
+func TestEngineCommitRejectsUndeclaredState(t *testing.T) {
+	ctx := context.Background()
+	e := NewEngine(NewMemoryStore())
+	if err := SaveState(ctx, e.store, "U1", LineWorkoutState{State: StateInWorkout, WorkoutID: testWorkoutID}); err != nil {
+		t.Fatal(err)
+	}
+	st, err := e.Begin(ctx, "U1", Input{Trigger: TriggerAdd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.State.State = StateEditSet // 追加の遷移先として宣言されていない
+	if err := e.Commit(ctx, "U1", st); err == nil {
+		t.Fatal("Commit: want error for undeclared transition")
+	}
+	if got := e.Load(ctx, "U1"); got.State != StateInWorkout {
+		t.Errorf("state = %s, want %s", got.State, StateInWorkout)
+	}
+}
@@ -7,28 +7,48 @@ import (
 	"fmt"
 	"time"
 
-	usecaseLine "github.com/sirasu21/Logbook/backend/usecase/LINE"
+	"github.com/sirasu21/Logbook/backend/models"
 )
 
 type State string
 
 const (
-	StateIdle        State = "idle"
-	StateAddExercise State = "add_exercise"
-	StateAddWeight   State = "add_weight"
-	StateAddCount    State = "add_count"
+	StateIdle         State = "idle"       // ワークアウト外
+	StateInWorkout    State = "in_workout" // ワークアウト中（入力待ちなし）
+	StateAddExercise  State = "add_exercise"
+	StateAddWeight    State = "add_weight"
+	StateAddCount     State = "add_count"
+	StateConfirmEntry State = "confirm_entry" // 自然文入力の確認待ち
+	StateEditSet      State = "edit_set"      // 直前のセットの修正内容待ち
 )
 
+// 会話の状態はワークアウト中の ID も含めてこの TTL で保持する（操作のたびに延長）
+const StateTTL = 12 * time.Hour
+
 type Pending struct {
-	ExerciseID  string   `json:"exerciseId"`
-	Weight      *float64 `json:"weight,omitempty"`
-	Repetitions *int     `json:"repetitions,omitempty"`
+	ExerciseID  string            `json:"exerciseId"`
+	Weight      *float64          `json:"weight,omitempty"`
+	WeightUnit  models.WeightUnit `json:"weightUnit,omitempty"` // "" ならユーザー設定の単位
+	Repetitions *int              `json:"repetitions,omitempty"`
+}
+
+// Draft は自然文入力のうち、登録前に確認が必要なもの
+type Draft struct {
+	Entry      ParsedEntry      `json:"entry"`
+	Candidates []DraftCandidate `json:"candidates"`
+}
+
+type DraftCandidate struct {
+	ExerciseID string `json:"exerciseId"`
+	Name       string `json:"name"`
 }
 
 type LineWorkoutState struct {
 	State     State     `json:"state"`
 	WorkoutID string    `json:"workoutId"`
 	Pending   Pending   `json:"pending"`
+	Draft     *Draft    `json:"draft,omitempty"`
+	EditSetID string    `json:"editSetId,omitempty"` // StateEditSet で修正するセット
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
@@ -43,27 +63,42 @@ func (s *LineWorkoutState) Ready() bool {
 		s.Pending.Repetitions != nil
 }
 
+// Candidate は exerciseID が確認待ちの候補に含まれていればそれを返す
+func (d *Draft) Candidate(exerciseID string) (DraftCandidate, bool) {
+	for _, c := range d.Candidates {
+		if c.ExerciseID == exerciseID {
+			return c, true
+		}
+	}
+	return DraftCandidate{}, false
+}
+
 func redisKey(lineUserID string) string {
 	return fmt.Sprintf("line:ctx:%s:state", lineUserID)
 }
 
+// Store は状態の保存先。usecaseLine.LineUsecase がそのまま満たす
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, val any, ttl time.Duration) error
+	Del(ctx context.Context, key string) error
+}
 
-
-func LoadState(ctx context.Context, lineuc usecaseLine.LineUsecase, lineUID string) (LineWorkoutState, error) {
-	raw, err := lineuc.Get(ctx, redisKey(lineUID))
+func LoadState(ctx context.Context, st Store, lineUID string) (LineWorkoutState, error) {
+	raw, err := st.Get(ctx, redisKey(lineUID))
 	if err != nil || raw == "" {
 		return defaultState(), nil
 	}
 	var s LineWorkoutState
-	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+	if err := json.Unmarshal([]byte(raw), &s); err != nil || s.State == "" {
 		return defaultState(), nil
 	}
 	return s, nil
 }
 
-func SaveState(ctx context.Context, lineuc usecaseLine.LineUsecase, lineUID string, s LineWorkoutState, ttl time.Duration) error {
+func SaveState(ctx context.Context, st Store, lineUID string, s LineWorkoutState) error {
 	s.Touch()
-	return lineuc.Set(ctx, redisKey(lineUID), s, ttl)
+	return st.Set(ctx, redisKey(lineUID), s, StateTTL)
 }
 
-func ClearState(ctx context.Context, lineuc usecaseLine.LineUsecase, lineUID string) { _ = lineuc.Del(ctx, redisKey(lineUID)) }
\ No newline at end of file
+func ClearState(ctx context.Context, st Store, lineUID string) { _ = st.Del(ctx, redisKey(lineUID)) }
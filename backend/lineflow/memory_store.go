@@ -0,0 +1,62 @@
+package lineflow
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// MemoryStore は Redis を使わない Store（会話の遷移確認・ローカル用）。
+// 値は LineUsecase.Set と同じく JSON で持ち、期限が切れたキーは無いものとして扱う。
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+	now   func() time.Time
+}
+
+type memoryItem struct {
+	val       string
+	expiresAt time.Time // ゼロ値なら期限なし
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: map[string]memoryItem{}, now: time.Now}
+}
+
+// Get はキーが無ければ "" を返す（LoadState は初期状態として扱う）
+func (m *MemoryStore) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	it, ok := m.items[key]
+	if !ok {
+		return "", nil
+	}
+	if !it.expiresAt.IsZero() && !m.now().Before(it.expiresAt) {
+		delete(m.items, key)
+		return "", nil
+	}
+	return it.val, nil
+}
+
+func (m *MemoryStore) Set(ctx context.Context, key string, val any, ttl time.Duration) error {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	it := memoryItem{val: string(b)}
+	if ttl > 0 {
+		it.expiresAt = m.now().Add(ttl)
+	}
+	m.items[key] = it
+	return nil
+}
+
+func (m *MemoryStore) Del(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, key)
+	return nil
+}
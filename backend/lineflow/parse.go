@@ -0,0 +1,320 @@
+// parse.go
+package lineflow
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+// 「ベンチ 60x8x3」「deadlift 140kg 5 reps @8」「squat 100x5, 105x5, 110x3」「bench 135lbx5」のような
+// 自由入力を、種目名 + セットの並びに分解する。
+
+var ErrNotSetEntry = errors.New("not a set entry")
+
+type ParsedSet struct {
+	Weight *float64          `json:"weight,omitempty"`
+	Unit   models.WeightUnit `json:"unit,omitempty"` // 単位を付けずに送った重さは ""（ユーザー設定の単位）
+	Reps   int               `json:"reps"`
+	RPE    *float64          `json:"rpe,omitempty"`
+}
+
+type ParsedEntry struct {
+	Exercise string      `json:"exercise"` // 入力された種目名（エイリアス解決前）
+	Sets     []ParsedSet `json:"sets"`
+	// 単位の無い数字を重量/回数と推測した場合 true（登録前に確認する）
+	Ambiguous bool `json:"ambiguous"`
+}
+
+const (
+	maxParsedReps   = 100
+	maxParsedSets   = 20
+	maxParsedWeight = 1000 // kg
+)
+
+var (
+	// 60x8 / 60kgx8x3 / 135lbx5 / 60x8@8
+	reCompact = regexp.MustCompile(`^(\d+(?:\.\d+)?)(kg|lbs?)?x(\d+)(?:x(\d+))?(?:@(\d+(?:\.\d+)?))?$`)
+	reWeight  = regexp.MustCompile(`^(\d+(?:\.\d+)?)(kg|lbs?)$`)
+	reReps    = regexp.MustCompile(`^(\d+)(?:reps?|回)$`)
+	reSets    = regexp.MustCompile(`^(\d+)(?:sets?|セット)$`)
+	reRPE     = regexp.MustCompile(`^(?:@|rpe)(\d+(?:\.\d+)?)$`)
+	reNumber  = regexp.MustCompile(`^\d+(?:\.\d+)?$`)
+	// 重量だけの入力（60 / 60kg / 135lbs）
+	reWeightText = regexp.MustCompile(`^(\d+(?:\.\d+)?)(kg|lbs?)?$`)
+
+	// 「5 reps」「60 kg」「135 lbs」のように数字と単位の間に空白がある入力をくっつける
+	reUnitGap = regexp.MustCompile(`(\d)\s+(kg|lbs?|reps?|回|sets?|セット)`)
+	// 「60 x 8」「@ 8」の空白を詰める
+	reTimesGap = regexp.MustCompile(`(\d(?:kg|lbs?)?)\s*x\s*(\d)`)
+	reAtGap    = regexp.MustCompile(`@\s+`)
+)
+
+var textReplacer = strings.NewReplacer(
+	"×", "x", "＊", "x", "*", "x", "ｘ", "x", "Ｘ", "x",
+	"，", ",", "、", ",", "／", ",", "/", ",", ";", ",",
+	"＠", "@", "．", ".", "　", " ",
+	"ｋｇ", "kg", "キロ", "kg", "ｌｂ", "lb", "ポンド", "lb",
+)
+
+// ParseSetText は自由入力を解析する。
+// セット入力として読めない場合は ErrNotSetEntry を返す（呼び出し側は通常フローへ）。
+func ParseSetText(text string) (*ParsedEntry, error) {
+	s := strings.ToLower(textReplacer.Replace(toHalfWidthDigits(strings.TrimSpace(text))))
+	if s == "" {
+		return nil, ErrNotSetEntry
+	}
+
+	// 先頭の「数字で始まらないトークン」を種目名とみなす
+	fields := strings.Fields(s)
+	nameEnd := 0
+	for nameEnd < len(fields) && !startsWithDigit(fields[nameEnd]) {
+		nameEnd++
+	}
+	if nameEnd == 0 || nameEnd == len(fields) {
+		return nil, ErrNotSetEntry
+	}
+	entry := &ParsedEntry{Exercise: strings.Join(fields[:nameEnd], " ")}
+
+	rest := strings.Join(fields[nameEnd:], " ")
+	rest = reUnitGap.ReplaceAllString(rest, "$1$2")
+	rest = reTimesGap.ReplaceAllString(rest, "${1}x$2")
+	rest = reAtGap.ReplaceAllString(rest, "@")
+
+	for _, seg := range strings.Split(rest, ",") {
+		seg = strings.TrimSpace(seg)
+		if seg == "" {
+			continue
+		}
+		sets, ambiguous, err := parseSegment(seg)
+		if err != nil {
+			return nil, err
+		}
+		entry.Sets = append(entry.Sets, sets...)
+		entry.Ambiguous = entry.Ambiguous || ambiguous
+	}
+	if len(entry.Sets) == 0 {
+		return nil, ErrNotSetEntry
+	}
+	if len(entry.Sets) > maxParsedSets {
+		return nil, errors.New("セット数が多すぎます")
+	}
+	return entry, nil
+}
+
+// SetEdit は直前のセットの修正内容（指定された項目だけ変える）
+type SetEdit struct {
+	Weight *float64
+	Unit   models.WeightUnit // 単位を付けずに「60x8」と送ったら ""
+	Reps   *int
+	RPE    *float64
+}
+
+// ParseSetEdit は「60x8」「60kg」「135lb」「8回」「60kg 8回 @8」のような修正入力を解析する。
+// 単位の無い数字は何を直したいのか分からないので受け付けない。
+func ParseSetEdit(text string) (*SetEdit, error) {
+	s := strings.ToLower(textReplacer.Replace(toHalfWidthDigits(strings.TrimSpace(text))))
+	s = reUnitGap.ReplaceAllString(s, "$1$2")
+	s = reTimesGap.ReplaceAllString(s, "${1}x$2")
+	s = reAtGap.ReplaceAllString(s, "@")
+
+	edit := &SetEdit{}
+	if m := reCompact.FindStringSubmatch(strings.ReplaceAll(s, " ", "")); m != nil {
+		if m[4] != "" {
+			return nil, errors.New("修正では セット数 は指定できません（例: 60x8）")
+		}
+		w, _ := strconv.ParseFloat(m[1], 64)
+		reps, _ := strconv.Atoi(m[3])
+		edit.Weight, edit.Unit, edit.Reps = &w, parseUnit(m[2]), &reps
+		if m[5] != "" {
+			v, _ := strconv.ParseFloat(m[5], 64)
+			edit.RPE = &v
+		}
+	} else {
+		for _, tok := range strings.Fields(s) {
+			switch {
+			case reWeight.MatchString(tok):
+				m := reWeight.FindStringSubmatch(tok)
+				v, _ := strconv.ParseFloat(m[1], 64)
+				edit.Weight, edit.Unit = &v, parseUnit(m[2])
+			case reReps.MatchString(tok):
+				v, _ := strconv.Atoi(reReps.FindStringSubmatch(tok)[1])
+				edit.Reps = &v
+			case reRPE.MatchString(tok):
+				v, _ := strconv.ParseFloat(reRPE.FindStringSubmatch(tok)[1], 64)
+				edit.RPE = &v
+			default:
+				return nil, errors.New("単位を付けて送ってください（例: 60kg / 8回 / 60x8）")
+			}
+		}
+	}
+
+	switch {
+	case edit.Weight == nil && edit.Reps == nil && edit.RPE == nil:
+		return nil, errors.New("修正後の内容を送ってください（例: 60kg / 8回 / 60x8）")
+	case edit.Weight != nil && tooHeavy(*edit.Weight, edit.Unit):
+		return nil, errors.New("重量が大きすぎます")
+	case edit.Reps != nil && (*edit.Reps <= 0 || *edit.Reps > maxParsedReps):
+		return nil, errors.New("回数は1〜100で入力してください")
+	case edit.RPE != nil && *edit.RPE > 10:
+		return nil, errors.New("RPE は10以下で入力してください")
+	}
+	return edit, nil
+}
+
+// parseSegment は 1 区切り分（カンマ区切りの 1 要素）を解析する。
+func parseSegment(seg string) ([]ParsedSet, bool, error) {
+	if m := reCompact.FindStringSubmatch(strings.ReplaceAll(seg, " ", "")); m != nil {
+		w, _ := strconv.ParseFloat(m[1], 64)
+		reps, _ := strconv.Atoi(m[3])
+		count := 1
+		if m[4] != "" {
+			count, _ = strconv.Atoi(m[4])
+		}
+		var rpe *float64
+		if m[5] != "" {
+			v, _ := strconv.ParseFloat(m[5], 64)
+			rpe = &v
+		}
+		return expandSets(&w, parseUnit(m[2]), reps, count, rpe, false)
+	}
+
+	var (
+		weight *float64
+		unit   models.WeightUnit
+		reps   *int
+		count  *int
+		rpe    *float64
+		bare   []string
+	)
+	for _, tok := range strings.Fields(seg) {
+		switch {
+		case reWeight.MatchString(tok):
+			m := reWeight.FindStringSubmatch(tok)
+			v, _ := strconv.ParseFloat(m[1], 64)
+			weight, unit = &v, parseUnit(m[2])
+		case reReps.MatchString(tok):
+			v, _ := strconv.Atoi(reReps.FindStringSubmatch(tok)[1])
+			reps = &v
+		case reSets.MatchString(tok):
+			v, _ := strconv.Atoi(reSets.FindStringSubmatch(tok)[1])
+			count = &v
+		case reRPE.MatchString(tok):
+			v, _ := strconv.ParseFloat(reRPE.FindStringSubmatch(tok)[1], 64)
+			rpe = &v
+		case reNumber.MatchString(tok):
+			bare = append(bare, tok)
+		default:
+			return nil, false, ErrNotSetEntry
+		}
+	}
+
+	// 単位の無い数字は 重量 → 回数 → セット数 の順に空きを埋める（推測扱い）
+	ambiguous := false
+	for _, tok := range bare {
+		ambiguous = true
+		switch {
+		case weight == nil && reps == nil:
+			v, _ := strconv.ParseFloat(tok, 64)
+			weight = &v
+		case reps == nil:
+			v, err := strconv.Atoi(tok)
+			if err != nil {
+				return nil, false, ErrNotSetEntry
+			}
+			reps = &v
+		case count == nil:
+			v, err := strconv.Atoi(tok)
+			if err != nil {
+				return nil, false, ErrNotSetEntry
+			}
+			count = &v
+		default:
+			return nil, false, ErrNotSetEntry
+		}
+	}
+	if reps == nil {
+		return nil, false, ErrNotSetEntry
+	}
+	n := 1
+	if count != nil {
+		n = *count
+	}
+	return expandSets(weight, unit, *reps, n, rpe, ambiguous)
+}
+
+func expandSets(weight *float64, unit models.WeightUnit, reps, count int, rpe *float64, ambiguous bool) ([]ParsedSet, bool, error) {
+	if reps <= 0 || reps > maxParsedReps {
+		return nil, false, errors.New("回数は1〜100で入力してください")
+	}
+	if count <= 0 || count > maxParsedSets {
+		return nil, false, errors.New("セット数は1〜20で入力してください")
+	}
+	if weight != nil && (*weight < 0 || tooHeavy(*weight, unit)) {
+		return nil, false, errors.New("重量は0〜1000kg（2200lb）で入力してください")
+	}
+	if rpe != nil && (*rpe < 1 || *rpe > 10) {
+		return nil, false, errors.New("RPEは1〜10で入力してください")
+	}
+	sets := make([]ParsedSet, 0, count)
+	for i := 0; i < count; i++ {
+		sets = append(sets, ParsedSet{Weight: weight, Unit: unit, Reps: reps, RPE: rpe})
+	}
+	return sets, ambiguous, nil
+}
+
+// parseUnit は正規表現で拾った単位（"kg" / "lb" / "lbs" / ""）を WeightUnit にする
+func parseUnit(s string) models.WeightUnit {
+	if strings.HasPrefix(s, "lb") {
+		return models.WeightUnitLb
+	}
+	return models.WeightUnit(s)
+}
+
+// tooHeavy は kg にして上限を超えるか（単位が無ければ数値のまま比べる）
+func tooHeavy(v float64, unit models.WeightUnit) bool {
+	return models.ToKg(v, unit) > maxParsedWeight
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+func toHalfWidthDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '０' && r <= '９' {
+			return r - '０' + '0'
+		}
+		return r
+	}, s)
+}
+
+// 種目名のよくある略称 → シード済み種目名
+var exerciseAliases = map[string]string{
+	"ベンチ":      "ベンチプレス",
+	"bench":    "ベンチプレス",
+	"bp":       "ベンチプレス",
+	"デッド":      "デッドリフト",
+	"deadlift": "デッドリフト",
+	"dl":       "デッドリフト",
+	"スクワット":    "バックスクワット",
+	"squat":    "バックスクワット",
+	"sq":       "バックスクワット",
+	"ラットプル":    "ラットプルダウン",
+	"lat":      "ラットプルダウン",
+	"ショルダー":    "ショルダープレス",
+	"ohp":      "ショルダープレス",
+}
+
+// ExerciseSearchTerms は種目名の検索候補（入力そのもの + エイリアス先）を返す。
+func ExerciseSearchTerms(name string) []string {
+	name = strings.TrimSpace(name)
+	terms := []string{name}
+	if alias, ok := exerciseAliases[strings.ToLower(name)]; ok && alias != name {
+		terms = append(terms, alias)
+	}
+	return terms
+}
@@ -0,0 +1,158 @@
+package lineflow
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// describeSets はセットを「60kg x 8 @8」形式にする（単位が無ければ「60 x 8」）
+func describeSets(sets []ParsedSet) []string {
+	out := make([]string, 0, len(sets))
+	for _, s := range sets {
+		var b strings.Builder
+		if s.Weight != nil {
+			fmt.Fprintf(&b, "%g%s x ", *s.Weight, s.Unit)
+		}
+		fmt.Fprintf(&b, "%d", s.Reps)
+		if s.RPE != nil {
+			fmt.Fprintf(&b, " @%g", *s.RPE)
+		}
+		out = append(out, b.String())
+	}
+	return out
+}
+
+func TestParseSetText(t *testing.T) {
+	cases := []struct {
+		text      string
+		exercise  string
+		sets      []string
+		ambiguous bool
+	}{
+		{text: "ベンチ 60x8x3", exercise: "ベンチ", sets: []string{"60 x 8", "60 x 8", "60 x 8"}},
+		{text: "deadlift 140kg 5 reps @8", exercise: "deadlift", sets: []string{"140kg x 5 @8"}},
+		{text: "squat 100x5, 105x5, 110x3", exercise: "squat", sets: []string{"100 x 5", "105 x 5", "110 x 3"}},
+		{text: "bench 135lbx5", exercise: "bench", sets: []string{"135lb x 5"}},
+		{text: "ベンチ　６０×８", exercise: "ベンチ", sets: []string{"60 x 8"}},
+		{text: "ベンチ 60 x 8 @ 7.5", exercise: "ベンチ", sets: []string{"60 x 8 @7.5"}},
+		{text: "Lat Pulldown 50キロ 10回 2セット", exercise: "lat pulldown", sets: []string{"50kg x 10", "50kg x 10"}},
+		{text: "スクワット 100 5、105 5", exercise: "スクワット", sets: []string{"100 x 5", "105 x 5"}, ambiguous: true},
+		{text: "スクワット 100 5 3", exercise: "スクワット", sets: []string{"100 x 5", "100 x 5", "100 x 5"}, ambiguous: true},
+		{text: "懸垂 10回", exercise: "懸垂", sets: []string{"10"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.text, func(t *testing.T) {
+			got, err := ParseSetText(tc.text)
+			if err != nil {
+				t.Fatalf("ParseSetText: %v", err)
+			}
+			if got.Exercise != tc.exercise {
+				t.Errorf("exercise = %q, want %q", got.Exercise, tc.exercise)
+			}
+			if sets := describeSets(got.Sets); !reflect.DeepEqual(sets, tc.sets) {
+				t.Errorf("sets = %q, want %q", sets, tc.sets)
+			}
+			if got.Ambiguous != tc.ambiguous {
+				t.Errorf("ambiguous = %v, want %v", got.Ambiguous, tc.ambiguous)
+			}
+		})
+	}
+}
+
+func TestParseSetTextRejects(t *testing.T) {
+	cases := []struct {
+		text     string
+		notEntry bool // ErrNotSetEntry（通常の会話として扱う）か、入力ミスとして理由を返すか
+	}{
+		{text: "", notEntry: true},
+		{text: "こんにちは", notEntry: true},
+		{text: "ベンチ", notEntry: true},
+		{text: "60x8", notEntry: true},
+		{text: "ベンチ 60kg", notEntry: true},
+		{text: "ベンチ 60x8 よろしく", notEntry: true},
+		{text: "ベンチ 1 2 3 4", notEntry: true},
+		{text: "スクワット 100、105", notEntry: true},
+		{text: "ベンチ 60x0"},
+		{text: "ベンチ 60x101"},
+		{text: "ベンチ 60x8x21"},
+		{text: "ベンチ 1001kg 5回"},
+		{text: "ベンチ 2300lbx5"},
+		{text: "ベンチ 60x8@11"},
+		{text: "ベンチ 60x5x10, 60x5x11"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.text, func(t *testing.T) {
+			got, err := ParseSetText(tc.text)
+			if err == nil {
+				t.Fatalf("ParseSetText = %+v, want error", got)
+			}
+			if errors.Is(err, ErrNotSetEntry) != tc.notEntry {
+				t.Errorf("err = %v, want ErrNotSetEntry = %v", err, tc.notEntry)
+			}
+		})
+	}
+}
+
+func TestParseSetEdit(t *testing.T) {
+	cases := []struct {
+		text string
+		want string // 変える項目だけ（weight / reps / rpe）
+	}{
+		{text: "60x8", want: "weight=60 reps=8"},
+		{text: "62.5kg 8回 @8", want: "weight=62.5kg reps=8 rpe=8"},
+		{text: "135lb", want: "weight=135lb"},
+		{text: "８回", want: "reps=8"},
+		{text: "rpe9", want: "rpe=9"},
+		{text: "60 kg", want: "weight=60kg"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.text, func(t *testing.T) {
+			got, err := ParseSetEdit(tc.text)
+			if err != nil {
+				t.Fatalf("ParseSetEdit: %v", err)
+			}
+			var parts []string
+			if got.Weight != nil {
+				parts = append(parts, fmt.Sprintf("weight=%g%s", *got.Weight, got.Unit))
+			}
+			if got.Reps != nil {
+				parts = append(parts, fmt.Sprintf("reps=%d", *got.Reps))
+			}
+			if got.RPE != nil {
+				parts = append(parts, fmt.Sprintf("rpe=%g", *got.RPE))
+			}
+			if s := strings.Join(parts, " "); s != tc.want {
+				t.Errorf("edit = %q, want %q", s, tc.want)
+			}
+		})
+	}
+
+	for _, text := range []string{"", "60", "60x8x3", "0回", "101回", "@11", "2000kg", "ベンチ 60kg"} {
+		t.Run("reject "+text, func(t *testing.T) {
+			if got, err := ParseSetEdit(text); err == nil {
+				t.Errorf("ParseSetEdit = %+v, want error", got)
+			}
+		})
+	}
+}
+
+func TestExerciseSearchTerms(t *testing.T) {
+	cases := []struct {
+		name string
+		want []string
+	}{
+		{name: "ベンチ", want: []string{"ベンチ", "ベンチプレス"}},
+		{name: "DL", want: []string{"DL", "デッドリフト"}},
+		{name: " squat ", want: []string{"squat", "バックスクワット"}},
+		{name: "ベンチプレス", want: []string{"ベンチプレス"}},
+		{name: "ケーブルフライ", want: []string{"ケーブルフライ"}},
+	}
+	for _, tc := range cases {
+		if got := ExerciseSearchTerms(tc.name); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ExerciseSearchTerms(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
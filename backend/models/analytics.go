@@ -0,0 +1,108 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// E1RMFormula は推定 1RM の計算式
+type E1RMFormula string
+
+const (
+	FormulaEpley    E1RMFormula = "epley"    // w × (1 + r/30)
+	FormulaBrzycki  E1RMFormula = "brzycki"  // w × 36 / (37 - r)
+	FormulaLombardi E1RMFormula = "lombardi" // w × r^0.10
+)
+
+func (f E1RMFormula) Valid() bool {
+	switch f {
+	case FormulaEpley, FormulaBrzycki, FormulaLombardi:
+		return true
+	}
+	return false
+}
+
+// RecordFormula は自己ベスト（PR）の推定 1RM の式。進捗グラフも formula を指定しなければこれで出す
+const RecordFormula = FormulaEpley
+
+// 推定 1RM は回数が多いと当てにならないので MaxE1RMReps 回までのセットで出す（自己ベストも進捗グラフも）
+const MaxE1RMReps = 12
+
+// E1RMReps は推定 1RM に使う回数。RPE があれば残り回数（10 - RPE）を足す
+func E1RMReps(reps int, rpe *float32) float64 {
+	r := float64(reps)
+	if rpe != nil && *rpe < 10 {
+		r += 10 - float64(*rpe)
+	}
+	return r
+}
+
+// Estimate は重量 w と回数 r（E1RMReps）からの推定 1RM。1 回ならどの式も重量そのもの。
+// 式が使えない回数（Brzycki の 37 回以上）なら false。進捗グラフの SQL も同じ式で計算する
+func (f E1RMFormula) Estimate(w, r float64) (float64, bool) {
+	if r <= 1 {
+		return w, true
+	}
+	switch f {
+	case FormulaEpley:
+		return w * (1 + r/30), true
+	case FormulaBrzycki:
+		if r >= 37 {
+			return 0, false
+		}
+		return w * 36 / (37 - r), true
+	case FormulaLombardi:
+		return w * math.Pow(r, 0.10), true
+	}
+	return 0, false
+}
+
+// ExerciseProgress は種目のワークアウトごとの推移（グラフ用）
+type ExerciseProgress struct {
+	ExerciseID string          `json:"exerciseId"`
+	Formula    E1RMFormula     `json:"formula"`
+	From       *time.Time      `json:"from,omitempty"`
+	To         *time.Time      `json:"to,omitempty"`
+	Points     []ProgressPoint `json:"points"` // 開始日時の古い順
+}
+
+// ProgressPoint は 1 回のワークアウトでのその種目の集計（ウォームアップ・未実施は除く）
+type ProgressPoint struct {
+	WorkoutID string    `json:"workoutId"`
+	StartedAt time.Time `json:"startedAt"`
+	// 最も高い推定 1RM（MaxE1RMReps 回までのセット）。RPE があれば残り回数（10 - RPE）を足した回数で計算する
+	BestE1RM *float64       `gorm:"column:best_e1rm" json:"bestE1rm,omitempty"`
+	TopSet   ProgressTopSet `gorm:"embedded;embeddedPrefix:top_" json:"topSet"`
+	VolumeKg float64        `json:"volumeKg"` // 重量 × 回数 の合計
+	Sets     int            `json:"sets"`     // ドロップセット・クラスターはグループで 1 セット
+	Reps     int            `json:"reps"`
+}
+
+// ProgressTopSet は最も重いセット（同じ重量なら回数の多いほう）
+type ProgressTopSet struct {
+	WeightKg *float32 `json:"weightKg,omitempty"`
+	Reps     *int     `json:"reps,omitempty"`
+	RPE      *float32 `json:"rpe,omitempty"`
+}
+
+// MuscleVolumeReport は週ごと・部位ごとのセット数と挙上量（部位の偏りを見る用）
+type MuscleVolumeReport struct {
+	From  time.Time            `json:"from"`
+	To    time.Time            `json:"to"`
+	Weeks []WeeklyMuscleVolume `json:"weeks"` // 古い週から
+}
+
+type WeeklyMuscleVolume struct {
+	WeekStart time.Time      `json:"weekStart"`
+	Muscles   []MuscleVolume `json:"muscles"` // MuscleGroups の順。0 の部位も含む
+	// 部位が未設定の種目のセット数
+	UnassignedSets int `json:"unassignedSets"`
+}
+
+// MuscleVolume は 1 部位の集計。協働筋のセットは寄与率をかけて数える
+type MuscleVolume struct {
+	Muscle    string  `json:"muscle"`
+	Name      string  `json:"name"`
+	HardSets  float64 `json:"hardSets"`  // ウォームアップ・未実施を除いたセット数（ドロップセット・クラスターはグループで 1）
+	TonnageKg float64 `json:"tonnageKg"` // 重量 × 回数
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// AuditSource は変更がどこから来たか
+type AuditSource string
+
+const (
+	AuditSourceWeb      AuditSource = "web"       // Web アプリ（セッション）
+	AuditSourceLine     AuditSource = "line"      // LINE Bot
+	AuditSourceAPIToken AuditSource = "api_token" // API トークン（トークン認証を入れたらそこで設定する）
+	AuditSourceJob      AuditSource = "job"       // スケジューラーなどのジョブ（操作したユーザーなし）
+	AuditSourceUnknown  AuditSource = "unknown"   // ctx に経路が無かったとき
+)
+
+// AuditEntity は変更したものの種類
+type AuditEntity string
+
+const (
+	AuditWorkout    AuditEntity = "workout"
+	AuditSet        AuditEntity = "workout_set"
+	AuditExercise   AuditEntity = "exercise"
+	AuditBodyMetric AuditEntity = "body_metric"
+	AuditTemplate   AuditEntity = "template"
+)
+
+func (e AuditEntity) Valid() bool {
+	switch e {
+	case AuditWorkout, AuditSet, AuditExercise, AuditBodyMetric, AuditTemplate:
+		return true
+	}
+	return false
+}
+
+// AuditAction は変更の種類
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"  // ゴミ箱に入れた
+	AuditRestore AuditAction = "restore" // ゴミ箱から戻した
+	AuditPurge   AuditAction = "purge"   // 保存期間が過ぎてゴミ箱から完全に消した（ジョブのみ）
+)
+
+// AuditLog は変更履歴の 1 件（追記のみ。DB のトリガーで更新・削除を止めている）
+type AuditLog struct {
+	ID string `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	// データの持ち主（アクティビティの絞り込みに使う）
+	UserID string `gorm:"type:uuid;not null" json:"userId"`
+	// 操作したユーザー（ジョブなら nil）
+	ActorUserID *string     `gorm:"type:uuid"         json:"actorUserId,omitempty"`
+	Source      AuditSource `gorm:"size:16;not null"  json:"source"`
+	// Web は X-Request-Id、LINE は webhookEventId
+	RequestID  string      `gorm:"size:64"           json:"requestId,omitempty"`
+	EntityType AuditEntity `gorm:"size:32;not null"  json:"entityType"`
+	EntityID   string      `gorm:"type:uuid;not null" json:"entityId"`
+	// ワークアウトとそのセットの変更に入れる（ワークアウトの履歴に使う）
+	WorkoutID *string      `gorm:"type:uuid"         json:"workoutId,omitempty"`
+	Action    AuditAction  `gorm:"size:16;not null"  json:"action"`
+	Changes   AuditChanges `gorm:"type:jsonb;not null" json:"changes"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+// FieldChange は 1 項目の変更前と変更後（作成なら Before、削除なら After が nil）
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditChanges は JSON の項目名ごとの変更。jsonb で保存する
+type AuditChanges map[string]FieldChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (c *AuditChanges) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	case nil:
+		*c = nil
+		return nil
+	default:
+		return errors.New("audit changes: unsupported type")
+	}
+	return json.Unmarshal(b, c)
+}
+
+// auditIgnoredFields は差分に出さない項目（毎回変わるもの・表示用のもの）
+var auditIgnoredFields = map[string]bool{
+	"id": true, "createdAt": true, "updatedAt": true, "version": true,
+	"records": true, "displayWeight": true, "displayUnit": true,
+}
+
+// DiffForAudit は before と after（同じ型のモデル。nil 可）を JSON にして、違う項目だけを返す
+func DiffForAudit(before, after any) (AuditChanges, error) {
+	b, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+	out := AuditChanges{}
+	for k, bv := range b {
+		if av, ok := a[k]; !ok || !reflect.DeepEqual(bv, av) {
+			out[k] = FieldChange{Before: bv, After: av}
+		}
+	}
+	for k, av := range a {
+		if _, ok := b[k]; !ok {
+			out[k] = FieldChange{After: av}
+		}
+	}
+	return out, nil
+}
+
+func auditFields(v any) (map[string]any, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil()) {
+		return map[string]any{}, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]any{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	for k, val := range m {
+		if auditIgnoredFields[k] || val == nil {
+			delete(m, k)
+		}
+	}
+	return m, nil
+}
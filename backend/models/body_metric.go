@@ -1,15 +1,41 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type BodyMetric struct {
 	ID         string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	UserID     string    `gorm:"type:uuid;index;not null"                       json:"userId"`
 	MeasuredAt time.Time `gorm:"not null;index"                                  json:"measuredAt"`
 	WeightKg   float32   `gorm:"not null"                                        json:"weightKg"`
-	BodyFatPct *float32  `json:"bodyFatPct,omitempty"`
-	Note       *string   `gorm:"type:text"                                       json:"note,omitempty"`
-	CreatedAt  time.Time `json:"createdAt"`
-	UpdatedAt  time.Time `json:"updatedAt"`
+	// 入力された体重と単位（WeightKg はこれを kg にした値）
+	WeightValue *float32   `json:"weightValue,omitempty"`
+	WeightUnit  WeightUnit `gorm:"size:2" json:"weightUnit,omitempty"`
+	BodyFatPct  *float32   `json:"bodyFatPct,omitempty"`
+	Note        *string    `gorm:"type:text"                                       json:"note,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	// ゴミ箱に入れた時刻
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	// 更新のたびに 1 上がる（ETag / If-Match に使う）
+	Version int `gorm:"not null;default:1" json:"version"`
+
+	// ユーザー設定の単位での体重（API の返却時に Localize で入れる）
+	DisplayWeight *float32   `gorm:"-" json:"displayWeight,omitempty"`
+	DisplayUnit   WeightUnit `gorm:"-" json:"displayUnit,omitempty"`
 }
 
+// SetWeight は入力された体重と単位を入れ、WeightKg も合わせる
+func (m *BodyMetric) SetWeight(v float32, unit WeightUnit) {
+	m.WeightKg = float32(ToKg(float64(v), unit))
+	m.WeightValue, m.WeightUnit = &v, unit
+}
+
+// Localize は DisplayWeight / DisplayUnit を unit で入れる
+func (m *BodyMetric) Localize(unit WeightUnit) {
+	v := weightIn(m.WeightKg, m.WeightValue, m.WeightUnit, unit)
+	m.DisplayWeight, m.DisplayUnit = &v, unit
+}
@@ -0,0 +1,45 @@
+package models
+
+// Calendar は 1 か月分のトレーニングカレンダー（日付はすべて Timezone の日付）
+type Calendar struct {
+	Year      int             `json:"year"`
+	Month     int             `json:"month"`
+	Timezone  string          `json:"timezone"`
+	WeekStart int             `json:"weekStart"` // 週の始まり（0 = 日曜, 1 = 月曜）
+	Days      []CalendarDay   `json:"days"`      // 月の全日（トレーニングしていない日も 0 で入る）
+	Streak    StreakSummary   `json:"streak"`
+	Adherence WeeklyAdherence `json:"adherence"`
+}
+
+type CalendarDay struct {
+	Date        string   `json:"date"` // YYYY-MM-DD
+	Workouts    int      `json:"workouts"`
+	Sets        int      `json:"sets"`
+	DurationSec int      `json:"durationSec"`
+	Muscles     []string `json:"muscles"` // 主動筋の key（MuscleGroups の順）
+}
+
+// StreakSummary は連続日数。今日まだやっていなければ昨日までの連続を今の連続とみなす
+type StreakSummary struct {
+	Current      int     `json:"current"`
+	CurrentSince *string `json:"currentSince,omitempty"` // YYYY-MM-DD
+	Longest      int     `json:"longest"`
+	LongestFrom  *string `json:"longestFrom,omitempty"`
+	LongestTo    *string `json:"longestTo,omitempty"`
+}
+
+// WeeklyAdherence は月にかかる週ごとに、週の目標日数を達成したか
+type WeeklyAdherence struct {
+	TargetDays int             `json:"targetDays"`
+	Weeks      []WeekAdherence `json:"weeks"`
+	MetWeeks   int             `json:"metWeeks"`
+	// 終わった週（今週は達成済みなら含める）のうち達成した割合
+	Rate float64 `json:"rate"`
+}
+
+type WeekAdherence struct {
+	WeekStart    string `json:"weekStart"` // YYYY-MM-DD
+	TrainingDays int    `json:"trainingDays"`
+	Met          bool   `json:"met"`
+	InProgress   bool   `json:"inProgress"` // 今週（または未来の週）
+}
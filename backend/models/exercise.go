@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type ExerciseType string
 
@@ -17,10 +21,17 @@ type Exercise struct {
 	Type          ExerciseType `gorm:"type:text;not null"                             json:"type"`
 	PrimaryMuscle *string      `gorm:"size:64"                                        json:"primaryMuscle,omitempty"`
 	IsActive      bool         `gorm:"not null;default:true"                          json:"isActive"`
-	CreatedAt     time.Time    `json:"createdAt"`
-	UpdatedAt     time.Time    `json:"updatedAt"`
+	// LINE の休憩タイマーの既定の長さ（秒）。無ければセットの restSec か 90 秒
+	DefaultRestSec *int      `json:"defaultRestSec,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	// ゴミ箱に入れた時刻（セットで使ったことのある種目はゴミ箱に入れず isActive=false にする）
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 効く部位（集計用）。PrimaryMuscle は表示用に残す
+	Muscles []ExerciseMuscle `gorm:"foreignKey:ExerciseID" json:"muscles,omitempty"`
 }
 
-// （メモ）一意制約はマイグレーションで張るのがおすすめ：
+// 一意制約（db/migrations/0002_exercise_unique_names）：
 // - グローバル: owner_user_id IS NULL のとき name UNIQUE
 // - 独自種目: UNIQUE (owner_user_id, name)
@@ -0,0 +1,125 @@
+package models
+
+import "strings"
+
+// 部位の分類。muscle_groups テーブルは MuscleGroups から作る（cmd/migrate）
+type MuscleGroup struct {
+	Key      string `gorm:"size:32;primaryKey" json:"key"`
+	Name     string `gorm:"size:32;not null"   json:"name"`
+	Region   string `gorm:"size:16;not null"   json:"region"` // upper / lower / core
+	Position int    `gorm:"not null"           json:"position"`
+}
+
+const (
+	MuscleChest      = "chest"
+	MuscleBack       = "back"
+	MuscleShoulders  = "shoulders"
+	MuscleBiceps     = "biceps"
+	MuscleTriceps    = "triceps"
+	MuscleForearms   = "forearms"
+	MuscleAbs        = "abs"
+	MuscleQuads      = "quads"
+	MuscleHamstrings = "hamstrings"
+	MuscleGlutes     = "glutes"
+	MuscleCalves     = "calves"
+)
+
+// MuscleGroups は表示順どおりの部位一覧
+var MuscleGroups = []MuscleGroup{
+	{Key: MuscleChest, Name: "胸", Region: "upper", Position: 1},
+	{Key: MuscleBack, Name: "背中", Region: "upper", Position: 2},
+	{Key: MuscleShoulders, Name: "肩", Region: "upper", Position: 3},
+	{Key: MuscleBiceps, Name: "上腕二頭筋", Region: "upper", Position: 4},
+	{Key: MuscleTriceps, Name: "上腕三頭筋", Region: "upper", Position: 5},
+	{Key: MuscleForearms, Name: "前腕", Region: "upper", Position: 6},
+	{Key: MuscleAbs, Name: "腹", Region: "core", Position: 7},
+	{Key: MuscleQuads, Name: "大腿四頭筋", Region: "lower", Position: 8},
+	{Key: MuscleHamstrings, Name: "ハムストリングス", Region: "lower", Position: 9},
+	{Key: MuscleGlutes, Name: "臀部", Region: "lower", Position: 10},
+	{Key: MuscleCalves, Name: "ふくらはぎ", Region: "lower", Position: 11},
+}
+
+// FindMuscleGroup は key の部位（無ければ nil）
+func FindMuscleGroup(key string) *MuscleGroup {
+	for i := range MuscleGroups {
+		if MuscleGroups[i].Key == key {
+			return &MuscleGroups[i]
+		}
+	}
+	return nil
+}
+
+type MuscleRole string
+
+const (
+	MuscleRolePrimary   MuscleRole = "primary"
+	MuscleRoleSecondary MuscleRole = "secondary"
+)
+
+// 寄与率を省略したときの既定値
+const (
+	DefaultPrimaryContribution   float32 = 1.0
+	DefaultSecondaryContribution float32 = 0.5
+)
+
+// ExerciseMuscle は種目が効く部位と寄与率（1 セットを部位ごとに何セット分と数えるか）
+type ExerciseMuscle struct {
+	ExerciseID   string     `gorm:"type:uuid;primaryKey" json:"-"`
+	Muscle       string     `gorm:"size:32;primaryKey"   json:"muscle"`
+	Role         MuscleRole `gorm:"size:16;not null"     json:"role"`
+	Contribution float32    `gorm:"not null;default:1"   json:"contribution"` // 0 < x <= 1
+}
+
+type ExerciseMuscleInput struct {
+	Muscle       string     `json:"muscle"`
+	Role         MuscleRole `json:"role"`
+	Contribution *float32   `json:"contribution,omitempty"` // 省略時は primary 1.0 / secondary 0.5
+}
+
+// muscleLabels は自由入力だった PrimaryMuscle からの対応表
+var muscleLabels = map[string][]ExerciseMuscle{
+	"胸": primary(MuscleChest), "大胸筋": primary(MuscleChest), "chest": primary(MuscleChest), "pecs": primary(MuscleChest),
+	"背中": primary(MuscleBack), "広背筋": primary(MuscleBack), "back": primary(MuscleBack), "lats": primary(MuscleBack),
+	"肩": primary(MuscleShoulders), "三角筋": primary(MuscleShoulders), "shoulders": primary(MuscleShoulders), "delts": primary(MuscleShoulders),
+	"二頭": primary(MuscleBiceps), "上腕二頭筋": primary(MuscleBiceps), "biceps": primary(MuscleBiceps),
+	"三頭": primary(MuscleTriceps), "上腕三頭筋": primary(MuscleTriceps), "triceps": primary(MuscleTriceps),
+	"前腕": primary(MuscleForearms), "forearms": primary(MuscleForearms),
+	"腹": primary(MuscleAbs), "腹筋": primary(MuscleAbs), "体幹": primary(MuscleAbs), "abs": primary(MuscleAbs), "core": primary(MuscleAbs),
+	"大腿四頭筋": primary(MuscleQuads), "quads": primary(MuscleQuads),
+	"ハムストリングス": primary(MuscleHamstrings), "ハム": primary(MuscleHamstrings), "hamstrings": primary(MuscleHamstrings),
+	"尻": primary(MuscleGlutes), "お尻": primary(MuscleGlutes), "臀部": primary(MuscleGlutes), "glutes": primary(MuscleGlutes),
+	"ふくらはぎ": primary(MuscleCalves), "カーフ": primary(MuscleCalves), "calves": primary(MuscleCalves),
+	// まとめた呼び方は部位に割り振る
+	"腕": arms(), "arms": arms(),
+	"脚": legs(), "足": legs(), "legs": legs(),
+}
+
+func primary(muscle string) []ExerciseMuscle {
+	return []ExerciseMuscle{{Muscle: muscle, Role: MuscleRolePrimary, Contribution: DefaultPrimaryContribution}}
+}
+
+func arms() []ExerciseMuscle {
+	return []ExerciseMuscle{
+		{Muscle: MuscleBiceps, Role: MuscleRolePrimary, Contribution: 0.5},
+		{Muscle: MuscleTriceps, Role: MuscleRolePrimary, Contribution: 0.5},
+	}
+}
+
+func legs() []ExerciseMuscle {
+	return []ExerciseMuscle{
+		{Muscle: MuscleQuads, Role: MuscleRolePrimary, Contribution: DefaultPrimaryContribution},
+		{Muscle: MuscleGlutes, Role: MuscleRoleSecondary, Contribution: DefaultSecondaryContribution},
+		{Muscle: MuscleHamstrings, Role: MuscleRoleSecondary, Contribution: DefaultSecondaryContribution},
+	}
+}
+
+// MusclesFromLabel は PrimaryMuscle の文字列を部位に直す（分からなければ nil）
+func MusclesFromLabel(label string) []ExerciseMuscle {
+	ms, ok := muscleLabels[strings.ToLower(strings.TrimSpace(label))]
+	if !ok {
+		return nil
+	}
+	out := make([]ExerciseMuscle, len(ms))
+	copy(out, ms)
+	return out
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// RecordKind は自己ベストの種類
+type RecordKind string
+
+const (
+	RecordMaxWeight     RecordKind = "max_weight"     // 最大重量
+	RecordRepsAtWeight  RecordKind = "reps_at_weight" // その重量での最多回数
+	RecordE1RM          RecordKind = "e1rm"           // 推定 1RM（RecordFormula。RPE があれば補正する）
+	RecordSessionVolume RecordKind = "session_volume" // 1 回のワークアウトでの総挙上量（kg×回）
+)
+
+// PersonalRecord は自己ベストを更新した履歴の 1 件。
+// 種目ごとに記録から作り直すので、セットを修正・削除しても辻褄が合う
+type PersonalRecord struct {
+	ID         string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey"       json:"id"`
+	UserID     string     `gorm:"type:uuid;index:idx_record_exercise,priority:1;not null" json:"userId"`
+	ExerciseID string     `gorm:"type:uuid;index:idx_record_exercise,priority:2;not null" json:"exerciseId"`
+	Kind       RecordKind `gorm:"size:32;not null"                                      json:"kind"`
+
+	WeightKg *float32 `json:"weightKg,omitempty"` // session_volume 以外
+	Reps     *int     `json:"reps,omitempty"`     // session_volume 以外
+	Value    float64  `gorm:"not null"          json:"value"`
+	// 更新前の値。その種類の最初の記録なら nil
+	PreviousValue *float64 `json:"previousValue,omitempty"`
+
+	WorkoutID  string    `gorm:"type:uuid;not null" json:"workoutId"`
+	SetID      *string   `gorm:"type:uuid;index"    json:"setId,omitempty"` // session_volume は nil
+	AchievedAt time.Time `gorm:"not null"           json:"achievedAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// IsImprovement は前の記録を上回ったか（最初の記録は含めない）
+func (r PersonalRecord) IsImprovement() bool {
+	return r.PreviousValue != nil
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// ReminderPreference は LINE リマインドの設定（ユーザーごとに 1 件）
+type ReminderPreference struct {
+	ID      string `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID  string `gorm:"type:uuid;uniqueIndex;not null"                 json:"userId"`
+	Enabled bool   `gorm:"not null;default:true"                          json:"enabled"`
+	// 通知する曜日のビットマスク（bit0 = 日曜 … bit6 = 土曜）
+	Weekdays int `gorm:"not null;default:0" json:"-"`
+	// 通知時刻（ユーザーのタイムゾーンでの "HH:MM"）
+	RemindAt string `gorm:"size:5;not null;default:'19:00'" json:"remindAt"`
+	// 最後のワークアウトからこの日数が空いたら声をかける（0 なら送らない）
+	InactiveDays int  `gorm:"not null;default:3"    json:"inactiveDays"`
+	WeeklyRecap  bool `gorm:"not null;default:true" json:"weeklyRecap"`
+	// LINE で開始したワークアウトを、最後のセットからこの時間操作がなければ自動で終了する（0 なら終了しない）
+	AutoCloseHours int `gorm:"not null;default:6" json:"autoCloseHours"`
+
+	SnoozedUntil   *time.Time `json:"snoozedUntil,omitempty"`
+	LastRemindedAt *time.Time `json:"-"`
+	LastNudgedAt   *time.Time `json:"-"`
+	LastRecapAt    *time.Time `json:"-"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ReminderTarget はスケジューラーが送信先を決めるときの 1 行
+type ReminderTarget struct {
+	ReminderPreference
+	LineUserID    string
+	LastWorkoutAt *time.Time
+	Timezone      string // user_settings.timezone（未設定なら既定のタイムゾーン）
+}
+
+// Location は日付・時刻を判定するユーザーのタイムゾーン
+func (t ReminderTarget) Location() *time.Location {
+	return LoadLocation(t.Timezone)
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	// DefaultRestSec は種目にもセットにも休憩の長さが無いときの休憩タイマー（秒）
+	DefaultRestSec = 90
+	// MaxRestSec は休憩タイマー・種目の既定の休憩の上限（秒）
+	MaxRestSec = 3600
+)
+
+// RestTimer は LINE でセットを記録したあとの休憩タイマー（ユーザーごとに 1 つ。Redis に置く）。
+// 終わったら次のセットを同じ種目・重さで入力できるように、記録したセットの内容を持つ
+type RestTimer struct {
+	LineUserID string     `json:"lineUserId"`
+	UserID     string     `json:"userId"`
+	WorkoutID  string     `json:"workoutId"`
+	SetID      string     `json:"setId"`
+	ExerciseID string     `json:"exerciseId"`
+	Weight     *float32   `json:"weight,omitempty"` // 入力した単位での重さ
+	WeightUnit WeightUnit `json:"weightUnit,omitempty"`
+	Reps       *int       `json:"reps,omitempty"`
+	RestSec    int        `json:"restSec"`
+	DueAt      time.Time  `json:"dueAt"`
+}
@@ -0,0 +1,58 @@
+package models
+
+// SetGroupType はセットのまとめ方
+type SetGroupType string
+
+const (
+	SetGroupStraight SetGroupType = "straight" // 通常のセット（グループなし）
+	SetGroupSuperset SetGroupType = "superset" // 2 種目を休まず交互に
+	SetGroupCircuit  SetGroupType = "circuit"  // 3 種目以上を順に回す
+	SetGroupDrop     SetGroupType = "drop"     // 重量を下げて続ける
+	SetGroupCluster  SetGroupType = "cluster"  // 短い休憩を挟んで 1 セットを分ける
+)
+
+func (t SetGroupType) Valid() bool {
+	switch t {
+	case SetGroupStraight, SetGroupSuperset, SetGroupCircuit, SetGroupDrop, SetGroupCluster:
+		return true
+	}
+	return false
+}
+
+// Merged はグループ全体で 1 セットとして数える種類か（ドロップセット・クラスター）
+func (t SetGroupType) Merged() bool {
+	return t == SetGroupDrop || t == SetGroupCluster
+}
+
+// SetGroup は一緒に行ったセットのまとまり。グループに入っていないセットは 1 件だけの straight
+type SetGroup struct {
+	ID   string       `json:"id,omitempty"` // straight なら空
+	Type SetGroupType `json:"type"`
+	Sets []WorkoutSet `json:"sets"` // GroupOrder 順
+}
+
+// GroupSets は set_index 順のセットをグループにまとめる。
+// グループはその最初のセットの位置に並べる
+func GroupSets(sets []WorkoutSet) []SetGroup {
+	groups := []SetGroup{}
+	index := map[string]int{} // GroupID → groups の位置
+	for _, s := range sets {
+		if s.GroupID == nil {
+			groups = append(groups, SetGroup{Type: SetGroupStraight, Sets: []WorkoutSet{s}})
+			continue
+		}
+		if i, ok := index[*s.GroupID]; ok {
+			g := &groups[i]
+			// GroupOrder 順に差し込む（同じなら set_index 順のまま）
+			pos := len(g.Sets)
+			for pos > 0 && g.Sets[pos-1].GroupOrder > s.GroupOrder {
+				pos--
+			}
+			g.Sets = append(g.Sets[:pos], append([]WorkoutSet{s}, g.Sets[pos:]...)...)
+			continue
+		}
+		index[*s.GroupID] = len(groups)
+		groups = append(groups, SetGroup{ID: *s.GroupID, Type: s.GroupType, Sets: []WorkoutSet{s}})
+	}
+	return groups
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// PeriodSummary は期間内（From <= startedAt < To）のワークアウトの集計
+type PeriodSummary struct {
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Workouts  int               `json:"workouts"`
+	Days      int               `json:"days"`     // トレーニングした日数
+	Sets      int               `json:"sets"`     // ドロップセット・クラスターはグループで 1 セット
+	VolumeKg  float64           `json:"volumeKg"` // 重量 × 回数 の合計（ウォームアップ除く）
+	Duration  time.Duration     `json:"duration"`
+	Exercises []ExerciseSummary `json:"exercises"` // 初めて行った順
+}
+
+type ExerciseSummary struct {
+	ExerciseID string       `json:"exerciseId"`
+	Name       string       `json:"name"`
+	Sets       []SetSummary `json:"sets"` // 同じ内容が続くセットはまとめる
+	VolumeKg   float64      `json:"volumeKg"`
+}
+
+type SetSummary struct {
+	Reps     *int     `json:"reps,omitempty"`
+	WeightKg *float32 `json:"weightKg,omitempty"`
+	Count    int      `json:"count"`
+}
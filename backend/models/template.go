@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// WorkoutTemplate は繰り返し行うメニュー（Push / Pull / Legs など）
+type WorkoutTemplate struct {
+	ID        string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID    string    `gorm:"type:uuid;index;not null"                       json:"userId"`
+	Name      string    `gorm:"size:64;not null"                               json:"name"`
+	Note      *string   `gorm:"type:text"                                      json:"note,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	Exercises []TemplateExercise `gorm:"foreignKey:TemplateID" json:"exercises"`
+}
+
+// TemplateExercise はテンプレートの 1 種目と目標
+type TemplateExercise struct {
+	ID         string `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TemplateID string `gorm:"type:uuid;index;not null"                       json:"templateId"`
+	ExerciseID string `gorm:"type:uuid;not null"                             json:"exerciseId"`
+	Position   int    `gorm:"not null"                                       json:"position"` // 0 始まりの並び順
+
+	TargetSets     int      `gorm:"not null;default:1" json:"targetSets"`
+	TargetReps     *int     `json:"targetReps,omitempty"`
+	TargetWeightKg *float32 `json:"targetWeightKg,omitempty"`
+	TargetRPE      *float32 `json:"targetRpe,omitempty"`
+	RestSec        *int     `json:"restSec,omitempty"`
+	Note           *string  `gorm:"type:text" json:"note,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type TemplateExerciseInput struct {
+	ExerciseID     string   `json:"exerciseId"`
+	TargetSets     int      `json:"targetSets"`
+	TargetReps     *int     `json:"targetReps,omitempty"`
+	TargetWeightKg *float32 `json:"targetWeightKg,omitempty"`
+	TargetRPE      *float32 `json:"targetRpe,omitempty"`
+	RestSec        *int     `json:"restSec,omitempty"`
+	Note           *string  `json:"note,omitempty"`
+}
+
+type CreateTemplateInput struct {
+	Name      string                  `json:"name"`
+	Note      *string                 `json:"note,omitempty"`
+	Exercises []TemplateExerciseInput `json:"exercises"` // 並び順どおり
+}
+
+// UpdateTemplateInput は Exercises を渡したときだけ種目を丸ごと置き換える
+type UpdateTemplateInput struct {
+	Name      *string                  `json:"name,omitempty"`
+	Note      *string                  `json:"note,omitempty"`
+	Exercises *[]TemplateExerciseInput `json:"exercises,omitempty"`
+}
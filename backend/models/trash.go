@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// TrashType はゴミ箱に入るものの種類（POST /api/trash/:type/:id/restore の :type）
+type TrashType string
+
+const (
+	TrashWorkout    TrashType = "workout"     // ワークアウト（セットごと）
+	TrashSet        TrashType = "set"         // 1 件ずつ消したセット
+	TrashExercise   TrashType = "exercise"    // 独自種目（セットで使っていないもの）
+	TrashBodyMetric TrashType = "body_metric" // 体重記録
+)
+
+func (t TrashType) Valid() bool {
+	switch t {
+	case TrashWorkout, TrashSet, TrashExercise, TrashBodyMetric:
+		return true
+	}
+	return false
+}
+
+// TrashRetention はゴミ箱に残す期間。過ぎたものはスケジューラーが完全に消す
+const TrashRetention = 30 * 24 * time.Hour
+
+// TrashItem はゴミ箱の 1 件。Type に応じて Workout / Set / Exercise / BodyMetric のどれかが入る
+type TrashItem struct {
+	Type      TrashType `json:"type"`
+	ID        string    `json:"id"`
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt"` // この時刻を過ぎると戻せない
+
+	Workout    *Workout    `json:"workout,omitempty"`
+	Set        *WorkoutSet `json:"set,omitempty"`
+	Exercise   *Exercise   `json:"exercise,omitempty"`
+	BodyMetric *BodyMetric `json:"bodyMetric,omitempty"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// 設定が無いユーザーの既定値
+const (
+	DefaultTimezone   = "Asia/Tokyo"
+	DefaultLocale     = "ja-JP"
+	DefaultWeekStart  = int(time.Monday)
+	DefaultWeightUnit = WeightUnitKg
+)
+
+// SupportedLocales は選べる表示言語
+var SupportedLocales = []string{"ja-JP", "en-US"}
+
+// UserSettings は日付の区切りと表示の設定（ユーザーごとに 1 件。無ければ既定値）
+type UserSettings struct {
+	ID     string `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"-"`
+	UserID string `gorm:"type:uuid;uniqueIndex;not null"                 json:"userId"`
+	// IANA のタイムゾーン名。「今日」「今週」やリマインドの時刻はこれで判定する
+	Timezone string `gorm:"size:64;not null;default:'Asia/Tokyo'" json:"timezone"`
+	Locale   string `gorm:"size:16;not null;default:'ja-JP'"      json:"locale"`
+	// 週の始まり（0 = 日曜, 1 = 月曜）
+	WeekStart int `gorm:"not null;default:1" json:"weekStart"`
+	// 表示と入力の既定の単位
+	WeightUnit WeightUnit `gorm:"size:2;not null;default:'kg'" json:"weightUnit"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func DefaultUserSettings(userID string) *UserSettings {
+	return &UserSettings{
+		UserID:     userID,
+		Timezone:   DefaultTimezone,
+		Locale:     DefaultLocale,
+		WeekStart:  DefaultWeekStart,
+		WeightUnit: DefaultWeightUnit,
+	}
+}
+
+func (s *UserSettings) Location() *time.Location {
+	return LoadLocation(s.Timezone)
+}
+
+func (s *UserSettings) WeekStartDay() time.Weekday {
+	return time.Weekday(s.WeekStart)
+}
+
+// LoadLocation は IANA 名の Location。読めなければ既定のタイムゾーン
+func LoadLocation(name string) *time.Location {
+	if loc, err := time.LoadLocation(name); err == nil && name != "" {
+		return loc
+	}
+	loc, err := time.LoadLocation(DefaultTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,46 @@
+package models
+
+import "math"
+
+// WeightUnit は重さの単位。保存と集計は kg に揃え、入力された値と単位は別に残す
+type WeightUnit string
+
+const (
+	WeightUnitKg WeightUnit = "kg"
+	WeightUnitLb WeightUnit = "lb"
+)
+
+// KgPerLb は 1 lb の kg（国際ポンド）
+const KgPerLb = 0.45359237
+
+func (u WeightUnit) Valid() bool {
+	return u == WeightUnitKg || u == WeightUnitLb
+}
+
+// ToKg は unit での値を kg にする
+func ToKg(v float64, unit WeightUnit) float64 {
+	if unit == WeightUnitLb {
+		return v * KgPerLb
+	}
+	return v
+}
+
+// FromKg は kg を unit での値にする（kg は 0.01、lb は 0.1 単位に丸める）
+func FromKg(kg float64, unit WeightUnit) float64 {
+	if unit == WeightUnitLb {
+		return math.Round(kg/KgPerLb*10) / 10
+	}
+	return math.Round(kg*100) / 100
+}
+
+// weightIn は unit での重さ。入力した単位と同じなら入力した値をそのまま返す（保存・編集を繰り返してもずれない）
+func weightIn(kg float32, value *float32, entered, unit WeightUnit) float32 {
+	if value != nil && entered == unit {
+		return *value
+	}
+	if value == nil && unit == WeightUnitKg {
+		// 単位を残す前のデータは kg で入力されたもの
+		return kg
+	}
+	return float32(FromKg(float64(kg), unit))
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Workout struct {
 	ID        string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
@@ -11,11 +15,22 @@ type Workout struct {
 	IsFromLine bool      `gorm:"not null;default:false"`
 	CreatedAt time.Time  `json:"createdAt"`
 	UpdatedAt time.Time  `json:"updatedAt"`
+	// ゴミ箱に入れた時刻（一緒に消したセットも同じ時刻になる）
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	// 更新のたびに 1 上がる（ETag / If-Match に使う）
+	Version int `gorm:"not null;default:1" json:"version"`
 
 	// 便利に preload したいとき用（必要になったら）
 	Sets []WorkoutSet `gorm:"foreignKey:WorkoutID" json:"-"`
 }
 
+// AbandonedWorkout は自動終了の候補（LINE で開始して終了されていないもの）
+type AbandonedWorkout struct {
+	Workout
+	LineUserID string
+	LastSetAt  *time.Time // セットがなければ nil
+}
+
 type CreateWorkoutInput struct {
 	StartedAt time.Time `json:"startedAt"`      // 必須（RFC3339）
 	Note      *string   `json:"note,omitempty"` // 任意
@@ -26,3 +41,23 @@ type UpdateWorkoutInput struct {
 	EndedAt   *time.Time `json:"endedAt,omitempty"`
 	Note      *string    `json:"note,omitempty"`
 }
+
+// Progression は前回のワークアウトを複製するときの進め方
+type Progression string
+
+const (
+	ProgressionNone   Progression = ""       // 前回と同じ
+	ProgressionWeight Progression = "weight" // 重量を上げる
+	ProgressionReps   Progression = "reps"   // 回数を増やす
+)
+
+type CloneWorkoutInput struct {
+	StartedAt   *time.Time  `json:"startedAt,omitempty"` // 省略時は現在時刻
+	Progression Progression `json:"progression,omitempty"`
+	// weight のとき 1 セットあたり上げる重量（既定 2.5kg）
+	WeightStepKg *float32 `json:"weightStepKg,omitempty"`
+	// reps のとき増やす回数（既定 1）
+	RepStep *int `json:"repStep,omitempty"`
+	// この回数に届いたセットだけ進める（省略時は前回の回数をこなせたものとみなして全セット）
+	TargetReps *int `json:"targetReps,omitempty"`
+}
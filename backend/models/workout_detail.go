@@ -3,4 +3,18 @@ package models
 type WorkoutDetail struct {
 	Workout Workout      `json:"workout"`
 	Sets    []WorkoutSet `json:"sets"`
+	// Sets をスーパーセット・ドロップセットなどのグループごとにまとめたもの
+	Groups []SetGroup `json:"groups"`
+}
+
+func NewWorkoutDetail(w Workout, sets []WorkoutSet) *WorkoutDetail {
+	return &WorkoutDetail{Workout: w, Sets: sets, Groups: GroupSets(sets)}
+}
+
+// Localize はセットの DisplayWeight / DisplayUnit を unit で入れる（Groups も作り直す）
+func (d *WorkoutDetail) Localize(unit WeightUnit) {
+	for i := range d.Sets {
+		d.Sets[i].Localize(unit)
+	}
+	d.Groups = GroupSets(d.Sets)
 }
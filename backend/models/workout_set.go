@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type WorkoutSet struct {
 	ID         string `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
@@ -12,6 +16,9 @@ type WorkoutSet struct {
 	Reps     *int     `json:"reps,omitempty"`
 	WeightKg *float32 `json:"weightKg,omitempty"`
 	RPE      *float32 `json:"rpe,omitempty"` // 0〜10 をアプリ側でバリデーション
+	// 入力された重さと単位（WeightKg はこれを kg にした値。集計・記録は WeightKg を使う）
+	WeightValue *float32   `json:"weightValue,omitempty"`
+	WeightUnit  WeightUnit `gorm:"size:2" json:"weightUnit,omitempty"`
 
 	// 有酸素向け
 	DurationSec *int     `json:"durationSec,omitempty"` // 秒
@@ -21,33 +28,110 @@ type WorkoutSet struct {
 	RestSec  *int    `json:"restSec,omitempty"`
 	IsWarmup bool    `gorm:"not null;default:false" json:"isWarmup"`
 	Note     *string `gorm:"type:text"              json:"note,omitempty"`
+	// テンプレートから作った未実施のセット（値は目標）。記録すると false になる
+	IsPlanned bool `gorm:"not null;default:false" json:"isPlanned"`
+	// まとめて行ったセット（スーパーセット・ドロップセットなど）。straight ならグループなし。
+	// GroupID はグループを作ったセット（最初のセット）の ID、GroupOrder はグループ内の順番（1 始まり）
+	GroupType  SetGroupType `gorm:"size:16;not null;default:'straight'" json:"groupType"`
+	GroupID    *string      `gorm:"type:uuid;index"                    json:"groupId,omitempty"`
+	GroupOrder int          `gorm:"not null;default:0"                 json:"groupOrder,omitempty"`
 
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 	IsFromLine bool      `gorm:"not null;default:false" json:"isFromLine"`
+	// ゴミ箱に入れた時刻
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	// 値を変えるたびに 1 上がる（ETag / If-Match に使う）。並び（set_index・グループ内の順番）だけの変更では上がらない
+	Version int `gorm:"not null;default:1" json:"version"`
+
+	// このセットで更新した自己ベスト（保存・更新時のみ）
+	Records []PersonalRecord `gorm:"-" json:"records,omitempty"`
+	// ユーザー設定の単位での重さ（API の返却時に Localize で入れる）
+	DisplayWeight *float32   `gorm:"-" json:"displayWeight,omitempty"`
+	DisplayUnit   WeightUnit `gorm:"-" json:"displayUnit,omitempty"`
+}
+
+// SetWeight は入力された重さと単位を入れ、WeightKg も合わせる。v が nil なら重さなし
+func (s *WorkoutSet) SetWeight(v *float32, unit WeightUnit) {
+	if v == nil {
+		s.WeightKg, s.WeightValue, s.WeightUnit = nil, nil, ""
+		return
+	}
+	value := *v
+	kg := float32(ToKg(float64(value), unit))
+	s.WeightKg, s.WeightValue, s.WeightUnit = &kg, &value, unit
+}
+
+// EnteredWeight は入力された重さと単位（単位を残す前のデータは kg）
+func (s *WorkoutSet) EnteredWeight() (*float32, WeightUnit) {
+	if s.WeightValue != nil && s.WeightUnit.Valid() {
+		return s.WeightValue, s.WeightUnit
+	}
+	return s.WeightKg, WeightUnitKg
+}
+
+// WeightIn は unit での重さ。重さが無ければ nil
+func (s *WorkoutSet) WeightIn(unit WeightUnit) *float32 {
+	if s.WeightKg == nil {
+		return nil
+	}
+	v := weightIn(*s.WeightKg, s.WeightValue, s.WeightUnit, unit)
+	return &v
+}
+
+// Localize は DisplayWeight / DisplayUnit を unit で入れる
+func (s *WorkoutSet) Localize(unit WeightUnit) {
+	s.DisplayWeight, s.DisplayUnit = s.WeightIn(unit), unit
+}
+
+// CountsAsSet は 1 セットとして数えるか。ドロップセット・クラスターはグループの最初のセットだけ数え、
+// 回数の自己ベスト（reps_at_weight / e1rm）もそのセットで見る（続きのセットは疲労や小休止の影響を受けるため）
+func (s *WorkoutSet) CountsAsSet() bool {
+	return s.GroupID == nil || !s.GroupType.Merged() || s.GroupOrder <= 1
 }
 
 type WorkoutSetCreateInput struct {
-	ExerciseID  string   `json:"exerciseId"  validate:"required,uuid4"`
-	SetIndex    int      `json:"setIndex"` // 0なら repoで自動採番でもOK
-	Reps        *int     `json:"reps,omitempty"`
-	WeightKg    *float32 `json:"weightKg,omitempty"`
-	RPE         *float32 `json:"rpe,omitempty"`
-	IsWarmup    bool     `json:"isWarmup"`
-	RestSec     *int     `json:"restSec,omitempty"`
-	Note        *string  `json:"note,omitempty"`
-	DurationSec *int     `json:"durationSec,omitempty"`
-	DistanceM   *float32 `json:"distanceM,omitempty"`
+	ExerciseID  string       `json:"exerciseId"  validate:"required,uuid4"`
+	SetIndex    int          `json:"setIndex"` // 0 ならワークアウトの最後。それ以外はその位置に差し込む
+	Reps        *int         `json:"reps,omitempty"`
+	WeightKg    *float32     `json:"weightKg,omitempty"`
+	Weight      *float32     `json:"weight,omitempty"`     // weightUnit での重さ。weightKg より優先
+	WeightUnit  WeightUnit   `json:"weightUnit,omitempty"` // 省略時はユーザー設定の単位
+	RPE         *float32     `json:"rpe,omitempty"`
+	IsWarmup    bool         `json:"isWarmup"`
+	IsPlanned   bool         `json:"isPlanned"` // 予定のセットとして追加する（値は目標）
+	RestSec     *int         `json:"restSec,omitempty"`
+	Note        *string      `json:"note,omitempty"`
+	DurationSec *int         `json:"durationSec,omitempty"`
+	DistanceM   *float32     `json:"distanceM,omitempty"`
+	GroupType   SetGroupType `json:"groupType,omitempty"`  // 省略時は straight。groupId が無ければこのセットから新しいグループを作る
+	GroupID     *string      `json:"groupId,omitempty"`    // 既存のグループに入れる（種類はグループに合わせる）
+	GroupOrder  int          `json:"groupOrder,omitempty"` // グループ内の順番。0 ならグループの最後
 }
 
 type WorkoutSetUpdateInput struct {
-	SetIndex    *int     `json:"setIndex,omitempty"`
-	Reps        *int     `json:"reps,omitempty"`
-	WeightKg    *float32 `json:"weightKg,omitempty"`
-	RPE         *float32 `json:"rpe,omitempty"`
-	IsWarmup    *bool    `json:"isWarmup,omitempty"`
-	RestSec     *int     `json:"restSec,omitempty"`
-	Note        *string  `json:"note,omitempty"`
-	DurationSec *int     `json:"durationSec,omitempty"`
-	DistanceM   *float32 `json:"distanceM,omitempty"`
+	SetIndex    *int          `json:"setIndex,omitempty"` // その位置へ動かす（間のセットはずれる）
+	Reps        *int          `json:"reps,omitempty"`
+	WeightKg    *float32      `json:"weightKg,omitempty"`
+	Weight      *float32      `json:"weight,omitempty"`     // weightUnit での重さ。weightKg より優先
+	WeightUnit  WeightUnit    `json:"weightUnit,omitempty"` // 省略時はユーザー設定の単位
+	RPE         *float32      `json:"rpe,omitempty"`
+	IsWarmup    *bool         `json:"isWarmup,omitempty"`
+	RestSec     *int          `json:"restSec,omitempty"`
+	Note        *string       `json:"note,omitempty"`
+	DurationSec *int          `json:"durationSec,omitempty"`
+	DistanceM   *float32      `json:"distanceM,omitempty"`
+	GroupType   *SetGroupType `json:"groupType,omitempty"` // straight でグループから外す。groupId が無ければ新しいグループを作る
+	GroupID     *string       `json:"groupId,omitempty"`
+	GroupOrder  *int          `json:"groupOrder,omitempty"`
+}
+
+// SetOrderInput は PUT /api/workouts/:id/sets/order の本文。ワークアウトの全セットを新しい順に並べる
+type SetOrderInput struct {
+	Sets []SetOrderItem `json:"sets"`
+}
+
+type SetOrderItem struct {
+	ID         string  `json:"id"`
+	ExerciseID *string `json:"exerciseId,omitempty"` // 別の種目に移すとき
 }
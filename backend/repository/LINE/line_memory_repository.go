@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// memoryLineRepository は Redis を使わない LineRepository（Webhook の重複排除の確認・ローカル用）。
+// 値の保存形式と、キーが無いときに redis.Nil を返す点は lineRepository に合わせている。
+type memoryLineRepository struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+	now   func() time.Time
+}
+
+type memoryItem struct {
+	val       string
+	expiresAt time.Time // ゼロ値なら期限なし
+}
+
+func NewMemoryLineRepository() LineRepository {
+	return &memoryLineRepository{items: map[string]memoryItem{}, now: time.Now}
+}
+
+func (r *memoryLineRepository) Get(ctx context.Context, key string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	it, ok := r.lookup(key)
+	if !ok {
+		return "", redis.Nil
+	}
+	return it.val, nil
+}
+
+func (r *memoryLineRepository) SetEX(ctx context.Context, key string, val any, ttl time.Duration) error {
+	b, _ := json.Marshal(val)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[key] = r.item(b, ttl)
+	return nil
+}
+
+func (r *memoryLineRepository) Del(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.items, key)
+	return nil
+}
+
+func (r *memoryLineRepository) SetNX(ctx context.Context, key string, val any, ttl time.Duration) (bool, error) {
+	b, _ := json.Marshal(val)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.lookup(key); ok {
+		return false, nil
+	}
+	r.items[key] = r.item(b, ttl)
+	return true, nil
+}
+
+// lookup は期限切れのキーを消してから探す（呼び出し側でロック済み）
+func (r *memoryLineRepository) lookup(key string) (memoryItem, bool) {
+	it, ok := r.items[key]
+	if ok && !it.expiresAt.IsZero() && !r.now().Before(it.expiresAt) {
+		delete(r.items, key)
+		return memoryItem{}, false
+	}
+	return it, ok
+}
+
+func (r *memoryLineRepository) item(b []byte, ttl time.Duration) memoryItem {
+	it := memoryItem{val: string(b)}
+	if ttl > 0 {
+		it.expiresAt = r.now().Add(ttl)
+	}
+	return it
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// LINE Webhook イベントのキュー（Redis Streams）
+
+const (
+	lineEventStream     = "line:events"
+	lineEventDeadStream = "line:events:dead"
+	lineEventGroup      = "line-workers"
+	// ストリームが無限に伸びないように古いものから捨てる（おおよその上限）
+	lineEventStreamMaxLen = 10000
+)
+
+type QueuedEvent struct {
+	ID       string // ストリームのメッセージID
+	UserID   string // 順序保証のキー（LINE の Source.UserID）
+	Payload  []byte // Webhook のイベント JSON そのまま
+	Attempts int64  // 今回を含めて何回目の処理か
+}
+
+// PendingEvent は読まれたまま ACK されていないメッセージ（処理中・失敗・落ちたワーカーのもの）
+type PendingEvent struct {
+	ID         string
+	Consumer   string
+	Idle       time.Duration // 最後に配られてからの時間
+	Deliveries int64         // これまでに配られた回数
+}
+
+type LineQueueRepository interface {
+	Enqueue(ctx context.Context, userID string, payload []byte) error
+	// コンシューマグループが無ければ作る（ストリームも同時に作成）
+	EnsureGroup(ctx context.Context) error
+	Read(ctx context.Context, consumer string, count int64, block time.Duration) ([]QueuedEvent, error)
+	// ACK されていないメッセージを古い順に count 件まで返す
+	Pending(ctx context.Context, count int64) ([]PendingEvent, error)
+	// pending を consumer に付け替えて読み直す。minIdle 未満のもの（直前に他で引き取られたもの）は除く
+	Claim(ctx context.Context, consumer string, minIdle time.Duration, pending []PendingEvent) ([]QueuedEvent, error)
+	Ack(ctx context.Context, id string) error
+	DeadLetter(ctx context.Context, ev QueuedEvent, reason string) error
+}
+
+type lineQueueRepository struct {
+	rd *redis.Client
+}
+
+func NewLineQueueRepository(rd *redis.Client) LineQueueRepository {
+	return &lineQueueRepository{rd: rd}
+}
+
+func (r *lineQueueRepository) Enqueue(ctx context.Context, userID string, payload []byte) error {
+	return r.rd.XAdd(&redis.XAddArgs{
+		Stream:       lineEventStream,
+		MaxLenApprox: lineEventStreamMaxLen,
+		Values: map[string]interface{}{
+			"user_id": userID,
+			"payload": string(payload),
+		},
+	}).Err()
+}
+
+func (r *lineQueueRepository) EnsureGroup(ctx context.Context) error {
+	err := r.rd.XGroupCreateMkStream(lineEventStream, lineEventGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+func (r *lineQueueRepository) Read(ctx context.Context, consumer string, count int64, block time.Duration) ([]QueuedEvent, error) {
+	streams, err := r.rd.XReadGroup(&redis.XReadGroupArgs{
+		Group:    lineEventGroup,
+		Consumer: consumer,
+		Streams:  []string{lineEventStream, ">"},
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []QueuedEvent
+	for _, st := range streams {
+		out = append(out, toQueuedEvents(st.Messages)...)
+	}
+	return out, nil
+}
+
+func (r *lineQueueRepository) Pending(ctx context.Context, count int64) ([]PendingEvent, error) {
+	pending, err := r.rd.XPendingExt(&redis.XPendingExtArgs{
+		Stream: lineEventStream,
+		Group:  lineEventGroup,
+		Start:  "-",
+		End:    "+",
+		Count:  count,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]PendingEvent, 0, len(pending))
+	for _, p := range pending {
+		out = append(out, PendingEvent{ID: p.Id, Consumer: p.Consumer, Idle: p.Idle, Deliveries: p.RetryCount})
+	}
+	return out, nil
+}
+
+func (r *lineQueueRepository) Claim(ctx context.Context, consumer string, minIdle time.Duration, pending []PendingEvent) ([]QueuedEvent, error) {
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(pending))
+	deliveries := make(map[string]int64, len(pending))
+	for _, p := range pending {
+		ids = append(ids, p.ID)
+		deliveries[p.ID] = p.Deliveries
+	}
+	msgs, err := r.rd.XClaim(&redis.XClaimArgs{
+		Stream:   lineEventStream,
+		Group:    lineEventGroup,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := toQueuedEvents(msgs)
+	for i := range out {
+		// XCLAIM で配られた回数が 1 増える
+		out[i].Attempts = deliveries[out[i].ID] + 1
+	}
+	return out, nil
+}
+
+func (r *lineQueueRepository) Ack(ctx context.Context, id string) error {
+	return r.rd.XAck(lineEventStream, lineEventGroup, id).Err()
+}
+
+func (r *lineQueueRepository) DeadLetter(ctx context.Context, ev QueuedEvent, reason string) error {
+	return r.rd.XAdd(&redis.XAddArgs{
+		Stream: lineEventDeadStream,
+		Values: map[string]interface{}{
+			"source_id": ev.ID,
+			"user_id":   ev.UserID,
+			"payload":   string(ev.Payload),
+			"reason":    reason,
+			"failed_at": time.Now().UTC().Format(time.RFC3339),
+		},
+	}).Err()
+}
+
+func toQueuedEvents(msgs []redis.XMessage) []QueuedEvent {
+	out := make([]QueuedEvent, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, QueuedEvent{
+			ID:       m.ID,
+			UserID:   fmt.Sprint(m.Values["user_id"]),
+			Payload:  []byte(fmt.Sprint(m.Values["payload"])),
+			Attempts: 1,
+		})
+	}
+	return out
+}
@@ -12,6 +12,8 @@ type LineRepository interface {
 	Get(ctx context.Context, key string) (string, error)
 	SetEX(ctx context.Context, key string, val any, ttl time.Duration) error
 	Del(ctx context.Context, key string) error
+	// key が無いときだけ書き込む。書き込めたら true
+	SetNX(ctx context.Context, key string, val any, ttl time.Duration) (bool, error)
 }
 
 type lineRepository struct {
@@ -41,4 +43,12 @@ func (r *lineRepository) Del(ctx context.Context, key string) error {
 	return err
   }
   return nil
-}
\ No newline at end of file
+}
+func (r *lineRepository) SetNX(ctx context.Context, key string, val any, ttl time.Duration) (bool, error) {
+  b, _ := json.Marshal(val)
+  ok, err := r.rd.SetNX(key, b, ttl).Result()
+  if err != nil{
+	return false, err
+  }
+  return ok, nil
+}
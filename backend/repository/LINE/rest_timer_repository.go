@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+// LINE の休憩タイマー。終わる時刻をスコアにした sorted set（メンバーは LINE のユーザーID）と、
+// 中身の hash に置く。API やスケジューラーを再起動しても残る
+
+const (
+	restTimerQueue    = "line:rest_timers"
+	restTimerPayloads = "line:rest_timers:payload"
+)
+
+// claimDueRestTimers は終わったタイマーを取り出して消す。
+// 取り出しと削除を 1 つのスクリプトで行うので、複数プロセスで回しても 1 回だけ送られる
+var claimDueRestTimers = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+local out = {}
+for _, m in ipairs(due) do
+	redis.call('ZREM', KEYS[1], m)
+	local p = redis.call('HGET', KEYS[2], m)
+	redis.call('HDEL', KEYS[2], m)
+	if p then
+		table.insert(out, p)
+	end
+end
+return out
+`)
+
+type RestTimerRepository interface {
+	// 同じユーザーのタイマーがあれば置き換える
+	Schedule(ctx context.Context, t models.RestTimer) error
+	// 動いていたタイマーを止めたら true
+	Cancel(ctx context.Context, lineUserID string) (bool, error)
+	// now までに終わったタイマーを最大 limit 件取り出す（取り出したものは消える）
+	ClaimDue(ctx context.Context, now time.Time, limit int) ([]models.RestTimer, error)
+}
+
+type restTimerRepository struct {
+	rd *redis.Client
+}
+
+func NewRestTimerRepository(rd *redis.Client) RestTimerRepository {
+	return &restTimerRepository{rd: rd}
+}
+
+func (r *restTimerRepository) Schedule(ctx context.Context, t models.RestTimer) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	pipe := r.rd.TxPipeline()
+	pipe.HSet(restTimerPayloads, t.LineUserID, b)
+	pipe.ZAdd(restTimerQueue, redis.Z{Score: float64(t.DueAt.Unix()), Member: t.LineUserID})
+	_, err = pipe.Exec()
+	return err
+}
+
+func (r *restTimerRepository) Cancel(ctx context.Context, lineUserID string) (bool, error) {
+	pipe := r.rd.TxPipeline()
+	removed := pipe.ZRem(restTimerQueue, lineUserID)
+	pipe.HDel(restTimerPayloads, lineUserID)
+	if _, err := pipe.Exec(); err != nil {
+		return false, err
+	}
+	return removed.Val() > 0, nil
+}
+
+func (r *restTimerRepository) ClaimDue(ctx context.Context, now time.Time, limit int) ([]models.RestTimer, error) {
+	res, err := claimDueRestTimers.Run(r.rd, []string{restTimerQueue, restTimerPayloads},
+		strconv.FormatInt(now.Unix(), 10), limit).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	items, _ := res.([]interface{})
+	out := make([]models.RestTimer, 0, len(items))
+	for _, it := range items {
+		s, ok := it.(string)
+		if !ok {
+			continue
+		}
+		var t models.RestTimer
+		if err := json.Unmarshal([]byte(s), &t); err != nil {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
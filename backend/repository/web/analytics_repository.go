@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+// グラフ用の集計。セットを Go に読み込まず SQL で集計する
+type AnalyticsRepository interface {
+	// ExerciseProgress はワークアウトごとの推定 1RM・トップセット・ボリューム（開始日時の古い順）
+	ExerciseProgress(ctx context.Context, userID, exerciseID string, q ProgressQuery) ([]models.ProgressPoint, error)
+	// MuscleVolume は [from, to) のワークアウトを tz（IANA 名）の週（weekStart 曜日始まり）・部位ごとに集計する。
+	// 部位が未設定の種目のセットは Muscle が nil の行にまとめる
+	MuscleVolume(ctx context.Context, userID, tz string, weekStart time.Weekday, from, to time.Time) ([]MuscleVolumeRow, error)
+}
+
+type ProgressQuery struct {
+	From    *time.Time // From <= started_at
+	To      *time.Time // started_at < To
+	Formula models.E1RMFormula
+}
+
+type MuscleVolumeRow struct {
+	WeekStart time.Time // tz での週の始まりの日（日付だけ）
+	Muscle    *string
+	HardSets  float64 // 寄与率をかけたセット数
+	TonnageKg float64 // 寄与率をかけた 重量 × 回数
+}
+
+type analyticsRepository struct {
+	db *gorm.DB
+}
+
+func NewAnalyticsRepository(db *gorm.DB) AnalyticsRepository {
+	return &analyticsRepository{db: db}
+}
+
+// countsAsSet はドロップセット・クラスターの続きのセットを除く条件（models.WorkoutSet.CountsAsSet と同じ）。
+// 挙上量には含めるが、セット数と推定 1RM には数えない
+const countsAsSet = "(ws.group_id IS NULL OR ws.group_type NOT IN ('drop', 'cluster') OR ws.group_order <= 1)"
+
+// e1rmExpr は w（重量）と r（RPE で補正した回数。models.E1RMReps）からの推定 1RM。
+// 自己ベストと同じ値になるよう models.E1RMFormula.Estimate と同じ式にする
+var e1rmExpr = map[models.E1RMFormula]string{
+	models.FormulaEpley:    "CASE WHEN r <= 1 THEN w ELSE w * (1 + r / 30.0) END",
+	models.FormulaBrzycki:  "CASE WHEN r <= 1 THEN w WHEN r >= 37 THEN NULL ELSE w * 36 / (37 - r) END",
+	models.FormulaLombardi: "CASE WHEN r <= 1 THEN w ELSE w * POWER(r, 0.10) END",
+}
+
+func (r *analyticsRepository) ExerciseProgress(ctx context.Context, userID, exerciseID string, q ProgressQuery) ([]models.ProgressPoint, error) {
+	expr, ok := e1rmExpr[q.Formula]
+	if !ok {
+		return nil, fmt.Errorf("unknown formula: %s", q.Formula)
+	}
+	where := []string{
+		"w.user_id = ?", "ws.exercise_id = ?",
+		"NOT ws.is_warmup", "NOT ws.is_planned", "ws.reps > 0", "ws.deleted_at IS NULL",
+	}
+	args := []any{userID, exerciseID}
+	if q.From != nil {
+		where = append(where, "w.started_at >= ?")
+		args = append(args, *q.From)
+	}
+	if q.To != nil {
+		where = append(where, "w.started_at < ?")
+		args = append(args, *q.To)
+	}
+
+	sql := `
+WITH s AS (
+	SELECT ws.workout_id, w.started_at, ws.set_index, ws.weight_kg, ws.reps, ws.rpe,
+		` + countsAsSet + ` AS counted,
+		NULLIF(ws.weight_kg, 0)::float8 AS w,
+		(ws.reps + COALESCE(GREATEST(10 - ws.rpe, 0), 0))::float8 AS r
+	FROM workout_sets ws
+	JOIN workouts w ON w.id = ws.workout_id
+	WHERE ` + strings.Join(where, " AND ") + `
+), ranked AS (
+	SELECT s.*, ` + expr + ` AS e1rm,
+		ROW_NUMBER() OVER (PARTITION BY workout_id ORDER BY weight_kg DESC NULLS LAST, reps DESC, set_index) AS rn
+	FROM s
+)
+SELECT workout_id,
+	MIN(started_at) AS started_at,
+	ROUND((MAX(e1rm) FILTER (WHERE counted AND reps <= ` + strconv.Itoa(models.MaxE1RMReps) + `))::numeric, 2)::float8 AS best_e1rm,
+	MAX(weight_kg) FILTER (WHERE rn = 1) AS top_weight_kg,
+	MAX(reps) FILTER (WHERE rn = 1) AS top_reps,
+	MAX(rpe) FILTER (WHERE rn = 1) AS top_rpe,
+	ROUND(COALESCE(SUM(weight_kg::float8 * reps), 0)::numeric, 2)::float8 AS volume_kg,
+	COUNT(*) FILTER (WHERE counted) AS sets,
+	SUM(reps) AS reps
+FROM ranked
+GROUP BY workout_id
+ORDER BY started_at ASC, workout_id ASC`
+
+	var points []models.ProgressPoint
+	if err := conn(ctx, r.db).Raw(sql, args...).Scan(&points).Error; err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+func (r *analyticsRepository) MuscleVolume(ctx context.Context, userID, tz string, weekStart time.Weekday, from, to time.Time) ([]MuscleVolumeRow, error) {
+	// date_trunc('week') は月曜始まりなので、週の始まりが月曜に来るようずらしてから切り捨てて戻す
+	shift := (int(time.Monday) - int(weekStart) + 7) % 7
+	var rows []MuscleVolumeRow
+	err := conn(ctx, r.db).Raw(`
+SELECT (date_trunc('week', (w.started_at AT TIME ZONE @tz) + make_interval(days => @shift)) - make_interval(days => @shift))::date AS week_start,
+	em.muscle,
+	SUM(CASE WHEN `+countsAsSet+` THEN COALESCE(em.contribution, 1) ELSE 0 END)::float8 AS hard_sets,
+	ROUND(SUM(COALESCE(ws.weight_kg, 0)::float8 * COALESCE(ws.reps, 0) * COALESCE(em.contribution, 1))::numeric, 2)::float8 AS tonnage_kg
+FROM workout_sets ws
+JOIN workouts w ON w.id = ws.workout_id
+LEFT JOIN exercise_muscles em ON em.exercise_id = ws.exercise_id
+WHERE w.user_id = @user AND w.started_at >= @from AND w.started_at < @to
+	AND NOT ws.is_warmup AND NOT ws.is_planned AND ws.deleted_at IS NULL AND w.deleted_at IS NULL
+GROUP BY 1, 2
+ORDER BY 1, 2`,
+		sql.Named("tz", tz), sql.Named("shift", shift), sql.Named("user", userID), sql.Named("from", from), sql.Named("to", to)).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
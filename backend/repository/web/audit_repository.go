@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+type AuditRepository interface {
+	// Create は変更履歴を追記する（呼び出し側のトランザクションに入る）
+	Create(ctx context.Context, logs []models.AuditLog) error
+	// ListByUser はユーザーのデータの変更履歴（新しい順）
+	ListByUser(ctx context.Context, userID string, f AuditListFilter) ([]models.AuditLog, int64, error)
+}
+
+type AuditListFilter struct {
+	WorkoutID  *string // ワークアウトとそのセットだけ
+	EntityType *models.AuditEntity
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Create(ctx context.Context, logs []models.AuditLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	return conn(ctx, r.db).Create(&logs).Error
+}
+
+func (r *auditRepository) ListByUser(ctx context.Context, userID string, f AuditListFilter) ([]models.AuditLog, int64, error) {
+	q := conn(ctx, r.db).Model(&models.AuditLog{}).Where("user_id = ?", userID)
+	if f.WorkoutID != nil {
+		q = q.Where("workout_id = ?", *f.WorkoutID)
+	}
+	if f.EntityType != nil {
+		q = q.Where("entity_type = ?", *f.EntityType)
+	}
+	if f.From != nil {
+		q = q.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("created_at < ?", *f.To)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var items []models.AuditLog
+	if err := q.Order("created_at DESC, id DESC").
+		Limit(f.Limit).
+		Offset(f.Offset).
+		Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
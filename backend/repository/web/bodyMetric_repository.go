@@ -12,8 +12,14 @@ import (
 type BodyMetricRepository interface {
 	ListByUser(ctx context.Context, userID string, f BodyMetricListFilter) ([]models.BodyMetric, int64, error)
 	Create(ctx context.Context, m *models.BodyMetric) error
-	UpdateOwned(ctx context.Context, userID, id string, upd UpdateBodyMetricFields) (*models.BodyMetric, error)
-	DeleteOwned(ctx context.Context, userID, id string) error
+	// 無ければ gorm.ErrRecordNotFound
+	FindOwned(ctx context.Context, userID, id string) (*models.BodyMetric, error)
+	// version が変わっていなければ更新（変わっていれば ErrVersionConflict）
+	UpdateOwned(ctx context.Context, userID, id string, version int, upd UpdateBodyMetricFields) (*models.BodyMetric, error)
+	// version が変わっていなければゴミ箱に入れる
+	DeleteOwned(ctx context.Context, userID, id string, version int) error
+	// ゴミ箱から戻す（ゴミ箱に無ければ gorm.ErrRecordNotFound）
+	RestoreOwned(ctx context.Context, userID, id string) (*models.BodyMetric, error)
 }
 
 type BodyMetricListFilter struct {
@@ -24,10 +30,12 @@ type BodyMetricListFilter struct {
 }
 
 type UpdateBodyMetricFields struct {
-	MeasuredAt *time.Time
-	WeightKg   *float32
-	BodyFatPct *float32 // nil を渡すと NULL に更新したい場合は「ptrは非nilで値は0」を渡す or 別制御でもOK
-	Note       *string  // nil を渡すと NULL に
+	MeasuredAt  *time.Time
+	WeightKg    *float32
+	WeightValue *float32 // 入力された体重と単位（WeightKg と一緒に変える）
+	WeightUnit  *models.WeightUnit
+	BodyFatPct  *float32 // nil を渡すと NULL に更新したい場合は「ptrは非nilで値は0」を渡す or 別制御でもOK
+	Note        *string  // nil を渡すと NULL に
 }
 
 type bodyMetricRepository struct {
@@ -39,7 +47,7 @@ func NewBodyMetricRepository(db *gorm.DB) BodyMetricRepository {
 }
 
 func (r *bodyMetricRepository) ListByUser(ctx context.Context, userID string, f BodyMetricListFilter) ([]models.BodyMetric, int64, error) {
-	q := r.db.WithContext(ctx).Model(&models.BodyMetric{}).Where("user_id = ?", userID)
+	q := conn(ctx, r.db).Model(&models.BodyMetric{}).Where("user_id = ?", userID)
 
 	if f.From != nil {
 		q = q.Where("measured_at >= ?", *f.From)
@@ -71,12 +79,21 @@ func (r *bodyMetricRepository) ListByUser(ctx context.Context, userID string, f
 }
 
 func (r *bodyMetricRepository) Create(ctx context.Context, m *models.BodyMetric) error {
-	return r.db.WithContext(ctx).Create(m).Error
+	return conn(ctx, r.db).Create(m).Error
 }
 
-func (r *bodyMetricRepository) UpdateOwned(ctx context.Context, userID, id string, upd UpdateBodyMetricFields) (*models.BodyMetric, error) {
+func (r *bodyMetricRepository) FindOwned(ctx context.Context, userID, id string) (*models.BodyMetric, error) {
 	var bm models.BodyMetric
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
+		First(&bm, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		return nil, err
+	}
+	return &bm, nil
+}
+
+func (r *bodyMetricRepository) UpdateOwned(ctx context.Context, userID, id string, version int, upd UpdateBodyMetricFields) (*models.BodyMetric, error) {
+	var bm models.BodyMetric
+	if err := conn(ctx, r.db).
 		First(&bm, "id = ? AND user_id = ?", id, userID).Error; err != nil {
 		return nil, err
 	}
@@ -88,6 +105,12 @@ func (r *bodyMetricRepository) UpdateOwned(ctx context.Context, userID, id strin
 	if upd.WeightKg != nil {
 		data["weight_kg"] = *upd.WeightKg
 	}
+	if upd.WeightValue != nil {
+		data["weight_value"] = *upd.WeightValue
+	}
+	if upd.WeightUnit != nil {
+		data["weight_unit"] = *upd.WeightUnit
+	}
 	if upd.BodyFatPct != nil {
 		// 明示的にNULLにしたい場合は Note 同様、ptrを非nilにして値は0区別が必要。要件次第。
 		data["body_fat_pct"] = upd.BodyFatPct
@@ -99,14 +122,38 @@ func (r *bodyMetricRepository) UpdateOwned(ctx context.Context, userID, id strin
 	if len(data) == 0 {
 		return &bm, nil
 	}
-	if err := r.db.WithContext(ctx).Model(&bm).Updates(data).Error; err != nil {
-		return nil, err
+	data["version"] = gorm.Expr("version + 1")
+	res := conn(ctx, r.db).Model(&bm).Where("version = ?", version).Updates(data)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrVersionConflict
 	}
+	bm.Version = version + 1
 	return &bm, nil
 }
 
-func (r *bodyMetricRepository) DeleteOwned(ctx context.Context, userID, id string) error {
-	return r.db.WithContext(ctx).
-		Where("id = ? AND user_id = ?", id, userID).
-		Delete(&models.BodyMetric{}).Error
-}
\ No newline at end of file
+func (r *bodyMetricRepository) DeleteOwned(ctx context.Context, userID, id string, version int) error {
+	res := conn(ctx, r.db).
+		Where("id = ? AND user_id = ? AND version = ?", id, userID, version).
+		Delete(&models.BodyMetric{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+func (r *bodyMetricRepository) RestoreOwned(ctx context.Context, userID, id string) (*models.BodyMetric, error) {
+	var bm models.BodyMetric
+	if err := conn(ctx, r.db).Unscoped().
+		First(&bm, "id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).Error; err != nil {
+		return nil, err
+	}
+	if err := conn(ctx, r.db).Unscoped().Model(&bm).Update("deleted_at", nil).Error; err != nil {
+		return nil, err
+	}
+	return &bm, nil
+}
@@ -15,25 +15,40 @@ type ExerciseRepository interface {
 	GetByID(ctx context.Context, id string) (*models.Exercise, error)
 	Create(ctx context.Context, ex *models.Exercise) error
 	UpdateOwned(ctx context.Context, userID string, id string, upd UpdateExerciseFields) (*models.Exercise, error)
+	// ゴミ箱に入れる（自分の独自種目のみ。無ければ gorm.ErrRecordNotFound）
 	DeleteOwned(ctx context.Context, userID string, id string) error
-	baseVisibleQuery(userID string) *gorm.DB
+	// ゴミ箱から戻す（自分の独自種目のみ。ゴミ箱に無ければ gorm.ErrRecordNotFound）
+	RestoreOwned(ctx context.Context, userID string, id string) (*models.Exercise, error)
+	// セット（ゴミ箱のものも含む）かテンプレートで使っているか
+	InUse(ctx context.Context, id string) (bool, error)
+	FindVisibleByNames(ctx context.Context, userID string, names []string) ([]models.Exercise, error)
+	// ReplaceMuscles は種目の部位を muscles で置き換える
+	ReplaceMuscles(ctx context.Context, exerciseID string, muscles []models.ExerciseMuscle) error
+	baseVisibleQuery(ctx context.Context, userID string) *gorm.DB
 }
 
 type ListExercisesFilter struct {
-	Q        string
-	Type     *string
-	OnlyMine bool
-	Limit    int
-	Offset   int
+	Q          string
+	Type       *string
+	OnlyMine   bool
+	ActiveOnly bool
+	// true ならユーザーのよく使う種目 → 最近使った種目の順に並べる
+	OrderByUsage bool
+	Limit        int
+	Offset       int
 }
 
 type UpdateExerciseFields struct {
-	Name          *string
-	Type          *string
-	PrimaryMuscle *string
-	IsActive      *bool
+	Name           *string
+	Type           *string
+	PrimaryMuscle  *string
+	IsActive       *bool
+	DefaultRestSec *int // 0 なら NULL に戻す
 }
 
+// likeEscaper は LIKE のワイルドカードを文字どおりに扱うためのエスケープ（ESCAPE '\' と組で使う）
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 type exerciseRepository struct {
 	db *gorm.DB
 }
@@ -42,14 +57,14 @@ func NewExerciseRepository(db *gorm.DB) ExerciseRepository {
 	return &exerciseRepository{db: db}
 }
 
-func (r *exerciseRepository) baseVisibleQuery(userID string) *gorm.DB {
+func (r *exerciseRepository) baseVisibleQuery(ctx context.Context, userID string) *gorm.DB {
 	// 可視対象: グローバル（owner_user_id IS NULL）or 自分の独自種目
-	return r.db.Model(&models.Exercise{}).
+	return conn(ctx, r.db).Model(&models.Exercise{}).
 		Where("owner_user_id IS NULL OR owner_user_id = ?", userID)
 }
 
 func (r *exerciseRepository) List(ctx context.Context, userID string, f ListExercisesFilter) ([]models.Exercise, int64, error) {
-	q := r.db.WithContext(ctx).Model(&models.Exercise{})
+	q := conn(ctx, r.db).Model(&models.Exercise{})
 
 	if f.OnlyMine {
 		q = q.Where("owner_user_id = ?", userID)
@@ -58,11 +73,14 @@ func (r *exerciseRepository) List(ctx context.Context, userID string, f ListExer
 	}
 
 	if s := strings.TrimSpace(f.Q); s != "" {
-		q = q.Where("name ILIKE ?", "%"+s+"%")
+		q = q.Where(`name ILIKE ? ESCAPE '\'`, "%"+likeEscaper.Replace(s)+"%")
 	}
 	if f.Type != nil && *f.Type != "" {
 		q = q.Where("type = ?", *f.Type)
 	}
+	if f.ActiveOnly {
+		q = q.Where("is_active = ?", true)
+	}
 
 	var total int64
 	if err := q.Count(&total).Error; err != nil {
@@ -76,8 +94,20 @@ func (r *exerciseRepository) List(ctx context.Context, userID string, f ListExer
 		f.Offset = 0
 	}
 
+	order := "name ASC, id ASC"
+	if f.OrderByUsage {
+		usage := r.db.Table("workout_sets AS ws").
+			Select("ws.exercise_id, COUNT(*) AS use_count, MAX(ws.created_at) AS last_used_at").
+			Joins("JOIN workouts AS w ON w.id = ws.workout_id").
+			Where("w.user_id = ? AND ws.deleted_at IS NULL", userID).
+			Group("ws.exercise_id")
+		q = q.Select("exercises.*").
+			Joins("LEFT JOIN (?) AS u ON u.exercise_id = exercises.id", usage)
+		order = "COALESCE(u.use_count, 0) DESC, u.last_used_at DESC NULLS LAST, exercises.name ASC, exercises.id ASC"
+	}
+
 	var items []models.Exercise
-	if err := q.Order("name ASC, id ASC").
+	if err := q.Preload("Muscles").Order(order).
 		Limit(f.Limit).
 		Offset(f.Offset).
 		Find(&items).Error; err != nil {
@@ -88,7 +118,8 @@ func (r *exerciseRepository) List(ctx context.Context, userID string, f ListExer
 
 func (r *exerciseRepository) GetByID(ctx context.Context, id string) (*models.Exercise, error) {
 	var ex models.Exercise
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
+		Preload("Muscles").
 		First(&ex, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
@@ -96,13 +127,13 @@ func (r *exerciseRepository) GetByID(ctx context.Context, id string) (*models.Ex
 }
 
 func (r *exerciseRepository) Create(ctx context.Context, ex *models.Exercise) error {
-	return r.db.WithContext(ctx).Create(ex).Error
+	return conn(ctx, r.db).Create(ex).Error
 }
 
 func (r *exerciseRepository) UpdateOwned(ctx context.Context, userID string, id string, upd UpdateExerciseFields) (*models.Exercise, error) {
 	// 自分の独自種目のみ
 	var ex models.Exercise
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		First(&ex, "id = ? AND owner_user_id = ?", id, userID).Error; err != nil {
 		return nil, err
 	}
@@ -120,11 +151,18 @@ func (r *exerciseRepository) UpdateOwned(ctx context.Context, userID string, id
 	if upd.IsActive != nil {
 		data["is_active"] = *upd.IsActive
 	}
+	if upd.DefaultRestSec != nil {
+		if *upd.DefaultRestSec == 0 {
+			data["default_rest_sec"] = nil
+		} else {
+			data["default_rest_sec"] = *upd.DefaultRestSec
+		}
+	}
 	if len(data) == 0 {
 		return &ex, nil
 	}
 
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Model(&ex).Updates(data).Error; err != nil {
 		return nil, err
 	}
@@ -134,18 +172,80 @@ func (r *exerciseRepository) UpdateOwned(ctx context.Context, userID string, id
 
 func (r *exerciseRepository) DeleteOwned(ctx context.Context, userID string, id string) error {
 	// 自分の独自種目のみ削除可
-	return r.db.WithContext(ctx).
+	res := conn(ctx, r.db).
 		Where("id = ? AND owner_user_id = ?", id, userID).
-		Delete(&models.Exercise{}).Error
+		Delete(&models.Exercise{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *exerciseRepository) RestoreOwned(ctx context.Context, userID string, id string) (*models.Exercise, error) {
+	var ex models.Exercise
+	if err := conn(ctx, r.db).Unscoped().
+		First(&ex, "id = ? AND owner_user_id = ? AND deleted_at IS NOT NULL", id, userID).Error; err != nil {
+		return nil, err
+	}
+	if err := conn(ctx, r.db).Unscoped().Model(&ex).Update("deleted_at", nil).Error; err != nil {
+		return nil, err
+	}
+	return &ex, nil
+}
+
+func (r *exerciseRepository) InUse(ctx context.Context, id string) (bool, error) {
+	var used bool
+	err := conn(ctx, r.db).Raw(`SELECT EXISTS (SELECT 1 FROM workout_sets WHERE exercise_id = ?)
+	OR EXISTS (SELECT 1 FROM template_exercises WHERE exercise_id = ?)`, id, id).Scan(&used).Error
+	return used, err
 }
 
 func (r *exerciseRepository) FindByID(ctx context.Context, id string) (*models.Exercise, error) {
 	var ex models.Exercise
-	if err := r.db.WithContext(ctx).First(&ex, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).First(&ex, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
 		return nil, err
 	}
 	return &ex, nil
-}
\ No newline at end of file
+}
+
+// FindVisibleByNames は可視範囲の有効な種目から、名前に names のいずれかを含むものを返す（部分一致）
+func (r *exerciseRepository) FindVisibleByNames(ctx context.Context, userID string, names []string) ([]models.Exercise, error) {
+	cond := r.db.Where("1 = 0")
+	for _, n := range names {
+		if n = strings.TrimSpace(n); n != "" {
+			cond = cond.Or(`name ILIKE ? ESCAPE '\'`, "%"+likeEscaper.Replace(n)+"%")
+		}
+	}
+
+	var items []models.Exercise
+	if err := r.baseVisibleQuery(ctx, userID).
+		Where("is_active = ?", true).
+		Where(cond).
+		Order("name ASC, id ASC").
+		Limit(10).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *exerciseRepository) ReplaceMuscles(ctx context.Context, exerciseID string, muscles []models.ExerciseMuscle) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("exercise_id = ?", exerciseID).Delete(&models.ExerciseMuscle{}).Error; err != nil {
+			return err
+		}
+		if len(muscles) == 0 {
+			return nil
+		}
+		for i := range muscles {
+			muscles[i].ExerciseID = exerciseID
+		}
+		return tx.Create(&muscles).Error
+	})
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+type PersonalRecordRepository interface {
+	// LockExercise はトランザクションの終わりまで (userID, exerciseID) の作り直しを 1 つに絞る
+	LockExercise(ctx context.Context, userID, exerciseID string) error
+	ListByExercise(ctx context.Context, userID, exerciseID string) ([]models.PersonalRecord, error)
+	// ReplaceForExercise は種目の履歴を items で置き換える
+	ReplaceForExercise(ctx context.Context, userID, exerciseID string, items []models.PersonalRecord) error
+	// ListCurrent は種目・種類ごとの今の記録（reps_at_weight は重量ごと）
+	ListCurrent(ctx context.Context, userID string) ([]models.PersonalRecord, error)
+}
+
+type personalRecordRepository struct {
+	db *gorm.DB
+}
+
+func NewPersonalRecordRepository(db *gorm.DB) PersonalRecordRepository {
+	return &personalRecordRepository{db: db}
+}
+
+func (r *personalRecordRepository) LockExercise(ctx context.Context, userID, exerciseID string) error {
+	return conn(ctx, r.db).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "personal_records:"+userID+":"+exerciseID).Error
+}
+
+func (r *personalRecordRepository) ListByExercise(ctx context.Context, userID, exerciseID string) ([]models.PersonalRecord, error) {
+	var items []models.PersonalRecord
+	if err := conn(ctx, r.db).
+		Where("user_id = ? AND exercise_id = ?", userID, exerciseID).
+		Order("achieved_at DESC, value DESC").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *personalRecordRepository) ReplaceForExercise(ctx context.Context, userID, exerciseID string, items []models.PersonalRecord) error {
+	db := conn(ctx, r.db)
+	if err := db.Where("user_id = ? AND exercise_id = ?", userID, exerciseID).Delete(&models.PersonalRecord{}).Error; err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return db.Create(&items).Error
+}
+
+func (r *personalRecordRepository) ListCurrent(ctx context.Context, userID string) ([]models.PersonalRecord, error) {
+	var items []models.PersonalRecord
+	err := conn(ctx, r.db).Raw(`
+SELECT DISTINCT ON (exercise_id, kind, CASE WHEN kind = ? THEN weight_kg END) *
+FROM personal_records
+WHERE user_id = ?
+ORDER BY exercise_id, kind, CASE WHEN kind = ? THEN weight_kg END, value DESC, achieved_at DESC`,
+		models.RecordRepsAtWeight, userID, models.RecordRepsAtWeight).
+		Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
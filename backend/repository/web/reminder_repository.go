@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+type ReminderRepository interface {
+	// 無ければ nil, nil
+	FindByUser(ctx context.Context, userID string) (*models.ReminderPreference, error)
+	// user_id 単位で作成 or 更新
+	Upsert(ctx context.Context, p *models.ReminderPreference) error
+	DeleteByUser(ctx context.Context, userID string) error
+	UpdateFields(ctx context.Context, id string, values map[string]any) error
+
+	// スケジューラー用（LINE のユーザーID・最後のワークアウト日時・タイムゾーン付き）。
+	// 曜日と時刻はユーザーのタイムゾーンで now を見て判定する
+	ListDue(ctx context.Context, now time.Time) ([]models.ReminderTarget, error)
+	// ユーザーのタイムゾーンで at（"HH:MM"）になった人
+	ListInactiveCandidates(ctx context.Context, now time.Time, at string) ([]models.ReminderTarget, error)
+	// ユーザーのタイムゾーンで週の最後の日の at になった人
+	ListRecapTargets(ctx context.Context, now time.Time, at string) ([]models.ReminderTarget, error)
+}
+
+type reminderRepository struct {
+	db *gorm.DB
+}
+
+func NewReminderRepository(db *gorm.DB) ReminderRepository {
+	return &reminderRepository{db: db}
+}
+
+func (r *reminderRepository) FindByUser(ctx context.Context, userID string) (*models.ReminderPreference, error) {
+	var p models.ReminderPreference
+	if err := conn(ctx, r.db).First(&p, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+var reminderColumns = []string{"enabled", "weekdays", "remind_at", "inactive_days", "weekly_recap", "auto_close_hours", "snoozed_until", "updated_at"}
+
+func (r *reminderRepository) Upsert(ctx context.Context, p *models.ReminderPreference) error {
+	// default 付きの列も false / 0 をそのまま書くために列を明示する
+	return conn(ctx, r.db).
+		Select(append([]string{"user_id", "created_at"}, reminderColumns...)).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns(reminderColumns),
+		}).
+		Create(p).Error
+}
+
+func (r *reminderRepository) DeleteByUser(ctx context.Context, userID string) error {
+	return conn(ctx, r.db).
+		Where("user_id = ?", userID).
+		Delete(&models.ReminderPreference{}).Error
+}
+
+func (r *reminderRepository) UpdateFields(ctx context.Context, id string, values map[string]any) error {
+	return conn(ctx, r.db).
+		Model(&models.ReminderPreference{}).
+		Where("id = ?", id).
+		Updates(values).Error
+}
+
+const (
+	// user_settings が無ければ既定のタイムゾーン
+	userTimezone = "COALESCE(user_settings.timezone, '" + models.DefaultTimezone + "')"
+	// ユーザーのタイムゾーンでの now（? に now を渡す）
+	localNow = "(CAST(? AS timestamptz) AT TIME ZONE " + userTimezone + ")"
+)
+
+func (r *reminderRepository) ListDue(ctx context.Context, now time.Time) ([]models.ReminderTarget, error) {
+	var out []models.ReminderTarget
+	err := r.targets(ctx, now, true).
+		Where("reminder_preferences.weekdays & (1 << EXTRACT(DOW FROM "+localNow+")::int) <> 0", now).
+		Where("reminder_preferences.remind_at = to_char("+localNow+", 'HH24:MI')", now).
+		Find(&out).Error
+	return out, err
+}
+
+func (r *reminderRepository) ListInactiveCandidates(ctx context.Context, now time.Time, at string) ([]models.ReminderTarget, error) {
+	var out []models.ReminderTarget
+	err := r.targets(ctx, now, true).
+		Where("reminder_preferences.inactive_days > 0").
+		Where("to_char("+localNow+", 'HH24:MI') = ?", now, at).
+		Find(&out).Error
+	return out, err
+}
+
+func (r *reminderRepository) ListRecapTargets(ctx context.Context, now time.Time, at string) ([]models.ReminderTarget, error) {
+	var out []models.ReminderTarget
+	err := r.targets(ctx, now, false).
+		Where("reminder_preferences.weekly_recap").
+		Where("EXTRACT(DOW FROM "+localNow+")::int = (COALESCE(user_settings.week_start, ?) + 6) % 7", now, models.DefaultWeekStart).
+		Where("to_char("+localNow+", 'HH24:MI') = ?", now, at).
+		Find(&out).Error
+	return out, err
+}
+
+// targets は有効な設定に users.line_user_id・最後のワークアウト日時・タイムゾーンを付けたクエリ。
+// skipSnoozed ならスヌーズ中のものを除く。
+func (r *reminderRepository) targets(ctx context.Context, now time.Time, skipSnoozed bool) *gorm.DB {
+	q := conn(ctx, r.db).
+		Table("reminder_preferences").
+		Select(`reminder_preferences.*, users.line_user_id,
+			(SELECT MAX(w.started_at) FROM workouts w WHERE w.user_id = reminder_preferences.user_id AND w.deleted_at IS NULL) AS last_workout_at,
+			` + userTimezone + ` AS timezone`).
+		Joins("JOIN users ON users.id = reminder_preferences.user_id").
+		Joins("LEFT JOIN user_settings ON user_settings.user_id = reminder_preferences.user_id").
+		Where("reminder_preferences.enabled")
+	if skipSnoozed {
+		q = q.Where("(reminder_preferences.snoozed_until IS NULL OR reminder_preferences.snoozed_until <= ?)", now)
+	}
+	return q
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+type TemplateRepository interface {
+	ListByUser(ctx context.Context, userID string) ([]models.WorkoutTemplate, error)
+	// 無ければ nil, nil
+	FindByIDAndUser(ctx context.Context, id, userID string) (*models.WorkoutTemplate, error)
+	// t.Exercises もまとめて登録する
+	Create(ctx context.Context, t *models.WorkoutTemplate) error
+	// replaceExercises なら種目を t.Exercises で置き換える
+	Update(ctx context.Context, t *models.WorkoutTemplate, replaceExercises bool) error
+	Delete(ctx context.Context, id, userID string) error
+}
+
+type templateRepository struct {
+	db *gorm.DB
+}
+
+func NewTemplateRepository(db *gorm.DB) TemplateRepository {
+	return &templateRepository{db: db}
+}
+
+func preloadTemplateExercises(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+func (r *templateRepository) ListByUser(ctx context.Context, userID string) ([]models.WorkoutTemplate, error) {
+	var items []models.WorkoutTemplate
+	if err := conn(ctx, r.db).
+		Preload("Exercises", preloadTemplateExercises).
+		Where("user_id = ?", userID).
+		Order("name ASC, id ASC").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *templateRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*models.WorkoutTemplate, error) {
+	var t models.WorkoutTemplate
+	if err := conn(ctx, r.db).
+		Preload("Exercises", preloadTemplateExercises).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&t).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *templateRepository) Create(ctx context.Context, t *models.WorkoutTemplate) error {
+	return conn(ctx, r.db).Create(t).Error
+}
+
+func (r *templateRepository) Update(ctx context.Context, t *models.WorkoutTemplate, replaceExercises bool) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(t).
+			Select("name", "note", "updated_at").
+			Updates(t).Error; err != nil {
+			return err
+		}
+		if !replaceExercises {
+			return nil
+		}
+		if err := tx.Where("template_id = ?", t.ID).Delete(&models.TemplateExercise{}).Error; err != nil {
+			return err
+		}
+		if len(t.Exercises) == 0 {
+			return nil
+		}
+		for i := range t.Exercises {
+			t.Exercises[i].TemplateID = t.ID
+		}
+		return tx.Create(&t.Exercises).Error
+	})
+}
+
+func (r *templateRepository) Delete(ctx context.Context, id, userID string) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.WorkoutTemplate{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("template_id = ?", id).Delete(&models.TemplateExercise{}).Error
+	})
+}
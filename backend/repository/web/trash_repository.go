@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+type TrashRepository interface {
+	// ゴミ箱の中身（消した新しい順）。types が空ならすべての種類
+	List(ctx context.Context, userID string, f TrashListFilter) ([]models.TrashItem, int64, error)
+	// before より前にゴミ箱に入れたものを完全に消し、消したものを返す
+	Purge(ctx context.Context, before time.Time) ([]PurgedRow, error)
+}
+
+// PurgedRow は完全に消した 1 件（変更履歴に残す）
+type PurgedRow struct {
+	Type      models.TrashType
+	ID        string
+	UserID    string  // 持ち主
+	WorkoutID *string // ワークアウトとセットだけ
+	DeletedAt time.Time
+}
+
+type TrashListFilter struct {
+	Types  []models.TrashType
+	Limit  int
+	Offset int
+}
+
+type trashRepository struct {
+	db *gorm.DB
+}
+
+func NewTrashRepository(db *gorm.DB) TrashRepository {
+	return &trashRepository{db: db}
+}
+
+// trashQueries は種類ごとのゴミ箱の (type, id, deleted_at)。
+// ワークアウトごと消したセットはワークアウトのほうに出す
+var trashQueries = map[models.TrashType]string{
+	models.TrashWorkout: `SELECT 'workout' AS type, id, deleted_at FROM workouts
+	WHERE user_id = @user AND deleted_at IS NOT NULL`,
+	models.TrashSet: `SELECT 'set' AS type, ws.id, ws.deleted_at FROM workout_sets ws JOIN workouts w ON w.id = ws.workout_id
+	WHERE w.user_id = @user AND ws.deleted_at IS NOT NULL AND w.deleted_at IS NULL`,
+	models.TrashExercise: `SELECT 'exercise' AS type, id, deleted_at FROM exercises
+	WHERE owner_user_id = @user AND deleted_at IS NOT NULL`,
+	models.TrashBodyMetric: `SELECT 'body_metric' AS type, id, deleted_at FROM body_metrics
+	WHERE user_id = @user AND deleted_at IS NOT NULL`,
+}
+
+var trashTypes = []models.TrashType{models.TrashWorkout, models.TrashSet, models.TrashExercise, models.TrashBodyMetric}
+
+type trashRow struct {
+	Type      models.TrashType
+	ID        string
+	DeletedAt time.Time
+}
+
+func (r *trashRepository) List(ctx context.Context, userID string, f TrashListFilter) ([]models.TrashItem, int64, error) {
+	types := f.Types
+	if len(types) == 0 {
+		types = trashTypes
+	}
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		parts = append(parts, trashQueries[t])
+	}
+	union := strings.Join(parts, "\nUNION ALL\n")
+	args := map[string]any{"user": userID, "limit": f.Limit, "offset": f.Offset}
+
+	var total int64
+	if err := conn(ctx, r.db).Raw(`SELECT COUNT(*) FROM (`+union+`) t`, args).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var rows []trashRow
+	if err := conn(ctx, r.db).Raw(`SELECT * FROM (`+union+`) t
+ORDER BY deleted_at DESC, id
+LIMIT @limit OFFSET @offset`, args).Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	ids := map[models.TrashType][]string{}
+	for _, row := range rows {
+		ids[row.Type] = append(ids[row.Type], row.ID)
+	}
+	items := make([]models.TrashItem, len(rows))
+	byKey := make(map[string]*models.TrashItem, len(rows))
+	for i, row := range rows {
+		items[i] = models.TrashItem{Type: row.Type, ID: row.ID, DeletedAt: row.DeletedAt, PurgeAt: row.DeletedAt.Add(models.TrashRetention)}
+		byKey[string(row.Type)+":"+row.ID] = &items[i]
+	}
+
+	db := conn(ctx, r.db).Unscoped()
+	if len(ids[models.TrashWorkout]) > 0 {
+		var ws []models.Workout
+		if err := db.Where("id IN ?", ids[models.TrashWorkout]).Find(&ws).Error; err != nil {
+			return nil, 0, err
+		}
+		for i := range ws {
+			byKey["workout:"+ws[i].ID].Workout = &ws[i]
+		}
+	}
+	if len(ids[models.TrashSet]) > 0 {
+		var ss []models.WorkoutSet
+		if err := db.Where("id IN ?", ids[models.TrashSet]).Find(&ss).Error; err != nil {
+			return nil, 0, err
+		}
+		for i := range ss {
+			byKey["set:"+ss[i].ID].Set = &ss[i]
+		}
+	}
+	if len(ids[models.TrashExercise]) > 0 {
+		var es []models.Exercise
+		if err := db.Preload("Muscles").Where("id IN ?", ids[models.TrashExercise]).Find(&es).Error; err != nil {
+			return nil, 0, err
+		}
+		for i := range es {
+			byKey["exercise:"+es[i].ID].Exercise = &es[i]
+		}
+	}
+	if len(ids[models.TrashBodyMetric]) > 0 {
+		var bs []models.BodyMetric
+		if err := db.Where("id IN ?", ids[models.TrashBodyMetric]).Find(&bs).Error; err != nil {
+			return nil, 0, err
+		}
+		for i := range bs {
+			byKey["body_metric:"+bs[i].ID].BodyMetric = &bs[i]
+		}
+	}
+	return items, total, nil
+}
+
+func (r *trashRepository) Purge(ctx context.Context, before time.Time) ([]PurgedRow, error) {
+	var out []PurgedRow
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		stmts := []string{
+			`DELETE FROM workout_sets ws USING workouts w WHERE w.id = ws.workout_id AND ws.deleted_at < ?
+	RETURNING 'set' AS type, ws.id, w.user_id, ws.workout_id, ws.deleted_at`,
+			`DELETE FROM workouts WHERE deleted_at < ?
+	RETURNING 'workout' AS type, id, user_id, id AS workout_id, deleted_at`,
+			`DELETE FROM body_metrics WHERE deleted_at < ?
+	RETURNING 'body_metric' AS type, id, user_id, NULL::uuid AS workout_id, deleted_at`,
+			// 消したあとにセットやテンプレートで使われた種目は残す
+			`DELETE FROM exercises e WHERE deleted_at < ?
+	AND NOT EXISTS (SELECT 1 FROM workout_sets ws WHERE ws.exercise_id = e.id)
+	AND NOT EXISTS (SELECT 1 FROM template_exercises te WHERE te.exercise_id = e.id)
+	RETURNING 'exercise' AS type, id, COALESCE(owner_user_id::text, '') AS user_id, NULL::uuid AS workout_id, deleted_at`,
+		}
+		for _, q := range stmts {
+			var rows []PurgedRow
+			if err := tx.Raw(q, before).Scan(&rows).Error; err != nil {
+				return err
+			}
+			out = append(out, rows...)
+		}
+		return nil
+	})
+	return out, err
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Transactor は fn の中の DB 操作を 1 つのトランザクションで行う。
+// トランザクションは ctx に載せて渡すので、Usecase は今のメソッドをそのまま呼べばよい
+// （ctx を見るのは conn を使うリポジトリだけ）。
+type Transactor interface {
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+type gormTransactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &gormTransactor{db: db}
+}
+
+func (t *gormTransactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// すでにトランザクション中ならそのまま使う
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn は ctx にトランザクションがあればそれを、なければ db を返す
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db.WithContext(ctx)
+}
+
+// ErrVersionConflict は version が読んだときから変わっていて更新・削除できなかったとき。
+// Workout / WorkoutSet / BodyMetric の更新は version を比べて書き込み、書き込んだら 1 上げる
+var ErrVersionConflict = errors.New("version conflict: reload and retry")
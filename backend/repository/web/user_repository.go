@@ -110,7 +110,7 @@ func (r *lineAuthRepository) FetchProfile(ctx context.Context, accessToken strin
 
 func (r *lineAuthRepository) ResolveOrCreateBySub(ctx context.Context, sub string, name, pictureURL, email *string) (*models.User, error) {
 	var u models.User
-	err := r.db.WithContext(ctx).Where("line_user_id = ?", sub).First(&u).Error
+	err := conn(ctx, r.db).Where("line_user_id = ?", sub).First(&u).Error
 	switch {
 	case err == nil:
 		// 既存ユーザー：必要なら表示名やアイコンを軽く同期
@@ -125,7 +125,7 @@ func (r *lineAuthRepository) ResolveOrCreateBySub(ctx context.Context, sub strin
 			updates["email"] = *email
 		}
 		if len(updates) > 0 {
-			if err := r.db.WithContext(ctx).Model(&u).Updates(updates).Error; err != nil {
+			if err := conn(ctx, r.db).Model(&u).Updates(updates).Error; err != nil {
 				return nil, err
 			}
 		}
@@ -139,7 +139,7 @@ func (r *lineAuthRepository) ResolveOrCreateBySub(ctx context.Context, sub strin
 			PictureURL: pictureURL,
 			Email:      email,
 		}
-		if err := r.db.WithContext(ctx).Create(&u).Error; err != nil {
+		if err := conn(ctx, r.db).Create(&u).Error; err != nil {
 			return nil, err
 		}
 		return &u, nil
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+type UserSettingsRepository interface {
+	// 無ければ nil, nil
+	FindByUser(ctx context.Context, userID string) (*models.UserSettings, error)
+	// user_id 単位で作成 or 更新
+	Upsert(ctx context.Context, s *models.UserSettings) error
+}
+
+type userSettingsRepository struct {
+	db *gorm.DB
+}
+
+func NewUserSettingsRepository(db *gorm.DB) UserSettingsRepository {
+	return &userSettingsRepository{db: db}
+}
+
+func (r *userSettingsRepository) FindByUser(ctx context.Context, userID string) (*models.UserSettings, error) {
+	var s models.UserSettings
+	if err := conn(ctx, r.db).First(&s, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+var userSettingsColumns = []string{"timezone", "locale", "week_start", "weight_unit", "updated_at"}
+
+func (r *userSettingsRepository) Upsert(ctx context.Context, s *models.UserSettings) error {
+	// week_start = 0（日曜）も書くために列を明示する
+	return conn(ctx, r.db).
+		Select(append([]string{"user_id", "created_at"}, userSettingsColumns...)).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns(userSettingsColumns),
+		}).
+		Create(s).Error
+}
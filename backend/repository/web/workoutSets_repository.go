@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -10,10 +12,42 @@ import (
 
 type WorkoutSetRepository interface {
 	FindByID(ctx context.Context, id string) (*models.WorkoutSet, error)
+	// 保存前のセットに使う ID を DB で払い出す（gen_random_uuid()）
+	NewID(ctx context.Context) (string, error)
 	Create(ctx context.Context, ws *models.WorkoutSet) error
+	// Update は ws.Version が DB と同じときだけ全項目を書き込み、ws.Version を 1 上げる（違えば ErrVersionConflict）
 	Update(ctx context.Context, ws *models.WorkoutSet) error
-	Delete(ctx context.Context, id string) error
-	DeleteByWorkoutID(ctx context.Context, workoutID string) error
+	// Delete は version が変わっていなければゴミ箱に入れる（set_index とグループはそのまま残し、戻すときの位置に使う）
+	Delete(ctx context.Context, id string, version int) error
+	// ワークアウトの残っているセットをまとめてゴミ箱に入れる（deleted_at = at）
+	DeleteByWorkoutID(ctx context.Context, workoutID string, at time.Time) error
+	// ゴミ箱のセット（無ければ nil, nil）
+	FindDeletedByID(ctx context.Context, id string) (*models.WorkoutSet, error)
+	// ゴミ箱から戻して、ws の SetIndex と GroupOrder の位置に置く（位置が変わったら version を上げる）
+	Restore(ctx context.Context, ws *models.WorkoutSet) error
+	// ユーザーが LINE から登録した最新のセット（予定のセットは除く。無ければ nil, nil）
+	FindLatestFromLineByUser(ctx context.Context, userID string) (*models.WorkoutSet, error)
+	// テンプレートから作った未実施のセットのうち、その種目の最初のもの（無ければ nil, nil）
+	FindFirstPlanned(ctx context.Context, workoutID, exerciseID string) (*models.WorkoutSet, error)
+	// 自己ベストの対象になるセット（実施済み・ウォームアップ以外・重量と回数あり）を
+	// ワークアウトの開始順 → 登録順に並べる。同じワークアウトのセットは連続する
+	ListForRecords(ctx context.Context, userID, exerciseID string) ([]models.WorkoutSet, error)
+	// ワークアウト内のグループのセット（グループ内の順番どおり）
+	ListGroup(ctx context.Context, workoutID, groupID string) ([]models.WorkoutSet, error)
+	// グループ内で from 番目以降のセットを 1 つ後ろへずらす（from 番目に差し込む前に呼ぶ）。
+	// 並び（set_index・グループ・グループ内の順番）を書き換えるメソッドは、動いたセットの version を上げる
+	ShiftGroupOrder(ctx context.Context, groupID string, from int) error
+	// グループ内の順番を 1 から詰め直す（抜けたセットの穴を埋める）
+	RenumberGroup(ctx context.Context, groupID string) error
+	// ワークアウトのセットの並びをトランザクションの終わりまでロックする（採番・並べ替えの前に呼ぶ）
+	LockWorkoutSets(ctx context.Context, workoutID string) error
+	// ワークアウトの次の set_index（最後 + 1。セットが無ければ 1）
+	NextSetIndex(ctx context.Context, workoutID string) (int, error)
+	// from 番目以降の set_index を delta ずらす（差し込む前は +1、抜いたあとは -1）
+	ShiftSetIndex(ctx context.Context, workoutID string, from, delta int) error
+	// sets の SetIndex と ExerciseID をまとめて書き込む（並べ替え・種目の付け替え）。
+	// set_index か種目が変わったセットだけ version を上げる
+	SaveOrder(ctx context.Context, workoutID string, sets []models.WorkoutSet) error
 }
 
 type workoutSetRepository struct {
@@ -26,7 +60,7 @@ func NewWorkoutSetRepository(db *gorm.DB) WorkoutSetRepository {
 
 func (r *workoutSetRepository) FindByID(ctx context.Context, id string) (*models.WorkoutSet, error) {
 	var ws models.WorkoutSet
-	if err := r.db.WithContext(ctx).First(&ws, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).First(&ws, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -35,18 +69,203 @@ func (r *workoutSetRepository) FindByID(ctx context.Context, id string) (*models
 	return &ws, nil
 }
 
+func (r *workoutSetRepository) NewID(ctx context.Context) (string, error) {
+	var id string
+	err := conn(ctx, r.db).Raw("SELECT gen_random_uuid()").Scan(&id).Error
+	return id, err
+}
+
 func (r *workoutSetRepository) Create(ctx context.Context, ws *models.WorkoutSet) error {
-	return r.db.WithContext(ctx).Create(ws).Error
+	return conn(ctx, r.db).Create(ws).Error
 }
 
 func (r *workoutSetRepository) Update(ctx context.Context, ws *models.WorkoutSet) error {
-	return r.db.WithContext(ctx).Save(ws).Error
+	version := ws.Version
+	ws.Version++
+	res := conn(ctx, r.db).
+		Model(ws).
+		Where("version = ?", version).
+		Select("*").Omit("id", "deleted_at").
+		Updates(ws)
+	if res.Error != nil || res.RowsAffected == 0 {
+		ws.Version = version
+		if res.Error != nil {
+			return res.Error
+		}
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (r *workoutSetRepository) Delete(ctx context.Context, id string, version int) error {
+	res := conn(ctx, r.db).Delete(&models.WorkoutSet{}, "id = ? AND version = ?", id, version)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (r *workoutSetRepository) DeleteByWorkoutID(ctx context.Context, workoutID string, at time.Time) error {
+	return conn(ctx, r.db).
+		Model(&models.WorkoutSet{}).
+		Where("workout_id = ?", workoutID).
+		UpdateColumn("deleted_at", at).Error
+}
+
+func (r *workoutSetRepository) FindDeletedByID(ctx context.Context, id string) (*models.WorkoutSet, error) {
+	var ws models.WorkoutSet
+	if err := conn(ctx, r.db).Unscoped().First(&ws, "id = ? AND deleted_at IS NOT NULL", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ws, nil
+}
+
+func (r *workoutSetRepository) Restore(ctx context.Context, ws *models.WorkoutSet) error {
+	return conn(ctx, r.db).Unscoped().
+		Model(ws).
+		Where("deleted_at IS NOT NULL").
+		Updates(map[string]any{
+			"deleted_at":  nil,
+			"set_index":   ws.SetIndex,
+			"group_order": ws.GroupOrder,
+			"version":     gorm.Expr("version + CASE WHEN set_index <> ? OR group_order <> ? THEN 1 ELSE 0 END", ws.SetIndex, ws.GroupOrder),
+		}).Error
+}
+
+func (r *workoutSetRepository) FindLatestFromLineByUser(ctx context.Context, userID string) (*models.WorkoutSet, error) {
+	var ws models.WorkoutSet
+	err := conn(ctx, r.db).
+		Joins("JOIN workouts ON workouts.id = workout_sets.workout_id").
+		Where("workouts.user_id = ? AND workout_sets.is_from_line AND NOT workout_sets.is_planned", userID).
+		Order("workout_sets.created_at DESC").
+		First(&ws).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ws, nil
 }
 
-func (r *workoutSetRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Delete(&models.WorkoutSet{}, "id = ?", id).Error
+func (r *workoutSetRepository) FindFirstPlanned(ctx context.Context, workoutID, exerciseID string) (*models.WorkoutSet, error) {
+	var ws models.WorkoutSet
+	err := conn(ctx, r.db).
+		Where("workout_id = ? AND exercise_id = ? AND is_planned", workoutID, exerciseID).
+		Order("set_index ASC, created_at ASC").
+		First(&ws).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ws, nil
+}
+
+func (r *workoutSetRepository) ListForRecords(ctx context.Context, userID, exerciseID string) ([]models.WorkoutSet, error) {
+	var items []models.WorkoutSet
+	err := conn(ctx, r.db).
+		Joins("JOIN workouts ON workouts.id = workout_sets.workout_id").
+		Where("workouts.user_id = ? AND workout_sets.exercise_id = ?", userID, exerciseID).
+		Where("NOT workout_sets.is_planned AND NOT workout_sets.is_warmup").
+		Where("workout_sets.reps > 0 AND workout_sets.weight_kg > 0").
+		Order("workouts.started_at ASC, workouts.id ASC, workout_sets.created_at ASC, workout_sets.set_index ASC").
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *workoutSetRepository) ListGroup(ctx context.Context, workoutID, groupID string) ([]models.WorkoutSet, error) {
+	var items []models.WorkoutSet
+	err := conn(ctx, r.db).
+		Where("workout_id = ? AND group_id = ?", workoutID, groupID).
+		Order("group_order ASC, set_index ASC, created_at ASC").
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *workoutSetRepository) ShiftGroupOrder(ctx context.Context, groupID string, from int) error {
+	return conn(ctx, r.db).
+		Model(&models.WorkoutSet{}).
+		Where("group_id = ? AND group_order >= ?", groupID, from).
+		UpdateColumns(map[string]any{"group_order": gorm.Expr("group_order + 1"), "version": gorm.Expr("version + 1")}).Error
+}
+
+func (r *workoutSetRepository) RenumberGroup(ctx context.Context, groupID string) error {
+	return conn(ctx, r.db).Exec(`
+UPDATE workout_sets ws SET group_order = g.n, version = ws.version + 1
+FROM (
+	SELECT id, ROW_NUMBER() OVER (ORDER BY group_order, set_index, created_at) AS n
+	FROM workout_sets WHERE group_id = ? AND deleted_at IS NULL
+) g
+WHERE ws.id = g.id AND ws.group_order <> g.n`, groupID).Error
+}
+
+func (r *workoutSetRepository) LockWorkoutSets(ctx context.Context, workoutID string) error {
+	return conn(ctx, r.db).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "workout_sets:"+workoutID).Error
+}
+
+func (r *workoutSetRepository) NextSetIndex(ctx context.Context, workoutID string) (int, error) {
+	var next int
+	err := conn(ctx, r.db).
+		Model(&models.WorkoutSet{}).
+		Where("workout_id = ?", workoutID).
+		Select("COALESCE(MAX(set_index), 0) + 1").
+		Scan(&next).Error
+	return next, err
+}
+
+// setIndexParking は書き換え中の set_index を逃がす先。
+// ShiftSetIndex と SaveOrder は一度ここより後ろに逃がしてから戻す
+// （残っているセットの (workout_id, set_index) は一意なので（0007_set_index_unique）、1 文で書き換えると
+// 行ごとの一意チェックで途中の重なりが落ちる。マイナスは set_index >= 1 の CHECK に掛かる）。ゴミ箱のセットは動かさない
+const setIndexParking = 1000000
+
+func (r *workoutSetRepository) ShiftSetIndex(ctx context.Context, workoutID string, from, delta int) error {
+	if err := conn(ctx, r.db).Exec(`UPDATE workout_sets SET set_index = set_index + ?, version = version + 1 WHERE workout_id = ? AND set_index >= ? AND deleted_at IS NULL`,
+		delta+setIndexParking, workoutID, from).Error; err != nil {
+		return err
+	}
+	return r.unpark(ctx, workoutID)
+}
+
+func (r *workoutSetRepository) SaveOrder(ctx context.Context, workoutID string, sets []models.WorkoutSet) error {
+	if len(sets) == 0 {
+		return nil
+	}
+	rows := make([]string, 0, len(sets))
+	args := make([]any, 0, len(sets)*3+2)
+	args = append(args, setIndexParking)
+	for _, s := range sets {
+		rows = append(rows, "(?::uuid, ?::uuid, ?::int)")
+		args = append(args, s.ID, s.ExerciseID, s.SetIndex)
+	}
+	args = append(args, workoutID)
+
+	err := conn(ctx, r.db).Exec(`
+UPDATE workout_sets ws SET set_index = v.n + ?, exercise_id = v.exercise_id, updated_at = NOW(),
+	version = ws.version + 1
+FROM (VALUES `+strings.Join(rows, ", ")+`) AS v(id, exercise_id, n)
+WHERE ws.id = v.id AND ws.workout_id = ? AND ws.deleted_at IS NULL AND (ws.set_index <> v.n OR ws.exercise_id <> v.exercise_id)`, args...).Error
+	if err != nil {
+		return err
+	}
+	return r.unpark(ctx, workoutID)
 }
 
-func (r *workoutSetRepository) DeleteByWorkoutID(ctx context.Context, workoutID string) error {
-	return r.db.WithContext(ctx).Delete(&models.WorkoutSet{}, "workout_id = ?", workoutID).Error
+func (r *workoutSetRepository) unpark(ctx context.Context, workoutID string) error {
+	return conn(ctx, r.db).Exec(`UPDATE workout_sets SET set_index = set_index - ? WHERE workout_id = ? AND set_index > ? AND deleted_at IS NULL`,
+		setIndexParking, workoutID, setIndexParking).Error
 }
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"maps"
 	"time"
 
 	"github.com/sirasu21/Logbook/backend/models"
@@ -12,15 +14,34 @@ type WorkoutRepository interface {
 	Create(ctx context.Context, w *models.Workout) error
 	// ユーザー本人のワークアウトだけ取得
 	FindByIDForUser(ctx context.Context, workoutID string, userID string) (*models.Workout, error)
-	// ended_at を更新
-	UpdateEndedAt(ctx context.Context, workoutID string, endedAt time.Time) (*models.Workout, error)
+	// version が変わっていなければ ended_at を更新（変わっていれば ErrVersionConflict）
+	UpdateEndedAt(ctx context.Context, workoutID string, version int, endedAt time.Time) (*models.Workout, error)
 	FindWorkoutsByUser(ctx context.Context, userID string, q WorkoutQuery) ([]models.Workout, int, error)
 	FindByID(ctx context.Context, id string) (*models.Workout, error)
 	FindByIDAndUser(ctx context.Context, workoutID string, userID string) (*models.Workout, error)
 	ListSetsByWorkout(ctx context.Context, workoutID string) ([]models.WorkoutSet, error)
-	UpdateWorkoutByIDAndUser(ctx context.Context, workoutID, userID string, values map[string]any) (*models.Workout, error)
-	DeleteWorkoutByIDAndUser(ctx context.Context, workoutID, userID string) error
+	// ListSetsInPeriod は開始日時が [from, to) のワークアウトの実施済みのセットを 1 回でまとめて取る
+	//（ワークアウトの開始日時の古い順、ワークアウトの中は set_index 順）
+	ListSetsInPeriod(ctx context.Context, userID string, from, to time.Time) ([]models.WorkoutSet, error)
+	// version が変わっていなければ values で更新（変わっていれば ErrVersionConflict）
+	UpdateWorkoutByIDAndUser(ctx context.Context, workoutID, userID string, version int, values map[string]any) (*models.Workout, error)
+	// version が変わっていなければゴミ箱に入れる（deleted_at = at）。セットは WorkoutSetRepository.DeleteByWorkoutID で同じ at にする
+	DeleteWorkoutByIDAndUser(ctx context.Context, workoutID, userID string, version int, at time.Time) error
+	// ゴミ箱にある本人のワークアウト（無ければ nil, nil）
+	FindDeletedByIDAndUser(ctx context.Context, workoutID, userID string) (*models.Workout, error)
+	// ゴミ箱のワークアウトを、一緒に消したセットごと戻す（ゴミ箱に無ければ gorm.ErrRecordNotFound）
+	RestoreWorkout(ctx context.Context, workoutID, userID string) (*models.Workout, error)
 	FindLatestFromLineByUser(ctx context.Context, userID string, onlyOpen bool) (*models.Workout, error)
+	// 最後のセットの登録日時（未実施のセットは除く。セットがなければ nil）
+	LastSetAt(ctx context.Context, workoutID string) (*time.Time, error)
+	// LINE で開始して、最後の操作から各ユーザーの AutoCloseHours（未設定なら defaultHours）が過ぎたもの
+	ListAbandoned(ctx context.Context, now time.Time, defaultHours int) ([]models.AbandonedWorkout, error)
+	// まだ終了していなければ ended_at を入れる（終了済みなら false）
+	CloseIfOpen(ctx context.Context, workoutID string, endedAt time.Time) (bool, error)
+	// DailyStats は [from, to) のワークアウトを tz（IANA 名）の日ごとに集計する（トレーニングした日だけ）
+	DailyStats(ctx context.Context, userID, tz string, from, to time.Time) ([]DailyStatRow, error)
+	// StreakRuns は tz の日付で連続してトレーニングした期間のうち、最新のものと最長のもの（無ければ nil）
+	StreakRuns(ctx context.Context, userID, tz string) (latest, longest *StreakRun, err error)
 }
 
 type WorkoutQuery struct {
@@ -30,6 +51,21 @@ type WorkoutQuery struct {
 	Offset int
 }
 
+type DailyStatRow struct {
+	Day         time.Time // tz での日付（UTC の 0 時として入る）
+	Workouts    int
+	Sets        int     // 未実施のセットは除く
+	DurationSec float64 // 終了時刻（無ければ最後のセット）まで
+	Muscles     string  // 主動筋の key をカンマ区切り（ウォームアップは除く）
+}
+
+type StreakRun struct {
+	Kind     string
+	StartDay time.Time
+	EndDay   time.Time
+	Days     int
+}
+
 type workoutRepository struct {
 	db *gorm.DB
 }
@@ -39,12 +75,12 @@ func NewWorkoutRepository(db *gorm.DB) WorkoutRepository {
 }
 
 func (r *workoutRepository) Create(ctx context.Context, w *models.Workout) error {
-	return r.db.WithContext(ctx).Create(w).Error
+	return conn(ctx, r.db).Create(w).Error
 }
 
 func (r *workoutRepository) FindByIDForUser(ctx context.Context, workoutID string, userID string) (*models.Workout, error) {
 	var w models.Workout
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Where("id = ? AND user_id = ?", workoutID, userID).
 		First(&w).Error; err != nil {
 		return nil, err
@@ -52,24 +88,28 @@ func (r *workoutRepository) FindByIDForUser(ctx context.Context, workoutID strin
 	return &w, nil
 }
 
-func (r *workoutRepository) UpdateEndedAt(ctx context.Context, workoutID string, endedAt time.Time) (*models.Workout, error) {
+func (r *workoutRepository) UpdateEndedAt(ctx context.Context, workoutID string, version int, endedAt time.Time) (*models.Workout, error) {
 	// 更新してから再取得（RETURNING がほしければ Update + First でもOK）
-	if err := r.db.WithContext(ctx).
+	res := conn(ctx, r.db).
 		Model(&models.Workout{}).
-		Where("id = ?", workoutID).
-		Update("ended_at", endedAt).Error; err != nil {
-		return nil, err
+		Where("id = ? AND version = ?", workoutID, version).
+		Updates(map[string]any{"ended_at": endedAt, "version": gorm.Expr("version + 1")})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrVersionConflict
 	}
 
 	var w models.Workout
-	if err := r.db.WithContext(ctx).First(&w, "id = ?", workoutID).Error; err != nil {
+	if err := conn(ctx, r.db).First(&w, "id = ?", workoutID).Error; err != nil {
 		return nil, err
 	}
 	return &w, nil
 }
 
 func (r *workoutRepository) FindWorkoutsByUser(ctx context.Context, userID string, q WorkoutQuery) ([]models.Workout, int, error) {
-	tx := r.db.WithContext(ctx).Model(&models.Workout{}).Where("user_id = ?", userID)
+	tx := conn(ctx, r.db).Model(&models.Workout{}).Where("user_id = ?", userID)
 	if q.From != nil {
 		tx = tx.Where("started_at >= ?", *q.From)
 	}
@@ -101,7 +141,7 @@ func (r *workoutRepository) FindWorkoutsByUser(ctx context.Context, userID strin
 // repository/workout_repository.go に追記
 func (r *workoutRepository) FindByID(ctx context.Context, id string) (*models.Workout, error) {
 	var w models.Workout
-	if err := r.db.WithContext(ctx).First(&w, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).First(&w, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -112,7 +152,7 @@ func (r *workoutRepository) FindByID(ctx context.Context, id string) (*models.Wo
 
 func (r *workoutRepository) FindByIDAndUser(ctx context.Context, workoutID string, userID string) (*models.Workout, error) {
 	var w models.Workout
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Where("id = ? AND user_id = ?", workoutID, userID).
 		First(&w).Error; err != nil {
 		return nil, err
@@ -122,7 +162,7 @@ func (r *workoutRepository) FindByIDAndUser(ctx context.Context, workoutID strin
 
 func (r *workoutRepository) ListSetsByWorkout(ctx context.Context, workoutID string) ([]models.WorkoutSet, error) {
 	var sets []models.WorkoutSet
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Where("workout_id = ?", workoutID).
 		Order("set_index ASC, created_at ASC").
 		Find(&sets).Error; err != nil {
@@ -131,27 +171,86 @@ func (r *workoutRepository) ListSetsByWorkout(ctx context.Context, workoutID str
 	return sets, nil
 }
 
-func (r *workoutRepository) UpdateWorkoutByIDAndUser(ctx context.Context, workoutID, userID string, values map[string]any) (*models.Workout, error) {
+func (r *workoutRepository) ListSetsInPeriod(ctx context.Context, userID string, from, to time.Time) ([]models.WorkoutSet, error) {
+	var sets []models.WorkoutSet
+	if err := conn(ctx, r.db).
+		Joins("JOIN workouts ON workouts.id = workout_sets.workout_id").
+		Where("workouts.user_id = ? AND workouts.started_at >= ? AND workouts.started_at < ? AND workouts.deleted_at IS NULL", userID, from, to).
+		Where("NOT workout_sets.is_planned").
+		Order("workouts.started_at ASC, workouts.id ASC, workout_sets.set_index ASC, workout_sets.created_at ASC").
+		Find(&sets).Error; err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+func (r *workoutRepository) UpdateWorkoutByIDAndUser(ctx context.Context, workoutID, userID string, version int, values map[string]any) (*models.Workout, error) {
 	if len(values) == 0 {
 		return r.FindByIDAndUser(ctx, workoutID, userID)
 	}
-	if err := r.db.WithContext(ctx).
+	data := maps.Clone(values)
+	data["version"] = gorm.Expr("version + 1")
+	res := conn(ctx, r.db).
 		Model(&models.Workout{}).
-		Where("id = ? AND user_id = ?", workoutID, userID).
-		Updates(values).Error; err != nil {
-		return nil, err
+		Where("id = ? AND user_id = ? AND version = ?", workoutID, userID, version).
+		Updates(data)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrVersionConflict
 	}
 	return r.FindByIDAndUser(ctx, workoutID, userID)
 }
 
-func (r *workoutRepository) DeleteWorkoutByIDAndUser(ctx context.Context, workoutID, userID string) error {
-	return r.db.WithContext(ctx).
-		Where("id = ? AND user_id = ?", workoutID, userID).
-		Delete(&models.Workout{}).Error
+func (r *workoutRepository) DeleteWorkoutByIDAndUser(ctx context.Context, workoutID, userID string, version int, at time.Time) error {
+	res := conn(ctx, r.db).
+		Model(&models.Workout{}).
+		Where("id = ? AND user_id = ? AND version = ?", workoutID, userID, version).
+		Update("deleted_at", at)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (r *workoutRepository) FindDeletedByIDAndUser(ctx context.Context, workoutID, userID string) (*models.Workout, error) {
+	var w models.Workout
+	err := conn(ctx, r.db).Unscoped().
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", workoutID, userID).
+		First(&w).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (r *workoutRepository) RestoreWorkout(ctx context.Context, workoutID, userID string) (*models.Workout, error) {
+	var w models.Workout
+	if err := conn(ctx, r.db).Unscoped().
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", workoutID, userID).
+		First(&w).Error; err != nil {
+		return nil, err
+	}
+	// 一緒に消したセット（deleted_at が同じ）だけ戻す。先に 1 件ずつ消したセットはゴミ箱に残す
+	if err := conn(ctx, r.db).Exec(`UPDATE workout_sets SET deleted_at = NULL WHERE workout_id = ? AND deleted_at = ?`,
+		w.ID, w.DeletedAt).Error; err != nil {
+		return nil, err
+	}
+	if err := conn(ctx, r.db).Unscoped().Model(&w).Update("deleted_at", nil).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
 }
 
 func (r *workoutRepository) FindLatestFromLineByUser(ctx context.Context, userID string, onlyOpen bool) (*models.Workout, error) {
-	tx := r.db.WithContext(ctx).Model(&models.Workout{}).
+	tx := conn(ctx, r.db).Model(&models.Workout{}).
 		Where("user_id = ? AND is_from_line = ?", userID, true)
 	if onlyOpen {
 		tx = tx.Where("ended_at IS NULL")
@@ -164,4 +263,111 @@ func (r *workoutRepository) FindLatestFromLineByUser(ctx context.Context, userID
 		return nil, err
 	}
 	return &w, nil
-}
\ No newline at end of file
+}
+
+func (r *workoutRepository) LastSetAt(ctx context.Context, workoutID string) (*time.Time, error) {
+	var last sql.NullTime
+	if err := conn(ctx, r.db).
+		Model(&models.WorkoutSet{}).
+		Select("MAX(created_at)").
+		Where("workout_id = ? AND NOT is_planned", workoutID).
+		Row().Scan(&last); err != nil {
+		return nil, err
+	}
+	if !last.Valid {
+		return nil, nil
+	}
+	return &last.Time, nil
+}
+
+func (r *workoutRepository) ListAbandoned(ctx context.Context, now time.Time, defaultHours int) ([]models.AbandonedWorkout, error) {
+	var out []models.AbandonedWorkout
+	err := conn(ctx, r.db).
+		Table("workouts").
+		Select("workouts.*, users.line_user_id, s.last_set_at").
+		Joins("JOIN users ON users.id = workouts.user_id").
+		Joins("LEFT JOIN (SELECT workout_id, MAX(created_at) AS last_set_at FROM workout_sets WHERE NOT is_planned AND deleted_at IS NULL GROUP BY workout_id) s ON s.workout_id = workouts.id").
+		Joins("LEFT JOIN reminder_preferences rp ON rp.user_id = workouts.user_id").
+		Where("workouts.ended_at IS NULL AND workouts.is_from_line AND workouts.deleted_at IS NULL").
+		Where("COALESCE(rp.auto_close_hours, ?) > 0", defaultHours).
+		Where("COALESCE(s.last_set_at, workouts.started_at) + COALESCE(rp.auto_close_hours, ?) * INTERVAL '1 hour' <= ?", defaultHours, now).
+		Order("workouts.started_at ASC").
+		Find(&out).Error
+	return out, err
+}
+
+func (r *workoutRepository) CloseIfOpen(ctx context.Context, workoutID string, endedAt time.Time) (bool, error) {
+	res := conn(ctx, r.db).
+		Model(&models.Workout{}).
+		Where("id = ? AND ended_at IS NULL", workoutID).
+		Updates(map[string]any{"ended_at": endedAt, "version": gorm.Expr("version + 1")})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *workoutRepository) DailyStats(ctx context.Context, userID, tz string, from, to time.Time) ([]DailyStatRow, error) {
+	var rows []DailyStatRow
+	err := conn(ctx, r.db).Raw(`
+WITH wk AS (
+	SELECT w.id, (w.started_at AT TIME ZONE @tz)::date AS day,
+		GREATEST(EXTRACT(EPOCH FROM (COALESCE(w.ended_at, ls.last_set_at, w.started_at) - w.started_at)), 0) AS duration_sec
+	FROM workouts w
+	LEFT JOIN (SELECT workout_id, MAX(created_at) AS last_set_at FROM workout_sets WHERE NOT is_planned AND deleted_at IS NULL GROUP BY workout_id) ls ON ls.workout_id = w.id
+	WHERE w.user_id = @user AND w.started_at >= @from AND w.started_at < @to AND w.deleted_at IS NULL
+), st AS (
+	SELECT wk.day, COUNT(*) AS sets
+	FROM wk JOIN workout_sets ws ON ws.workout_id = wk.id
+	WHERE NOT ws.is_planned AND ws.deleted_at IS NULL
+	GROUP BY wk.day
+), mu AS (
+	SELECT wk.day, STRING_AGG(DISTINCT em.muscle, ',') AS muscles
+	FROM wk
+	JOIN workout_sets ws ON ws.workout_id = wk.id
+	JOIN exercise_muscles em ON em.exercise_id = ws.exercise_id AND em.role = 'primary'
+	WHERE NOT ws.is_planned AND NOT ws.is_warmup AND ws.deleted_at IS NULL
+	GROUP BY wk.day
+)
+SELECT wk.day, COUNT(*) AS workouts, COALESCE(MAX(st.sets), 0) AS sets,
+	SUM(wk.duration_sec)::float8 AS duration_sec, COALESCE(MAX(mu.muscles), '') AS muscles
+FROM wk
+LEFT JOIN st ON st.day = wk.day
+LEFT JOIN mu ON mu.day = wk.day
+GROUP BY wk.day
+ORDER BY wk.day`,
+		sql.Named("tz", tz), sql.Named("user", userID), sql.Named("from", from), sql.Named("to", to)).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *workoutRepository) StreakRuns(ctx context.Context, userID, tz string) (*StreakRun, *StreakRun, error) {
+	// 連続した日付は「日付 - 連番」が同じになる
+	var rows []StreakRun
+	err := conn(ctx, r.db).Raw(`
+WITH d AS (
+	SELECT DISTINCT (started_at AT TIME ZONE @tz)::date AS day FROM workouts WHERE user_id = @user AND deleted_at IS NULL
+), g AS (
+	SELECT day, day - (ROW_NUMBER() OVER (ORDER BY day))::int AS grp FROM d
+), runs AS (
+	SELECT MIN(day) AS start_day, MAX(day) AS end_day, COUNT(*) AS days FROM g GROUP BY grp
+)
+(SELECT 'latest' AS kind, * FROM runs ORDER BY end_day DESC LIMIT 1)
+UNION ALL
+(SELECT 'longest' AS kind, * FROM runs ORDER BY days DESC, end_day DESC LIMIT 1)`,
+		sql.Named("tz", tz), sql.Named("user", userID)).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	var latest, longest *StreakRun
+	for i := range rows {
+		switch rows[i].Kind {
+		case "latest":
+			latest = &rows[i]
+		case "longest":
+			longest = &rows[i]
+		}
+	}
+	return latest, longest, nil
+}
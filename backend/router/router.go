@@ -12,10 +12,11 @@ import (
 	controllerLine "github.com/sirasu21/Logbook/backend/controller/LINE"
 	controller "github.com/sirasu21/Logbook/backend/controller/web"
 	"github.com/sirasu21/Logbook/backend/models"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
 	"gorm.io/gorm"
 )
 
-func NewRouter(cfg models.Config, gdb *gorm.DB, userCtl controller.UserController, workoutCtl controller.WorkoutController, workoutSetCtl controller.WorkoutSetController, exerciseCtl controller.ExerciseController, bodyCtl controller.BodyMetricController, lineExerciseCtl controllerLine.LineController) *echo.Echo {
+func NewRouter(cfg models.Config, gdb *gorm.DB, userCtl controller.UserController, workoutCtl controller.WorkoutController, workoutSetCtl controller.WorkoutSetController, exerciseCtl controller.ExerciseController, bodyCtl controller.BodyMetricController, reminderCtl controller.ReminderController, templateCtl controller.TemplateController, recordCtl controller.RecordController, analyticsCtl controller.AnalyticsController, calendarCtl controller.CalendarController, settingsCtl controller.UserSettingsController, trashCtl controller.TrashController, auditCtl controller.AuditController, lineExerciseCtl controllerLine.LineController) *echo.Echo {
 	e := echo.New()
 	store := sessions.NewCookieStore([]byte("super-secret-key"))
 	store.Options = &sessions.Options{
@@ -35,7 +36,8 @@ func NewRouter(cfg models.Config, gdb *gorm.DB, userCtl controller.UserControlle
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     []string{cfg.FrontendOrigin},
 		AllowMethods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions},
-		AllowHeaders:     []string{"Content-Type", "Authorization", "X-Debug-User"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", "X-Debug-User", "If-Match"},
+		ExposeHeaders:    []string{"ETag"},
 		AllowCredentials: true,
 	}))
 
@@ -43,8 +45,10 @@ func NewRouter(cfg models.Config, gdb *gorm.DB, userCtl controller.UserControlle
 	e.GET("/api/auth/line/login", userCtl.LineLogin)
 	e.GET("/api/auth/line/callback", userCtl.LineCallback)
 
-	api := e.Group("/api")
+	api := e.Group("/api", webAuditSource)
 	api.GET("/me", userCtl.Me)
+	api.GET("/me/settings", settingsCtl.Get)
+	api.PUT("/me/settings", settingsCtl.Save)
 
 	api.POST("/workouts", workoutCtl.CreateWorkout)
 	api.PATCH("/workouts/:id", workoutCtl.UpdateWorkout)
@@ -52,8 +56,11 @@ func NewRouter(cfg models.Config, gdb *gorm.DB, userCtl controller.UserControlle
 	api.DELETE("/workouts/:id", workoutCtl.DeleteWorkout)
 	api.GET("/workouts", workoutCtl.ListWorkouts)
 	api.GET("/workouts/:id/detail", workoutCtl.GetWorkoutDetail)
+	api.POST("/workouts/:id/clone", workoutCtl.CloneWorkout)
 
 	api.POST("/workouts/:workoutId/sets", workoutSetCtl.AddSet)
+	api.PUT("/workouts/:id/sets/order", workoutSetCtl.ReorderSets)
+	api.GET("/workout_sets/:setId", workoutSetCtl.GetSet)
 	api.PATCH("/workout_sets/:setId", workoutSetCtl.UpdateSet)
 	api.DELETE("/workout_sets/:setId", workoutSetCtl.DeleteSet)
 
@@ -65,9 +72,34 @@ func NewRouter(cfg models.Config, gdb *gorm.DB, userCtl controller.UserControlle
 
 	api.GET("/body_metrics", bodyCtl.List)
 	api.POST("/body_metrics", bodyCtl.Create)
+	api.GET("/body_metrics/:id", bodyCtl.Get)
 	api.PATCH("/body_metrics/:id", bodyCtl.Update)
 	api.DELETE("/body_metrics/:id", bodyCtl.Delete)
 
+	api.GET("/reminders", reminderCtl.Get)
+	api.PUT("/reminders", reminderCtl.Save)
+	api.DELETE("/reminders", reminderCtl.Delete)
+
+	api.GET("/templates", templateCtl.List)
+	api.GET("/templates/:id", templateCtl.Get)
+	api.POST("/templates", templateCtl.Create)
+	api.PATCH("/templates/:id", templateCtl.Update)
+	api.DELETE("/templates/:id", templateCtl.Delete)
+	api.POST("/templates/:id/start", templateCtl.Start)
+
+	api.GET("/exercises/:id/records", recordCtl.ListByExercise)
+	api.GET("/records", recordCtl.ListCurrent)
+
+	api.GET("/exercises/:id/progress", analyticsCtl.ExerciseProgress) // ?from=&to=&formula=
+	api.GET("/analytics/muscle-volume", analyticsCtl.MuscleVolume)    // ?week=&weeks=
+	api.GET("/calendar", calendarCtl.Month)                           // ?year=&month=&tz=&target=
+
+	api.GET("/trash", trashCtl.List) // ?type=&limit=&offset=
+	api.POST("/trash/:type/:id/restore", trashCtl.Restore)
+
+	api.GET("/workouts/:id/history", auditCtl.WorkoutHistory) // ?entityType=&from=&to=&limit=&offset=
+	api.GET("/activity", auditCtl.Activity)                   // ?entityType=&from=&to=&limit=&offset=
+
 
 	e.GET("/api/logout", userCtl.Logout)
 	e.POST("/callback", echo.HandlerFunc(lineExerciseCtl.Webhook))
@@ -83,3 +115,13 @@ func NewRouter(cfg models.Config, gdb *gorm.DB, userCtl controller.UserControlle
 
 	return e
 }
+
+// webAuditSource は Web からの変更を X-Request-Id 付きで変更履歴に残すよう ctx に載せる
+func webAuditSource(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		ctx := usecase.WithAuditSource(req.Context(), models.AuditSourceWeb, c.Response().Header().Get(echo.HeaderXRequestID))
+		c.SetRequest(req.WithContext(ctx))
+		return next(c)
+	}
+}
@@ -0,0 +1,219 @@
+// Package scheduler は LINE のリマインド・声かけ・週次まとめ・休憩タイマーの終わりを送り、放置されたワークアウトを終了する。
+// 保存期間を過ぎたゴミ箱の中身もここで消す。
+package scheduler
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repositoryLine "github.com/sirasu21/Logbook/backend/repository/LINE"
+	usecase "github.com/sirasu21/Logbook/backend/usecase/web"
+)
+
+// Notifier は LINE へのプッシュ送信（controllerLine.LineController が満たす）
+type Notifier interface {
+	PushTrainingReminder(ctx context.Context, lineUserID string) error
+	PushInactiveNudge(ctx context.Context, lineUserID string, days int) error
+	PushWeeklyRecap(ctx context.Context, lineUserID, userID string) error
+	PushWorkoutAutoClosed(ctx context.Context, w models.AbandonedWorkout) error
+	PushRestOver(ctx context.Context, t models.RestTimer) error
+}
+
+// RestTimers は終わった休憩タイマーの取り出し（usecaseLine.LineUsecase が満たす）
+type RestTimers interface {
+	DueRestTimers(ctx context.Context, now time.Time) ([]models.RestTimer, error)
+}
+
+// 時刻・曜日の判定はユーザーごとのタイムゾーン（user_settings）で行う
+const (
+	// 「N 日トレーニングしていません」を送る時刻
+	inactiveNudgeAt = "20:00"
+	// 週次まとめは週の最後の日（月曜始まりなら日曜）のこの時刻
+	weeklyRecapAt = "21:00"
+	// 放置されたワークアウトの終了はこの間隔（分）で行う
+	autoCloseEvery = 10
+	// 同じ分の処理を複数プロセスで重複させないためのロック
+	tickLockTTL = 10 * time.Minute
+	// ゴミ箱の掃除は毎時この分に行う
+	purgeTrashAt = 30
+	// 休憩タイマーは秒単位なので分の tick とは別に確かめる
+	restTimerPoll = 5 * time.Second
+	// これより遅れた休憩タイマーは知らせずに捨てる（スケジューラーが長く止まっていたとき）
+	restTimerStale = 10 * time.Minute
+)
+
+type Scheduler struct {
+	reminders usecase.ReminderUsecase
+	workouts  usecase.WorkoutUsecase
+	notify    Notifier
+	lock      repositoryLine.LineRepository
+	timers    RestTimers
+	trash     usecase.TrashUsecase
+}
+
+func New(reminders usecase.ReminderUsecase, workouts usecase.WorkoutUsecase, notify Notifier, lock repositoryLine.LineRepository, timers RestTimers, trash usecase.TrashUsecase) *Scheduler {
+	return &Scheduler{reminders: reminders, workouts: workouts, notify: notify, lock: lock, timers: timers, trash: trash}
+}
+
+// EnabledInProcess は SCHEDULER_MODE に応じて API プロセス内で動かすかを返す
+// （external なら cmd/scheduler を別に動かす、off なら動かさない）
+func EnabledInProcess() bool {
+	mode := os.Getenv("SCHEDULER_MODE")
+	return mode != "external" && mode != "off"
+}
+
+// Run は ctx がキャンセルされるまで毎分 tick する（休憩タイマーは restTimerPoll ごと）
+func (s *Scheduler) Run(ctx context.Context) error {
+	log.Printf("scheduler started")
+	go s.runRestTimers(ctx)
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		t := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case at := <-t.C:
+			s.Tick(ctx, at)
+		}
+	}
+}
+
+// Tick は 1 分ぶんの処理。どのプロセスが実行するかは Redis のロックで 1 つに絞る
+func (s *Scheduler) Tick(ctx context.Context, at time.Time) {
+	now := at.UTC().Truncate(time.Minute)
+	key := "scheduler:tick:" + now.Format("200601021504")
+	ok, err := s.lock.SetNX(ctx, key, now, tickLockTTL)
+	if err != nil {
+		log.Printf("❌ scheduler: lock failed / err=%v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	// ジョブが変えたもの（放置されたワークアウトの終了）は操作したユーザーなしで履歴に残す
+	ctx = usecase.WithAuditSource(ctx, models.AuditSourceJob, key)
+
+	s.sendTrainingReminders(ctx, now)
+	if now.Minute()%autoCloseEvery == 0 {
+		s.closeAbandonedWorkouts(ctx, now)
+	}
+	if now.Minute() == purgeTrashAt {
+		s.purgeTrash(ctx, now)
+	}
+	// 送る時刻はユーザーごとに違うので毎分確認する
+	s.sendInactiveNudges(ctx, now)
+	s.sendWeeklyRecaps(ctx, now)
+}
+
+func (s *Scheduler) sendTrainingReminders(ctx context.Context, now time.Time) {
+	targets, err := s.reminders.DueReminders(ctx, now)
+	if err != nil {
+		log.Printf("❌ scheduler: list reminders failed / err=%v", err)
+		return
+	}
+	for _, t := range targets {
+		if err := s.notify.PushTrainingReminder(ctx, t.LineUserID); err != nil {
+			log.Printf("❌ scheduler: reminder push failed / userID=%s / err=%v", t.UserID, err)
+			continue
+		}
+		s.markSent(ctx, t.ID, usecase.ReminderKindTraining, now)
+	}
+}
+
+func (s *Scheduler) sendInactiveNudges(ctx context.Context, now time.Time) {
+	targets, err := s.reminders.InactiveTargets(ctx, now, inactiveNudgeAt)
+	if err != nil {
+		log.Printf("❌ scheduler: list inactive users failed / err=%v", err)
+		return
+	}
+	for _, t := range targets {
+		if err := s.notify.PushInactiveNudge(ctx, t.LineUserID, t.Days); err != nil {
+			log.Printf("❌ scheduler: nudge push failed / userID=%s / err=%v", t.UserID, err)
+			continue
+		}
+		s.markSent(ctx, t.ID, usecase.ReminderKindInactive, now)
+	}
+}
+
+func (s *Scheduler) sendWeeklyRecaps(ctx context.Context, now time.Time) {
+	targets, err := s.reminders.RecapTargets(ctx, now, weeklyRecapAt)
+	if err != nil {
+		log.Printf("❌ scheduler: list recap targets failed / err=%v", err)
+		return
+	}
+	for _, t := range targets {
+		if err := s.notify.PushWeeklyRecap(ctx, t.LineUserID, t.UserID); err != nil {
+			log.Printf("❌ scheduler: recap push failed / userID=%s / err=%v", t.UserID, err)
+			continue
+		}
+		s.markSent(ctx, t.ID, usecase.ReminderKindRecap, now)
+	}
+}
+
+// closeAbandonedWorkouts は最後のセットから AutoCloseHours 操作のないワークアウトを終了して知らせる
+func (s *Scheduler) closeAbandonedWorkouts(ctx context.Context, now time.Time) {
+	closed, err := s.workouts.CloseAbandoned(ctx, now)
+	if err != nil {
+		log.Printf("❌ scheduler: auto close failed / err=%v", err)
+	}
+	for _, w := range closed {
+		if err := s.notify.PushWorkoutAutoClosed(ctx, w); err != nil {
+			log.Printf("❌ scheduler: auto close push failed / workoutID=%s / err=%v", w.ID, err)
+		}
+	}
+}
+
+// purgeTrash は TrashRetention を過ぎたゴミ箱の中身を完全に消す
+func (s *Scheduler) purgeTrash(ctx context.Context, now time.Time) {
+	n, err := s.trash.Purge(ctx, now)
+	if err != nil {
+		log.Printf("❌ scheduler: purge trash failed / err=%v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("scheduler: purged %d trashed rows", n)
+	}
+}
+
+// runRestTimers は ctx がキャンセルされるまで、終わった休憩タイマーを知らせる。
+// タイマーは取り出したときに消えるので、複数プロセスで回しても 1 回だけ送られる
+func (s *Scheduler) runRestTimers(ctx context.Context) {
+	t := time.NewTicker(restTimerPoll)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			s.sendRestTimers(ctx, now)
+		}
+	}
+}
+
+func (s *Scheduler) sendRestTimers(ctx context.Context, now time.Time) {
+	due, err := s.timers.DueRestTimers(ctx, now)
+	if err != nil {
+		log.Printf("❌ scheduler: list rest timers failed / err=%v", err)
+		return
+	}
+	for _, t := range due {
+		if now.Sub(t.DueAt) > restTimerStale {
+			log.Printf("scheduler: rest timer dropped (stale) / userID=%s / dueAt=%s", t.UserID, t.DueAt.Format(time.RFC3339))
+			continue
+		}
+		if err := s.notify.PushRestOver(ctx, t); err != nil {
+			log.Printf("❌ scheduler: rest timer push failed / userID=%s / err=%v", t.UserID, err)
+		}
+	}
+}
+
+func (s *Scheduler) markSent(ctx context.Context, id string, kind usecase.ReminderKind, at time.Time) {
+	if err := s.reminders.MarkSent(ctx, id, kind, at); err != nil {
+		log.Printf("❌ scheduler: mark sent failed / id=%s / kind=%s / err=%v", id, kind, err)
+	}
+}
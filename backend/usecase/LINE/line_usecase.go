@@ -2,8 +2,14 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/go-redis/redis"
+
+	"github.com/sirasu21/Logbook/backend/models"
 	repositoryLine "github.com/sirasu21/Logbook/backend/repository/LINE"
 )
 
@@ -11,14 +17,53 @@ type LineUsecase interface {
 	Get(ctx context.Context, key string) (string, error)
 	Set(ctx context.Context, key string, val any, ttl time.Duration) error
 	Del(ctx context.Context, key string) error
+	// Webhook イベントの重複排除。初めて見る eventID なら true（処理中の印を付ける）。
+	// 処理済みなら false、他で処理中なら ErrEventInProgress
+	ClaimEvent(ctx context.Context, eventID string) (bool, error)
+	// 処理し終えたイベントを処理済みにする（これ以降の再送は読み捨てる）
+	CompleteEvent(ctx context.Context, eventID string) error
+	// 処理に失敗したイベントを再送で再処理できるように戻す
+	ReleaseEvent(ctx context.Context, eventID string) error
+	// Webhook イベントをワーカー用キューに積む（userID ごとに順序保証される）
+	EnqueueEvent(ctx context.Context, userID string, payload []byte) error
+	// 休憩タイマーを t.RestSec 秒後に鳴らす（動いているタイマーは置き換える）
+	StartRestTimer(ctx context.Context, t models.RestTimer) (*models.RestTimer, error)
+	// 動いていたタイマーを止めたら true
+	CancelRestTimer(ctx context.Context, lineUserID string) (bool, error)
+	// now までに終わったタイマーを取り出す（スケジューラー用。取り出したものは消える）
+	DueRestTimers(ctx context.Context, now time.Time) ([]models.RestTimer, error)
+}
+
+// LINE の再送は最大でも 1 日程度なので、それより長めに保持する
+const eventDedupTTL = 48 * time.Hour
+
+// 処理中の印の寿命。処理の途中でワーカーが落ちても、これが切れれば引き取ったワーカーが処理し直せる
+const eventClaimTTL = time.Minute
+
+// 重複排除キーの値
+const (
+	eventProcessing = "processing"
+	eventDone       = "done"
+)
+
+// ErrEventInProgress は同じイベントを別のワーカーが処理中のときに返る（ACK せず後でやり直す）
+var ErrEventInProgress = errors.New("LINE event is being processed by another worker")
+
+// 1 回に取り出す休憩タイマーの数
+const restTimerBatch = 100
+
+func eventKey(eventID string) string {
+	return "line:event:" + eventID
 }
 
 type lineUsecase struct{
 	rp repositoryLine.LineRepository
+	qrp repositoryLine.LineQueueRepository
+	trp repositoryLine.RestTimerRepository
 }
 
-func NewLineUsecase(rp repositoryLine.LineRepository) LineUsecase{
-	return &lineUsecase{rp: rp}
+func NewLineUsecase(rp repositoryLine.LineRepository, qrp repositoryLine.LineQueueRepository, trp repositoryLine.RestTimerRepository) LineUsecase{
+	return &lineUsecase{rp: rp, qrp: qrp, trp: trp}
 }
 
 func (u *lineUsecase)Get(ctx context.Context, key string) (string, error){
@@ -45,7 +90,59 @@ func (u *lineUsecase)Del(ctx context.Context, key string) error{
 	return nil
 }
 
+func (u *lineUsecase) ClaimEvent(ctx context.Context, eventID string) (bool, error) {
+	ok, err := u.rp.SetNX(ctx, eventKey(eventID), eventProcessing, eventClaimTTL)
+	if err != nil || ok {
+		return ok, err
+	}
+	raw, err := u.rp.Get(ctx, eventKey(eventID))
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			// 見ている間に処理中の印が切れた。次のリトライで取り直す
+			return false, ErrEventInProgress
+		}
+		return false, err
+	}
+	var state string
+	if err := json.Unmarshal([]byte(raw), &state); err != nil {
+		return false, err
+	}
+	if state != eventDone {
+		return false, ErrEventInProgress
+	}
+	return false, nil
+}
+
+func (u *lineUsecase) CompleteEvent(ctx context.Context, eventID string) error {
+	return u.rp.SetEX(ctx, eventKey(eventID), eventDone, eventDedupTTL)
+}
 
+func (u *lineUsecase) ReleaseEvent(ctx context.Context, eventID string) error {
+	return u.rp.Del(ctx, eventKey(eventID))
+}
+
+func (u *lineUsecase) EnqueueEvent(ctx context.Context, userID string, payload []byte) error {
+	return u.qrp.Enqueue(ctx, userID, payload)
+}
 
+func (u *lineUsecase) StartRestTimer(ctx context.Context, t models.RestTimer) (*models.RestTimer, error) {
+	if t.LineUserID == "" {
+		return nil, errors.New("lineUserID is required")
+	}
+	if t.RestSec < 1 || t.RestSec > models.MaxRestSec {
+		return nil, fmt.Errorf("restSec must be between 1 and %d", models.MaxRestSec)
+	}
+	t.DueAt = time.Now().Add(time.Duration(t.RestSec) * time.Second).UTC()
+	if err := u.trp.Schedule(ctx, t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
 
+func (u *lineUsecase) CancelRestTimer(ctx context.Context, lineUserID string) (bool, error) {
+	return u.trp.Cancel(ctx, lineUserID)
+}
 
+func (u *lineUsecase) DueRestTimers(ctx context.Context, now time.Time) ([]models.RestTimer, error) {
+	return u.trp.ClaimDue(ctx, now, restTimerBatch)
+}
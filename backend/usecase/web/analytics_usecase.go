@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+// ダッシュボードのグラフ用の集計
+type AnalyticsUsecase interface {
+	// ExerciseProgress は種目のワークアウトごとの推移。formula が空なら自己ベストと同じ式（models.RecordFormula）
+	ExerciseProgress(ctx context.Context, userID, exerciseID string, f ProgressFilter) (*models.ExerciseProgress, error)
+	// MuscleVolume は week の日付を含む週まで weeks 週分の部位ごとのセット数と挙上量。
+	// 週の区切りはユーザーの設定（タイムゾーン・週の始まり）に従う。week は年月日だけを見る。nil なら今週
+	MuscleVolume(ctx context.Context, userID string, week *time.Time, weeks int) (*models.MuscleVolumeReport, error)
+}
+
+// MaxMuscleVolumeWeeks は MuscleVolume で一度に見られる週数
+const MaxMuscleVolumeWeeks = 26
+
+type ProgressFilter struct {
+	From    *time.Time
+	To      *time.Time
+	Formula models.E1RMFormula
+}
+
+type analyticsUsecase struct {
+	repo         repository.AnalyticsRepository
+	exerciseRepo repository.ExerciseRepository
+	settings     UserSettingsUsecase
+}
+
+func NewAnalyticsUsecase(repo repository.AnalyticsRepository, exerciseRepo repository.ExerciseRepository, settings UserSettingsUsecase) AnalyticsUsecase {
+	return &analyticsUsecase{repo: repo, exerciseRepo: exerciseRepo, settings: settings}
+}
+
+func (u *analyticsUsecase) ExerciseProgress(ctx context.Context, userID, exerciseID string, f ProgressFilter) (*models.ExerciseProgress, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	ex, err := u.exerciseRepo.FindByID(ctx, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	if ex == nil || (ex.OwnerUserID != nil && *ex.OwnerUserID != userID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if f.Formula == "" {
+		f.Formula = models.RecordFormula
+	}
+
+	points, err := u.repo.ExerciseProgress(ctx, userID, exerciseID, repository.ProgressQuery{
+		From: f.From, To: f.To, Formula: f.Formula,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if points == nil {
+		points = []models.ProgressPoint{}
+	}
+	return &models.ExerciseProgress{
+		ExerciseID: exerciseID,
+		Formula:    f.Formula,
+		From:       f.From,
+		To:         f.To,
+		Points:     points,
+	}, nil
+}
+
+func (u *analyticsUsecase) MuscleVolume(ctx context.Context, userID string, week *time.Time, weeks int) (*models.MuscleVolumeReport, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	if weeks < 1 || weeks > MaxMuscleVolumeWeeks {
+		return nil, fmt.Errorf("weeks must be between 1 and %d", MaxMuscleVolumeWeeks)
+	}
+	settings, err := u.settings.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	loc := settings.Location()
+	day := startOfDay(time.Now().In(loc))
+	if week != nil {
+		day = time.Date(week.Year(), week.Month(), week.Day(), 0, 0, 0, 0, loc)
+	}
+	last := startOfWeek(day, settings.WeekStartDay())
+	from := last.AddDate(0, 0, -7*(weeks-1))
+	to := last.AddDate(0, 0, 7)
+
+	rows, err := u.repo.MuscleVolume(ctx, userID, loc.String(), settings.WeekStartDay(), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &models.MuscleVolumeReport{From: from, To: to, Weeks: make([]models.WeeklyMuscleVolume, weeks)}
+	byWeek := make(map[string]*models.WeeklyMuscleVolume, weeks)
+	for i := range out.Weeks {
+		wk := &out.Weeks[i]
+		wk.WeekStart = from.AddDate(0, 0, 7*i)
+		byWeek[wk.WeekStart.Format("2006-01-02")] = wk
+		wk.Muscles = make([]models.MuscleVolume, len(models.MuscleGroups))
+		for j, g := range models.MuscleGroups {
+			wk.Muscles[j] = models.MuscleVolume{Muscle: g.Key, Name: g.Name}
+		}
+	}
+	for _, r := range rows {
+		wk, ok := byWeek[r.WeekStart.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		if r.Muscle == nil {
+			wk.UnassignedSets += int(r.HardSets)
+			continue
+		}
+		for j := range wk.Muscles {
+			if wk.Muscles[j].Muscle == *r.Muscle {
+				wk.Muscles[j].HardSets = r.HardSets
+				wk.Muscles[j].TonnageKg = r.TonnageKg
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// startOfWeek は t を含む週の始まりの日（weekStart 曜日）の 0 時
+func startOfWeek(t time.Time, weekStart time.Weekday) time.Time {
+	d := startOfDay(t)
+	return d.AddDate(0, 0, -((int(d.Weekday()) - int(weekStart) + 7) % 7))
+}
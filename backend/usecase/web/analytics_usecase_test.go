@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+type fixedSettings struct {
+	UserSettingsUsecase
+	settings models.UserSettings
+}
+
+func (s fixedSettings) Get(ctx context.Context, userID string) (*models.UserSettings, error) {
+	return &s.settings, nil
+}
+
+// weekRepo は SQL と同じく、週の始まりの日付（date なので UTC の 0 時）で行を返す
+type weekRepo struct {
+	repository.AnalyticsRepository
+	tz        string
+	weekStart time.Weekday
+	rows      []repository.MuscleVolumeRow
+}
+
+func (r *weekRepo) MuscleVolume(ctx context.Context, userID, tz string, weekStart time.Weekday, from, to time.Time) ([]repository.MuscleVolumeRow, error) {
+	r.tz, r.weekStart = tz, weekStart
+	return r.rows, nil
+}
+
+func TestMuscleVolumeUsesUserWeeks(t *testing.T) {
+	chest := models.MuscleChest
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	repo := &weekRepo{rows: []repository.MuscleVolumeRow{
+		// 日曜始まり・ニューヨーク時間の週。3/8 は夏時間が始まる週
+		{WeekStart: day(2026, time.March, 1), Muscle: &chest, HardSets: 3},
+		{WeekStart: day(2026, time.March, 8), Muscle: &chest, HardSets: 5},
+		{WeekStart: day(2026, time.March, 8), HardSets: 2},
+		// 範囲外の週は入れない
+		{WeekStart: day(2026, time.February, 22), Muscle: &chest, HardSets: 9},
+	}}
+	settings := fixedSettings{settings: models.UserSettings{Timezone: "America/New_York", WeekStart: int(time.Sunday)}}
+	uc := NewAnalyticsUsecase(repo, nil, settings)
+
+	week := day(2026, time.March, 10)
+	out, err := uc.MuscleVolume(context.Background(), txUserID, &week, 2)
+	if err != nil {
+		t.Fatalf("MuscleVolume: %v", err)
+	}
+	if repo.tz != "America/New_York" || repo.weekStart != time.Sunday {
+		t.Errorf("repo got tz = %q, weekStart = %v", repo.tz, repo.weekStart)
+	}
+	want := []struct {
+		start      string
+		chest      float64
+		unassigned int
+	}{
+		{start: "2026-03-01", chest: 3},
+		{start: "2026-03-08", chest: 5, unassigned: 2},
+	}
+	if len(out.Weeks) != len(want) {
+		t.Fatalf("weeks = %d, want %d", len(out.Weeks), len(want))
+	}
+	for i, w := range want {
+		wk := out.Weeks[i]
+		if got := wk.WeekStart.Format("2006-01-02"); got != w.start || wk.WeekStart.Location().String() != "America/New_York" {
+			t.Errorf("week %d start = %v, want %s in America/New_York", i, wk.WeekStart, w.start)
+		}
+		if wk.Muscles[0].Muscle != chest || wk.Muscles[0].HardSets != w.chest || wk.UnassignedSets != w.unassigned {
+			t.Errorf("week %d = %+v, want chest %v, unassigned %d", i, wk, w.chest, w.unassigned)
+		}
+	}
+}
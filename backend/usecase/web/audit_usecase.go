@@ -0,0 +1,219 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+// 変更履歴（監査ログ）
+type AuditUsecase interface {
+	// Record は userID のデータの変更を追記する。中身が変わっていない更新は記録しない。
+	// 変更と同じトランザクションの中で呼ぶ（記録に失敗したら変更も取り消す）
+	Record(ctx context.Context, userID string, entries ...AuditEntry) error
+	// WorkoutHistory はワークアウトとそのセットの変更履歴（新しい順）
+	WorkoutHistory(ctx context.Context, userID, workoutID string, in AuditListInput) (AuditListOutput, error)
+	// Activity はユーザーのデータの変更履歴（新しい順）
+	Activity(ctx context.Context, userID string, in AuditListInput) (AuditListOutput, error)
+}
+
+// AuditEntry は記録する変更 1 件。Before / After は変更前・変更後のモデル（作成なら Before、削除なら After が nil）
+type AuditEntry struct {
+	Entity    models.AuditEntity
+	EntityID  string
+	WorkoutID *string
+	Action    models.AuditAction
+	Before    any
+	After     any
+}
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+)
+
+type AuditListInput struct {
+	EntityType string // 空ならすべて
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+type AuditListOutput struct {
+	Items  []models.AuditLog `json:"items"`
+	Total  int64             `json:"total"`
+	Limit  int               `json:"limit"`
+	Offset int               `json:"offset"`
+}
+
+// auditOriginKey は ctx に載せる変更の経路
+type auditOriginKey struct{}
+
+type auditOrigin struct {
+	source    models.AuditSource
+	requestID string
+}
+
+// WithAuditSource は ctx に変更の経路とリクエスト ID を載せる（Web はミドルウェア、LINE はイベントごと、ジョブは実行ごと）
+func WithAuditSource(ctx context.Context, source models.AuditSource, requestID string) context.Context {
+	return context.WithValue(ctx, auditOriginKey{}, auditOrigin{source: source, requestID: requestID})
+}
+
+func auditOriginFrom(ctx context.Context) auditOrigin {
+	if o, ok := ctx.Value(auditOriginKey{}).(auditOrigin); ok {
+		return o
+	}
+	return auditOrigin{source: models.AuditSourceUnknown}
+}
+
+type auditUsecase struct {
+	repo        repository.AuditRepository
+	workoutRepo repository.WorkoutRepository
+}
+
+func NewAuditUsecase(repo repository.AuditRepository, workoutRepo repository.WorkoutRepository) AuditUsecase {
+	return &auditUsecase{repo: repo, workoutRepo: workoutRepo}
+}
+
+func (u *auditUsecase) Record(ctx context.Context, userID string, entries ...AuditEntry) error {
+	origin := auditOriginFrom(ctx)
+	var actor *string
+	if origin.source != models.AuditSourceJob {
+		actor = &userID
+	}
+	now := time.Now()
+	logs := make([]models.AuditLog, 0, len(entries))
+	for _, e := range entries {
+		changes, err := models.DiffForAudit(e.Before, e.After)
+		if err != nil {
+			return err
+		}
+		if e.Action == models.AuditUpdate && len(changes) == 0 {
+			continue
+		}
+		logs = append(logs, models.AuditLog{
+			UserID:      userID,
+			ActorUserID: actor,
+			Source:      origin.source,
+			RequestID:   origin.requestID,
+			EntityType:  e.Entity,
+			EntityID:    e.EntityID,
+			WorkoutID:   e.WorkoutID,
+			Action:      e.Action,
+			Changes:     changes,
+			CreatedAt:   now,
+		})
+	}
+	return u.repo.Create(ctx, logs)
+}
+
+func (u *auditUsecase) WorkoutHistory(ctx context.Context, userID, workoutID string, in AuditListInput) (AuditListOutput, error) {
+	if err := ensureUserID(userID); err != nil {
+		return AuditListOutput{}, err
+	}
+	// ゴミ箱のワークアウトの履歴も見られるように、持ち主は履歴の user_id で確かめる
+	out, err := u.list(ctx, userID, &workoutID, in)
+	if err != nil {
+		return AuditListOutput{}, err
+	}
+	if out.Total == 0 {
+		w, err := u.workoutRepo.FindByIDAndUser(ctx, workoutID, userID)
+		if err != nil {
+			return AuditListOutput{}, err
+		}
+		if w == nil {
+			return AuditListOutput{}, gorm.ErrRecordNotFound
+		}
+	}
+	return out, nil
+}
+
+func (u *auditUsecase) Activity(ctx context.Context, userID string, in AuditListInput) (AuditListOutput, error) {
+	if err := ensureUserID(userID); err != nil {
+		return AuditListOutput{}, err
+	}
+	return u.list(ctx, userID, nil, in)
+}
+
+func (u *auditUsecase) list(ctx context.Context, userID string, workoutID *string, in AuditListInput) (AuditListOutput, error) {
+	f := repository.AuditListFilter{WorkoutID: workoutID, From: in.From, To: in.To, Limit: in.Limit, Offset: in.Offset}
+	if in.EntityType != "" {
+		t := models.AuditEntity(in.EntityType)
+		if !t.Valid() {
+			return AuditListOutput{}, errors.New("entityType must be one of workout, workout_set, exercise, body_metric, template")
+		}
+		f.EntityType = &t
+	}
+	if f.Limit <= 0 {
+		f.Limit = defaultAuditLimit
+	}
+	if f.Limit > maxAuditLimit {
+		f.Limit = maxAuditLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	items, total, err := u.repo.ListByUser(ctx, userID, f)
+	if err != nil {
+		return AuditListOutput{}, err
+	}
+	return AuditListOutput{Items: items, Total: total, Limit: f.Limit, Offset: f.Offset}, nil
+}
+
+// 記録する変更を作るヘルパー ---------------------------------------------------
+
+func workoutAudit(action models.AuditAction, before, after *models.Workout) AuditEntry {
+	e := AuditEntry{Entity: models.AuditWorkout, Action: action, Before: before, After: after}
+	if w := pick(before, after); w != nil {
+		id := w.ID
+		e.EntityID, e.WorkoutID = id, &id
+	}
+	return e
+}
+
+func setAudit(action models.AuditAction, before, after *models.WorkoutSet) AuditEntry {
+	e := AuditEntry{Entity: models.AuditSet, Action: action, Before: before, After: after}
+	if s := pick(before, after); s != nil {
+		workoutID := s.WorkoutID
+		e.EntityID, e.WorkoutID = s.ID, &workoutID
+	}
+	return e
+}
+
+func exerciseAudit(action models.AuditAction, before, after *models.Exercise) AuditEntry {
+	e := AuditEntry{Entity: models.AuditExercise, Action: action, Before: before, After: after}
+	if ex := pick(before, after); ex != nil {
+		e.EntityID = ex.ID
+	}
+	return e
+}
+
+func bodyMetricAudit(action models.AuditAction, before, after *models.BodyMetric) AuditEntry {
+	e := AuditEntry{Entity: models.AuditBodyMetric, Action: action, Before: before, After: after}
+	if m := pick(before, after); m != nil {
+		e.EntityID = m.ID
+	}
+	return e
+}
+
+func templateAudit(action models.AuditAction, before, after *models.WorkoutTemplate) AuditEntry {
+	e := AuditEntry{Entity: models.AuditTemplate, Action: action, Before: before, After: after}
+	if t := pick(before, after); t != nil {
+		e.EntityID = t.ID
+	}
+	return e
+}
+
+// pick は after があれば after、無ければ before
+func pick[T any](before, after *T) *T {
+	if after != nil {
+		return after
+	}
+	return before
+}
@@ -12,8 +12,14 @@ import (
 type BodyMetricUsecase interface {
 	List(ctx context.Context, userID string, in BodyMetricListInput) (BodyMetricListOutput, error)
 	Create(ctx context.Context, userID string, in CreateBodyMetricInput) (*models.BodyMetric, error)
-	Update(ctx context.Context, userID, id string, in UpdateBodyMetricInput) (*models.BodyMetric, error)
-	Delete(ctx context.Context, userID, id string) error
+	// 自分の記録（無ければ gorm.ErrRecordNotFound）
+	Get(ctx context.Context, userID, id string) (*models.BodyMetric, error)
+	// version は読んだときの版（If-Match。nil なら確かめない）。違えば repository.ErrVersionConflict
+	Update(ctx context.Context, userID, id string, version *int, in UpdateBodyMetricInput) (*models.BodyMetric, error)
+	// ゴミ箱に入れる。version は Update と同じ
+	Delete(ctx context.Context, userID, id string, version *int) error
+	// ゴミ箱から戻す
+	Restore(ctx context.Context, userID, id string) (*models.BodyMetric, error)
 }
 
 type BodyMetricListInput struct {
@@ -31,25 +37,31 @@ type BodyMetricListOutput struct {
 }
 
 type CreateBodyMetricInput struct {
-	MeasuredAt time.Time `json:"measuredAt"`
-	WeightKg   float32   `json:"weightKg"`
-	BodyFatPct *float32  `json:"bodyFatPct,omitempty"`
-	Note       *string   `json:"note,omitempty"`
+	MeasuredAt time.Time         `json:"measuredAt"`
+	WeightKg   float32           `json:"weightKg"`
+	Weight     *float32          `json:"weight,omitempty"`     // weightUnit での体重。weightKg より優先
+	WeightUnit models.WeightUnit `json:"weightUnit,omitempty"` // 省略時はユーザー設定の単位（コントローラーで入れる）
+	BodyFatPct *float32          `json:"bodyFatPct,omitempty"`
+	Note       *string           `json:"note,omitempty"`
 }
 
 type UpdateBodyMetricInput struct {
-	MeasuredAt *time.Time `json:"measuredAt,omitempty"`
-	WeightKg   *float32   `json:"weightKg,omitempty"`
-	BodyFatPct *float32   `json:"bodyFatPct,omitempty"`
-	Note       *string    `json:"note,omitempty"`
+	MeasuredAt *time.Time        `json:"measuredAt,omitempty"`
+	WeightKg   *float32          `json:"weightKg,omitempty"`
+	Weight     *float32          `json:"weight,omitempty"`
+	WeightUnit models.WeightUnit `json:"weightUnit,omitempty"`
+	BodyFatPct *float32          `json:"bodyFatPct,omitempty"`
+	Note       *string           `json:"note,omitempty"`
 }
 
 type bodyMetricUsecase struct {
-	repo repository.BodyMetricRepository
+	tx    repository.Transactor
+	repo  repository.BodyMetricRepository
+	audit AuditUsecase
 }
 
-func NewBodyMetricUsecase(repo repository.BodyMetricRepository) BodyMetricUsecase {
-	return &bodyMetricUsecase{repo: repo}
+func NewBodyMetricUsecase(tx repository.Transactor, repo repository.BodyMetricRepository, audit AuditUsecase) BodyMetricUsecase {
+	return &bodyMetricUsecase{tx: tx, repo: repo, audit: audit}
 }
 
 func (u *bodyMetricUsecase) List(ctx context.Context, userID string, in BodyMetricListInput) (BodyMetricListOutput, error) {
@@ -72,34 +84,120 @@ func (u *bodyMetricUsecase) List(ctx context.Context, userID string, in BodyMetr
 }
 
 func (u *bodyMetricUsecase) Create(ctx context.Context, userID string, in CreateBodyMetricInput) (*models.BodyMetric, error) {
-	if in.WeightKg <= 0 {
-		return nil, errors.New("weightKg must be > 0")
+	weight, unit, err := bodyWeightInput(&in.WeightKg, in.Weight, in.WeightUnit)
+	if err != nil {
+		return nil, err
 	}
 	m := &models.BodyMetric{
 		UserID:     userID,
 		MeasuredAt: in.MeasuredAt,
-		WeightKg:   in.WeightKg,
 		BodyFatPct: in.BodyFatPct,
 		Note:       in.Note,
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
 	}
-	if err := u.repo.Create(ctx, m); err != nil {
+	m.SetWeight(weight, unit)
+	err = u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.repo.Create(ctx, m); err != nil {
+			return err
+		}
+		return u.audit.Record(ctx, userID, bodyMetricAudit(models.AuditCreate, nil, m))
+	})
+	if err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
-func (u *bodyMetricUsecase) Update(ctx context.Context, userID, id string, in UpdateBodyMetricInput) (*models.BodyMetric, error) {
+func (u *bodyMetricUsecase) Get(ctx context.Context, userID, id string) (*models.BodyMetric, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	return u.repo.FindOwned(ctx, userID, id)
+}
+
+func (u *bodyMetricUsecase) Update(ctx context.Context, userID, id string, version *int, in UpdateBodyMetricInput) (*models.BodyMetric, error) {
 	upd := repository.UpdateBodyMetricFields{
 		MeasuredAt: in.MeasuredAt,
-		WeightKg:   in.WeightKg,
 		BodyFatPct: in.BodyFatPct,
 		Note:       in.Note,
 	}
-	return u.repo.UpdateOwned(ctx, userID, id, upd)
+	if in.WeightKg != nil || in.Weight != nil {
+		weight, unit, err := bodyWeightInput(in.WeightKg, in.Weight, in.WeightUnit)
+		if err != nil {
+			return nil, err
+		}
+		var m models.BodyMetric
+		m.SetWeight(weight, unit)
+		upd.WeightKg, upd.WeightValue, upd.WeightUnit = &m.WeightKg, m.WeightValue, &m.WeightUnit
+	}
+	var m *models.BodyMetric
+	err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+		before, err := u.repo.FindOwned(ctx, userID, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(version, before.Version); err != nil {
+			return err
+		}
+		if m, err = u.repo.UpdateOwned(ctx, userID, id, before.Version, upd); err != nil {
+			return err
+		}
+		return u.audit.Record(ctx, userID, bodyMetricAudit(models.AuditUpdate, before, m))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (u *bodyMetricUsecase) Delete(ctx context.Context, userID, id string, version *int) error {
+	return u.tx.Transaction(ctx, func(ctx context.Context) error {
+		before, err := u.repo.FindOwned(ctx, userID, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(version, before.Version); err != nil {
+			return err
+		}
+		if err := u.repo.DeleteOwned(ctx, userID, id, before.Version); err != nil {
+			return err
+		}
+		return u.audit.Record(ctx, userID, bodyMetricAudit(models.AuditDelete, before, nil))
+	})
+}
+
+func (u *bodyMetricUsecase) Restore(ctx context.Context, userID, id string) (*models.BodyMetric, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	var m *models.BodyMetric
+	err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		if m, err = u.repo.RestoreOwned(ctx, userID, id); err != nil {
+			return err
+		}
+		return u.audit.Record(ctx, userID, bodyMetricAudit(models.AuditRestore, nil, m))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-func (u *bodyMetricUsecase) Delete(ctx context.Context, userID, id string) error {
-	return u.repo.DeleteOwned(ctx, userID, id)
-}
\ No newline at end of file
+// bodyWeightInput は weight + weightUnit（無ければ weightKg）を入力された体重と単位にする
+func bodyWeightInput(weightKg, weight *float32, unit models.WeightUnit) (float32, models.WeightUnit, error) {
+	if weight == nil {
+		if weightKg == nil || *weightKg <= 0 {
+			return 0, "", errors.New("weightKg must be > 0")
+		}
+		return *weightKg, models.WeightUnitKg, nil
+	}
+	if !unit.Valid() {
+		return 0, "", errors.New("weightUnit must be kg or lb")
+	}
+	if *weight <= 0 {
+		return 0, "", errors.New("weight must be > 0")
+	}
+	return *weight, unit, nil
+}
@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+// トレーニングカレンダー（日ごとの集計・連続日数・週の目標の達成度）
+type CalendarUsecase interface {
+	Month(ctx context.Context, userID string, q CalendarQuery, now time.Time) (*models.Calendar, error)
+}
+
+// CalendarQuery の範囲チェックはコントローラーで行う
+type CalendarQuery struct {
+	Year       int    // 0 なら Timezone での今年
+	Month      int    // 1〜12。0 なら Timezone での今月
+	Timezone   string // IANA 名。空ならユーザーの設定
+	TargetDays int    // 週に何日トレーニングするか。0 なら DefaultWeeklyTargetDays
+}
+
+const DefaultWeeklyTargetDays = 3
+
+type calendarUsecase struct {
+	workoutRepo repository.WorkoutRepository
+	settings    UserSettingsUsecase
+}
+
+func NewCalendarUsecase(workoutRepo repository.WorkoutRepository, settings UserSettingsUsecase) CalendarUsecase {
+	return &calendarUsecase{workoutRepo: workoutRepo, settings: settings}
+}
+
+func (u *calendarUsecase) Month(ctx context.Context, userID string, q CalendarQuery, now time.Time) (*models.Calendar, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	settings, err := u.settings.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if q.Timezone == "" {
+		q.Timezone = settings.Timezone
+	}
+	loc, err := time.LoadLocation(q.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	if q.Year == 0 {
+		q.Year = now.In(loc).Year()
+	}
+	if q.Month == 0 {
+		q.Month = int(now.In(loc).Month())
+	}
+	if q.TargetDays == 0 {
+		q.TargetDays = DefaultWeeklyTargetDays
+	}
+
+	first := time.Date(q.Year, time.Month(q.Month), 1, 0, 0, 0, 0, loc)
+	next := first.AddDate(0, 1, 0)
+	// 週の達成度のため、月にかかる週の頭から終わりまで集計する
+	weekStart := settings.WeekStartDay()
+	from := startOfWeek(first, weekStart)
+	to := startOfWeek(next.AddDate(0, 0, -1), weekStart).AddDate(0, 0, 7)
+
+	rows, err := u.workoutRepo.DailyStats(ctx, userID, q.Timezone, from, to)
+	if err != nil {
+		return nil, err
+	}
+	byDay := make(map[string]repository.DailyStatRow, len(rows))
+	for _, r := range rows {
+		byDay[r.Day.Format("2006-01-02")] = r
+	}
+
+	out := &models.Calendar{Year: q.Year, Month: q.Month, Timezone: q.Timezone, WeekStart: int(weekStart)}
+	for d := first; d.Before(next); d = d.AddDate(0, 0, 1) {
+		key := d.Format("2006-01-02")
+		day := models.CalendarDay{Date: key, Muscles: []string{}}
+		if r, ok := byDay[key]; ok {
+			day.Workouts = r.Workouts
+			day.Sets = r.Sets
+			day.DurationSec = int(r.DurationSec)
+			day.Muscles = sortMuscles(r.Muscles)
+		}
+		out.Days = append(out.Days, day)
+	}
+
+	out.Adherence = weeklyAdherence(byDay, from, to, q.TargetDays, now)
+
+	latest, longest, err := u.workoutRepo.StreakRuns(ctx, userID, q.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	out.Streak = streakSummary(latest, longest, now.In(loc))
+	return out, nil
+}
+
+// internal helpers ----------------------------------------------------------
+
+// sortMuscles はカンマ区切りの部位を MuscleGroups の順に並べる
+func sortMuscles(csv string) []string {
+	hit := map[string]bool{}
+	for _, m := range strings.Split(csv, ",") {
+		hit[m] = true
+	}
+	out := []string{}
+	for _, g := range models.MuscleGroups {
+		if hit[g.Key] {
+			out = append(out, g.Key)
+		}
+	}
+	return out
+}
+
+func weeklyAdherence(byDay map[string]repository.DailyStatRow, from, to time.Time, target int, now time.Time) models.WeeklyAdherence {
+	out := models.WeeklyAdherence{TargetDays: target}
+	counted := 0
+	for ws := from; ws.Before(to); ws = ws.AddDate(0, 0, 7) {
+		we := ws.AddDate(0, 0, 7)
+		w := models.WeekAdherence{WeekStart: ws.Format("2006-01-02"), InProgress: now.Before(we)}
+		for d := ws; d.Before(we); d = d.AddDate(0, 0, 1) {
+			if _, ok := byDay[d.Format("2006-01-02")]; ok {
+				w.TrainingDays++
+			}
+		}
+		w.Met = w.TrainingDays >= target
+		// 終わっていない週は、達成済みのときだけ数える
+		if !w.InProgress || w.Met {
+			counted++
+			if w.Met {
+				out.MetWeeks++
+			}
+		}
+		out.Weeks = append(out.Weeks, w)
+	}
+	if counted > 0 {
+		out.Rate = float64(out.MetWeeks) / float64(counted)
+	}
+	return out
+}
+
+// streakSummary は今日か昨日まで続いている期間を今の連続日数とする
+func streakSummary(latest, longest *repository.StreakRun, now time.Time) models.StreakSummary {
+	var out models.StreakSummary
+	today := startOfDay(now)
+	if latest != nil {
+		end := latest.EndDay.Format("2006-01-02")
+		if end == today.Format("2006-01-02") || end == today.AddDate(0, 0, -1).Format("2006-01-02") {
+			since := latest.StartDay.Format("2006-01-02")
+			out.Current = latest.Days
+			out.CurrentSince = &since
+		}
+	}
+	if longest != nil {
+		from, to := longest.StartDay.Format("2006-01-02"), longest.EndDay.Format("2006-01-02")
+		out.Longest = longest.Days
+		out.LongestFrom = &from
+		out.LongestTo = &to
+	}
+	return out
+}
@@ -3,9 +3,12 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
+	"gorm.io/gorm"
+
 	"github.com/sirasu21/Logbook/backend/models"
 	repository "github.com/sirasu21/Logbook/backend/repository/web"
 )
@@ -15,15 +18,26 @@ type ExerciseUsecase interface {
 	Get(ctx context.Context, userID string, id string) (*models.Exercise, error)
 	Create(ctx context.Context, userID string, in CreateExerciseInput) (*models.Exercise, error)
 	Update(ctx context.Context, userID string, id string, in UpdateExerciseInput) (*models.Exercise, error)
-	Delete(ctx context.Context, userID string, id string) error
+	// セットやテンプレートで使っていれば isActive=false にして返す（過去のセットを残すため）。
+	// 使っていなければゴミ箱に入れて nil を返す
+	Delete(ctx context.Context, userID string, id string) (*models.Exercise, error)
+	// ゴミ箱から戻す
+	Restore(ctx context.Context, userID string, id string) (*models.Exercise, error)
+	// 名前（またはエイリアス）から種目候補を探す。完全一致があればそれだけを返す
+	ResolveByName(ctx context.Context, userID string, names []string) ([]models.Exercise, error)
 }
 
+// errDuplicateExerciseName は同じ名前の種目がすでにあるとき
+var errDuplicateExerciseName = errors.New("exercise name already exists")
+
 type ListExercisesInput struct {
-	Q        string
-	Type     *string // "strength" | "cardio" | "other"
-	OnlyMine bool
-	Limit    int
-	Offset   int
+	Q            string
+	Type         *string // "strength" | "cardio" | "other"
+	OnlyMine     bool
+	ActiveOnly   bool
+	OrderByUsage bool // よく使う・最近使った順（LINE の種目ピッカー用）
+	Limit        int
+	Offset       int
 }
 
 type ExerciseListOutput struct {
@@ -34,33 +48,43 @@ type ExerciseListOutput struct {
 }
 
 type CreateExerciseInput struct {
-	Name          string  `json:"name"`
-	Type          string  `json:"type"`
-	PrimaryMuscle *string `json:"primaryMuscle,omitempty"`
+	Name           string  `json:"name"`
+	Type           string  `json:"type"`
+	PrimaryMuscle  *string `json:"primaryMuscle,omitempty"`
+	DefaultRestSec *int    `json:"defaultRestSec,omitempty"`
+	// 省略時は PrimaryMuscle から推定する
+	Muscles []models.ExerciseMuscleInput `json:"muscles,omitempty"`
 }
 
 type UpdateExerciseInput struct {
-	Name          *string `json:"name,omitempty"`
-	Type          *string `json:"type,omitempty"`
-	PrimaryMuscle *string `json:"primaryMuscle,omitempty"`
-	IsActive      *bool   `json:"isActive,omitempty"`
+	Name           *string `json:"name,omitempty"`
+	Type           *string `json:"type,omitempty"`
+	PrimaryMuscle  *string `json:"primaryMuscle,omitempty"`
+	IsActive       *bool   `json:"isActive,omitempty"`
+	DefaultRestSec *int    `json:"defaultRestSec,omitempty"` // 0 で既定なし
+	// 渡すと丸ごと置き換え。省略して PrimaryMuscle だけ変えたときは推定し直す
+	Muscles *[]models.ExerciseMuscleInput `json:"muscles,omitempty"`
 }
 
 type exerciseUsecase struct {
-	repo repository.ExerciseRepository
+	tx    repository.Transactor
+	repo  repository.ExerciseRepository
+	audit AuditUsecase
 }
 
-func NewExerciseUsecase(repo repository.ExerciseRepository) ExerciseUsecase {
-	return &exerciseUsecase{repo: repo}
+func NewExerciseUsecase(tx repository.Transactor, repo repository.ExerciseRepository, audit AuditUsecase) ExerciseUsecase {
+	return &exerciseUsecase{tx: tx, repo: repo, audit: audit}
 }
 
 func (u *exerciseUsecase) List(ctx context.Context, userID string, in ListExercisesInput) (ExerciseListOutput, error) {
 	f := repository.ListExercisesFilter{
-		Q:        in.Q,
-		Type:     in.Type,
-		OnlyMine: in.OnlyMine,
-		Limit:    in.Limit,
-		Offset:   in.Offset,
+		Q:            in.Q,
+		Type:         in.Type,
+		OnlyMine:     in.OnlyMine,
+		ActiveOnly:   in.ActiveOnly,
+		OrderByUsage: in.OrderByUsage,
+		Limit:        in.Limit,
+		Offset:       in.Offset,
 	}
 	items, total, err := u.repo.List(ctx, userID, f)
 	if err != nil {
@@ -94,33 +118,218 @@ func (u *exerciseUsecase) Create(ctx context.Context, userID string, in CreateEx
 	if in.Type == "" {
 		return nil, errors.New("type is required")
 	}
+	if err := validateRestSec(in.DefaultRestSec, false); err != nil {
+		return nil, err
+	}
+	muscles, err := buildExerciseMuscles(in.Muscles, in.PrimaryMuscle)
+	if err != nil {
+		return nil, err
+	}
 	now := time.Now()
 	ex := &models.Exercise{
 		// ID は DB デフォルト（gen_random_uuid）ならゼロ値でOK
-		OwnerUserID:   &userID,
-		Name:          name,
-		Type:          models.ExerciseType(in.Type),
-		PrimaryMuscle: in.PrimaryMuscle, // nil 可
-		IsActive:      true,
-		CreatedAt:     now,
-		UpdatedAt:     now,
-	}
-	if err := u.repo.Create(ctx, ex); err != nil {
+		OwnerUserID:    &userID,
+		Name:           name,
+		Type:           models.ExerciseType(in.Type),
+		PrimaryMuscle:  in.PrimaryMuscle, // nil 可
+		DefaultRestSec: in.DefaultRestSec,
+		IsActive:       true,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Muscles:        muscles,
+	}
+	err = u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.repo.Create(ctx, ex); err != nil {
+			return err
+		}
+		return u.audit.Record(ctx, userID, exerciseAudit(models.AuditCreate, nil, ex))
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, errDuplicateExerciseName
+	}
+	if err != nil {
 		return nil, err
 	}
 	return ex, nil
 }
 
 func (u *exerciseUsecase) Update(ctx context.Context, userID string, id string, in UpdateExerciseInput) (*models.Exercise, error) {
+	if err := validateRestSec(in.DefaultRestSec, true); err != nil {
+		return nil, err
+	}
+	var muscles []models.ExerciseMuscle
+	replaceMuscles := false
+	switch {
+	case in.Muscles != nil:
+		m, err := buildExerciseMuscles(*in.Muscles, nil)
+		if err != nil {
+			return nil, err
+		}
+		muscles, replaceMuscles = m, true
+	case in.PrimaryMuscle != nil:
+		// 分からない呼び方なら今の部位を残す
+		muscles = models.MusclesFromLabel(*in.PrimaryMuscle)
+		replaceMuscles = muscles != nil
+	}
+
 	upd := repository.UpdateExerciseFields{
-		Name:          in.Name,
-		Type:          in.Type,
-		PrimaryMuscle: in.PrimaryMuscle,
-		IsActive:      in.IsActive,
+		Name:           in.Name,
+		Type:           in.Type,
+		PrimaryMuscle:  in.PrimaryMuscle,
+		IsActive:       in.IsActive,
+		DefaultRestSec: in.DefaultRestSec,
+	}
+	var ex *models.Exercise
+	// 名前などと部位はまとめて更新する
+	err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+		before, err := u.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if ex, err = u.repo.UpdateOwned(ctx, userID, id, upd); err != nil {
+			return err
+		}
+		after := *ex
+		after.Muscles = before.Muscles
+		if replaceMuscles {
+			if err := u.repo.ReplaceMuscles(ctx, ex.ID, muscles); err != nil {
+				return err
+			}
+			ex.Muscles = muscles
+			after.Muscles = muscles
+		}
+		return u.audit.Record(ctx, userID, exerciseAudit(models.AuditUpdate, before, &after))
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, errDuplicateExerciseName
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ex, nil
+}
+
+func (u *exerciseUsecase) Delete(ctx context.Context, userID string, id string) (*models.Exercise, error) {
+	var archived *models.Exercise
+	err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+		before, err := u.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		used, err := u.repo.InUse(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !used {
+			if err := u.repo.DeleteOwned(ctx, userID, id); err != nil {
+				return err
+			}
+			return u.audit.Record(ctx, userID, exerciseAudit(models.AuditDelete, before, nil))
+		}
+		inactive := false
+		if archived, err = u.repo.UpdateOwned(ctx, userID, id, repository.UpdateExerciseFields{IsActive: &inactive}); err != nil {
+			return err
+		}
+		after := *archived
+		after.Muscles = before.Muscles
+		return u.audit.Record(ctx, userID, exerciseAudit(models.AuditUpdate, before, &after))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
+
+func (u *exerciseUsecase) Restore(ctx context.Context, userID string, id string) (*models.Exercise, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	var ex *models.Exercise
+	err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		if ex, err = u.repo.RestoreOwned(ctx, userID, id); err != nil {
+			return err
+		}
+		return u.audit.Record(ctx, userID, exerciseAudit(models.AuditRestore, nil, ex))
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// ゴミ箱に入れている間に同じ名前の種目を作った
+		return nil, errDuplicateExerciseName
+	}
+	if err != nil {
+		return nil, err
 	}
-	return u.repo.UpdateOwned(ctx, userID, id, upd)
+	return ex, nil
 }
 
-func (u *exerciseUsecase) Delete(ctx context.Context, userID string, id string) error {
-	return u.repo.DeleteOwned(ctx, userID, id)
-}
\ No newline at end of file
+func (u *exerciseUsecase) ResolveByName(ctx context.Context, userID string, names []string) ([]models.Exercise, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	items, err := u.repo.FindVisibleByNames(ctx, userID, names)
+	if err != nil {
+		return nil, err
+	}
+	var exact []models.Exercise
+	for _, ex := range items {
+		for _, n := range names {
+			if strings.EqualFold(ex.Name, strings.TrimSpace(n)) {
+				exact = append(exact, ex)
+				break
+			}
+		}
+	}
+	if len(exact) > 0 {
+		return exact, nil
+	}
+	return items, nil
+}
+
+// buildExerciseMuscles は部位の入力を検証する。入力が無ければ primaryMuscle から推定する
+func buildExerciseMuscles(in []models.ExerciseMuscleInput, primaryMuscle *string) ([]models.ExerciseMuscle, error) {
+	if len(in) == 0 {
+		if primaryMuscle == nil {
+			return nil, nil
+		}
+		return models.MusclesFromLabel(*primaryMuscle), nil
+	}
+	seen := map[string]bool{}
+	out := make([]models.ExerciseMuscle, 0, len(in))
+	for i, m := range in {
+		if models.FindMuscleGroup(m.Muscle) == nil {
+			return nil, fmt.Errorf("muscles[%d]: unknown muscle %q", i, m.Muscle)
+		}
+		if seen[m.Muscle] {
+			return nil, fmt.Errorf("muscles[%d]: duplicated muscle %q", i, m.Muscle)
+		}
+		seen[m.Muscle] = true
+
+		contribution := models.DefaultPrimaryContribution
+		switch m.Role {
+		case models.MuscleRolePrimary:
+		case models.MuscleRoleSecondary:
+			contribution = models.DefaultSecondaryContribution
+		default:
+			return nil, fmt.Errorf("muscles[%d]: role must be primary or secondary", i)
+		}
+		if m.Contribution != nil {
+			if *m.Contribution <= 0 || *m.Contribution > 1 {
+				return nil, fmt.Errorf("muscles[%d]: contribution must be greater than 0 and at most 1", i)
+			}
+			contribution = *m.Contribution
+		}
+		out = append(out, models.ExerciseMuscle{Muscle: m.Muscle, Role: m.Role, Contribution: contribution})
+	}
+	return out, nil
+}
+
+// validateRestSec は休憩の長さを確かめる（allowZero なら 0 は「既定なし」）
+func validateRestSec(sec *int, allowZero bool) error {
+	if sec == nil || (allowZero && *sec == 0) {
+		return nil
+	}
+	if *sec < 1 || *sec > models.MaxRestSec {
+		return fmt.Errorf("defaultRestSec must be between 1 and %d", models.MaxRestSec)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+// 自己ベスト（PR）
+type RecordUsecase interface {
+	// Recompute は種目の記録をセットから作り直し、今回新しくできた記録を返す
+	Recompute(ctx context.Context, userID, exerciseID string) ([]models.PersonalRecord, error)
+	// ListByExercise は種目の更新履歴（新しい順）
+	ListByExercise(ctx context.Context, userID, exerciseID string) ([]models.PersonalRecord, error)
+	// ListCurrent は種目・種類ごとの今の記録
+	ListCurrent(ctx context.Context, userID string) ([]models.PersonalRecord, error)
+}
+
+type recordUsecase struct {
+	tx           repository.Transactor
+	repo         repository.PersonalRecordRepository
+	setRepo      repository.WorkoutSetRepository
+	exerciseRepo repository.ExerciseRepository
+}
+
+func NewRecordUsecase(tx repository.Transactor, repo repository.PersonalRecordRepository, setRepo repository.WorkoutSetRepository, exerciseRepo repository.ExerciseRepository) RecordUsecase {
+	return &recordUsecase{tx: tx, repo: repo, setRepo: setRepo, exerciseRepo: exerciseRepo}
+}
+
+func (u *recordUsecase) Recompute(ctx context.Context, userID, exerciseID string) ([]models.PersonalRecord, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	var added []models.PersonalRecord
+	err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.repo.LockExercise(ctx, userID, exerciseID); err != nil {
+			return err
+		}
+		before, err := u.repo.ListByExercise(ctx, userID, exerciseID)
+		if err != nil {
+			return err
+		}
+		sets, err := u.setRepo.ListForRecords(ctx, userID, exerciseID)
+		if err != nil {
+			return err
+		}
+		records := replayRecords(userID, exerciseID, sets, time.Now())
+		if err := u.repo.ReplaceForExercise(ctx, userID, exerciseID, records); err != nil {
+			return err
+		}
+		added = newRecords(before, records)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+func (u *recordUsecase) ListByExercise(ctx context.Context, userID, exerciseID string) ([]models.PersonalRecord, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	ex, err := u.exerciseRepo.FindByID(ctx, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	if ex == nil || (ex.OwnerUserID != nil && *ex.OwnerUserID != userID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return u.repo.ListByExercise(ctx, userID, exerciseID)
+}
+
+func (u *recordUsecase) ListCurrent(ctx context.Context, userID string) ([]models.PersonalRecord, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	return u.repo.ListCurrent(ctx, userID)
+}
+
+// internal helpers ----------------------------------------------------------
+
+// replayRecords はセットを古い順にたどり、ベストを更新するたびに記録を残す
+func replayRecords(userID, exerciseID string, sets []models.WorkoutSet, now time.Time) []models.PersonalRecord {
+	var (
+		out                             []models.PersonalRecord
+		maxWeight, bestE1RM, bestVolume *float64
+		repsAt                          = map[float32]*float64{}
+	)
+	beat := func(best **float64, kind models.RecordKind, value float64, s models.WorkoutSet) {
+		if *best != nil && value <= **best {
+			return
+		}
+		r := models.PersonalRecord{
+			UserID:        userID,
+			ExerciseID:    exerciseID,
+			Kind:          kind,
+			Value:         value,
+			PreviousValue: *best,
+			WorkoutID:     s.WorkoutID,
+			AchievedAt:    s.CreatedAt,
+			CreatedAt:     now,
+		}
+		if kind != models.RecordSessionVolume {
+			id := s.ID
+			r.SetID = &id
+			r.WeightKg = s.WeightKg
+			r.Reps = s.Reps
+		}
+		out = append(out, r)
+		*best = &value
+	}
+
+	var volume float64
+	for i, s := range sets {
+		weight, reps := float64(*s.WeightKg), *s.Reps
+		beat(&maxWeight, models.RecordMaxWeight, roundRecord(weight), s)
+		// ドロップセット・クラスターの続きのセットは回数の記録に使わない
+		if s.CountsAsSet() {
+			best := repsAt[*s.WeightKg]
+			beat(&best, models.RecordRepsAtWeight, float64(reps), s)
+			repsAt[*s.WeightKg] = best
+			if reps <= models.MaxE1RMReps {
+				if e1rm, ok := models.RecordFormula.Estimate(weight, models.E1RMReps(reps, s.RPE)); ok {
+					beat(&bestE1RM, models.RecordE1RM, roundRecord(e1rm), s)
+				}
+			}
+		}
+
+		// ワークアウトの最後のセットで、その回の総挙上量を比べる
+		volume += weight * float64(reps)
+		if i == len(sets)-1 || sets[i+1].WorkoutID != s.WorkoutID {
+			beat(&bestVolume, models.RecordSessionVolume, roundRecord(volume), s)
+			volume = 0
+		}
+	}
+	return out
+}
+
+func roundRecord(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// newRecords は作り直す前になかった記録を返す。
+// セットの記録は値ごと、総挙上量はワークアウトごとに 1 回だけ新しいとみなす
+func newRecords(before, after []models.PersonalRecord) []models.PersonalRecord {
+	seen := make(map[string]bool, len(before))
+	for _, r := range before {
+		seen[recordKey(r)] = true
+	}
+	var out []models.PersonalRecord
+	for _, r := range after {
+		if !seen[recordKey(r)] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func recordKey(r models.PersonalRecord) string {
+	if r.SetID == nil {
+		return string(r.Kind) + "/" + r.WorkoutID
+	}
+	return string(r.Kind) + "/" + *r.SetID + "/" + strconv.FormatFloat(r.Value, 'f', -1, 64)
+}
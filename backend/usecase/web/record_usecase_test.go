@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+func TestReplayRecordsE1RM(t *testing.T) {
+	weight := func(kg float32) *float32 { return &kg }
+	count := func(n int) *int { return &n }
+	at := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+	sets := []models.WorkoutSet{
+		{ID: "s-1", WorkoutID: "w-1", WeightKg: weight(100), Reps: count(5), CreatedAt: at},
+		// 同じ 100kg x 5 でも RPE 8 なら 2 回残っている（7 回で推定する）
+		{ID: "s-2", WorkoutID: "w-1", WeightKg: weight(100), Reps: count(5), RPE: weight(8), CreatedAt: at.Add(time.Minute)},
+		// 12 回を超えるセットは推定 1RM に使わない
+		{ID: "s-3", WorkoutID: "w-2", WeightKg: weight(80), Reps: count(20), CreatedAt: at.Add(24 * time.Hour)},
+		{ID: "s-4", WorkoutID: "w-2", WeightKg: weight(130), Reps: count(1), RPE: weight(10), CreatedAt: at.Add(25 * time.Hour)},
+	}
+
+	var got []models.PersonalRecord
+	for _, r := range replayRecords(txUserID, "e-1", sets, at) {
+		if r.Kind == models.RecordE1RM {
+			got = append(got, r)
+		}
+	}
+	want := []struct {
+		setID string
+		value float64
+	}{
+		{setID: "s-1", value: 116.67},
+		{setID: "s-2", value: 123.33},
+		{setID: "s-4", value: 130},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("e1rm records = %+v, want %d", got, len(want))
+	}
+	bySet := map[string]models.WorkoutSet{}
+	for _, s := range sets {
+		bySet[s.ID] = s
+	}
+	for i, w := range want {
+		if *got[i].SetID != w.setID || got[i].Value != w.value {
+			t.Errorf("record %d = %s %v, want %s %v", i, *got[i].SetID, got[i].Value, w.setID, w.value)
+		}
+		// 進捗グラフ（同じ式）で見た値とも合う
+		s := bySet[w.setID]
+		e1rm, ok := models.RecordFormula.Estimate(float64(*s.WeightKg), models.E1RMReps(*s.Reps, s.RPE))
+		if !ok || roundRecord(e1rm) != w.value {
+			t.Errorf("%s: RecordFormula.Estimate = %v, want %v", w.setID, e1rm, w.value)
+		}
+	}
+}
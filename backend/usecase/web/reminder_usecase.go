@@ -0,0 +1,286 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+type ReminderUsecase interface {
+	// 未設定なら既定値（無効）を返す
+	Get(ctx context.Context, userID string) (*ReminderSettings, error)
+	Save(ctx context.Context, userID string, in SaveReminderInput) (*ReminderSettings, error)
+	Delete(ctx context.Context, userID string) error
+	Snooze(ctx context.Context, userID string, until time.Time) error
+
+	// スケジューラー用。曜日・時刻・日付は各ユーザーのタイムゾーンで判定する
+	DueReminders(ctx context.Context, now time.Time) ([]models.ReminderTarget, error)
+	// InactiveTargets は at（"HH:MM"）になったユーザーのうち、しばらくトレーニングしていない人
+	InactiveTargets(ctx context.Context, now time.Time, at string) ([]InactiveTarget, error)
+	// RecapTargets は週の最後の日の at になったユーザー
+	RecapTargets(ctx context.Context, now time.Time, at string) ([]models.ReminderTarget, error)
+	MarkSent(ctx context.Context, id string, kind ReminderKind, at time.Time) error
+}
+
+type ReminderKind string
+
+const (
+	ReminderKindTraining ReminderKind = "training"
+	ReminderKindInactive ReminderKind = "inactive"
+	ReminderKindRecap    ReminderKind = "recap"
+)
+
+// ReminderSettings は API で返す形（曜日は 0=日曜 … 6=土曜 の配列）
+type ReminderSettings struct {
+	Enabled        bool       `json:"enabled"`
+	Weekdays       []int      `json:"weekdays"`
+	RemindAt       string     `json:"remindAt"`
+	InactiveDays   int        `json:"inactiveDays"`
+	WeeklyRecap    bool       `json:"weeklyRecap"`
+	AutoCloseHours int        `json:"autoCloseHours"` // LINE のワークアウトを自動で終了するまでの時間（0 なら終了しない）
+	SnoozedUntil   *time.Time `json:"snoozedUntil,omitempty"`
+}
+
+// SaveReminderInput は省略した項目を今の設定のままにする
+type SaveReminderInput struct {
+	Enabled        *bool   `json:"enabled,omitempty"`
+	Weekdays       *[]int  `json:"weekdays,omitempty"`
+	RemindAt       *string `json:"remindAt,omitempty"`
+	InactiveDays   *int    `json:"inactiveDays,omitempty"`
+	WeeklyRecap    *bool   `json:"weeklyRecap,omitempty"`
+	AutoCloseHours *int    `json:"autoCloseHours,omitempty"`
+}
+
+type InactiveTarget struct {
+	models.ReminderTarget
+	Days int // 最後のワークアウトから空いた日数
+}
+
+const (
+	maxInactiveDays   = 30
+	maxAutoCloseHours = 72
+)
+
+type reminderUsecase struct {
+	repo repository.ReminderRepository
+}
+
+func NewReminderUsecase(repo repository.ReminderRepository) ReminderUsecase {
+	return &reminderUsecase{repo: repo}
+}
+
+func defaultReminder(userID string) *models.ReminderPreference {
+	return &models.ReminderPreference{
+		UserID:         userID,
+		Enabled:        false,
+		RemindAt:       "19:00",
+		InactiveDays:   3,
+		WeeklyRecap:    true,
+		AutoCloseHours: DefaultAutoCloseHours,
+	}
+}
+
+func (u *reminderUsecase) Get(ctx context.Context, userID string) (*ReminderSettings, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	p, err := u.repo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		p = defaultReminder(userID)
+	}
+	return toReminderSettings(p), nil
+}
+
+func (u *reminderUsecase) Save(ctx context.Context, userID string, in SaveReminderInput) (*ReminderSettings, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	p, err := u.repo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		p = defaultReminder(userID)
+		// 初めて保存するときは有効にする
+		p.Enabled = true
+		p.CreatedAt = time.Now()
+	}
+
+	if in.Enabled != nil {
+		p.Enabled = *in.Enabled
+	}
+	if in.Weekdays != nil {
+		mask, err := weekdayMask(*in.Weekdays)
+		if err != nil {
+			return nil, err
+		}
+		p.Weekdays = mask
+	}
+	if in.RemindAt != nil {
+		if _, err := time.Parse("15:04", *in.RemindAt); err != nil || len(*in.RemindAt) != 5 {
+			return nil, errors.New("remindAt must be HH:MM")
+		}
+		p.RemindAt = *in.RemindAt
+	}
+	if in.InactiveDays != nil {
+		if *in.InactiveDays < 0 || *in.InactiveDays > maxInactiveDays {
+			return nil, errors.New("inactiveDays must be between 0 and 30")
+		}
+		p.InactiveDays = *in.InactiveDays
+	}
+	if in.WeeklyRecap != nil {
+		p.WeeklyRecap = *in.WeeklyRecap
+	}
+	if in.AutoCloseHours != nil {
+		if *in.AutoCloseHours < 0 || *in.AutoCloseHours > maxAutoCloseHours {
+			return nil, errors.New("autoCloseHours must be between 0 and 72")
+		}
+		p.AutoCloseHours = *in.AutoCloseHours
+	}
+	p.UpdatedAt = time.Now()
+
+	if err := u.repo.Upsert(ctx, p); err != nil {
+		return nil, err
+	}
+	return toReminderSettings(p), nil
+}
+
+func (u *reminderUsecase) Delete(ctx context.Context, userID string) error {
+	if err := ensureUserID(userID); err != nil {
+		return err
+	}
+	return u.repo.DeleteByUser(ctx, userID)
+}
+
+func (u *reminderUsecase) Snooze(ctx context.Context, userID string, until time.Time) error {
+	if err := ensureUserID(userID); err != nil {
+		return err
+	}
+	p, err := u.repo.FindByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return errors.New("reminder not configured")
+	}
+	return u.repo.UpdateFields(ctx, p.ID, map[string]any{"snoozed_until": until})
+}
+
+func (u *reminderUsecase) DueReminders(ctx context.Context, now time.Time) ([]models.ReminderTarget, error) {
+	rows, err := u.repo.ListDue(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	out := rows[:0]
+	for _, t := range rows {
+		local := now.In(t.Location())
+		// 今日すでに送った / 今日もうトレーニングした人には送らない
+		if sameDay(t.LastRemindedAt, local) || sameDay(t.LastWorkoutAt, local) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func (u *reminderUsecase) InactiveTargets(ctx context.Context, now time.Time, at string) ([]InactiveTarget, error) {
+	rows, err := u.repo.ListInactiveCandidates(ctx, now, at)
+	if err != nil {
+		return nil, err
+	}
+	var out []InactiveTarget
+	for _, t := range rows {
+		local := now.In(t.Location())
+		base := t.CreatedAt
+		if t.LastWorkoutAt != nil {
+			base = *t.LastWorkoutAt
+		}
+		days := daysBetween(base, local)
+		if days < t.InactiveDays {
+			continue
+		}
+		// 同じ空白期間には InactiveDays ごとに 1 回まで
+		if t.LastNudgedAt != nil && t.LastNudgedAt.After(base) && daysBetween(*t.LastNudgedAt, local) < t.InactiveDays {
+			continue
+		}
+		out = append(out, InactiveTarget{ReminderTarget: t, Days: days})
+	}
+	return out, nil
+}
+
+func (u *reminderUsecase) RecapTargets(ctx context.Context, now time.Time, at string) ([]models.ReminderTarget, error) {
+	rows, err := u.repo.ListRecapTargets(ctx, now, at)
+	if err != nil {
+		return nil, err
+	}
+	out := rows[:0]
+	for _, t := range rows {
+		if t.LastRecapAt != nil && daysBetween(*t.LastRecapAt, now.In(t.Location())) < 6 {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func (u *reminderUsecase) MarkSent(ctx context.Context, id string, kind ReminderKind, at time.Time) error {
+	col := map[ReminderKind]string{
+		ReminderKindTraining: "last_reminded_at",
+		ReminderKindInactive: "last_nudged_at",
+		ReminderKindRecap:    "last_recap_at",
+	}[kind]
+	if col == "" {
+		return errors.New("unknown reminder kind")
+	}
+	return u.repo.UpdateFields(ctx, id, map[string]any{col: at})
+}
+
+func toReminderSettings(p *models.ReminderPreference) *ReminderSettings {
+	days := []int{}
+	for d := 0; d < 7; d++ {
+		if p.Weekdays&(1<<d) != 0 {
+			days = append(days, d)
+		}
+	}
+	return &ReminderSettings{
+		Enabled:        p.Enabled,
+		Weekdays:       days,
+		RemindAt:       p.RemindAt,
+		InactiveDays:   p.InactiveDays,
+		WeeklyRecap:    p.WeeklyRecap,
+		AutoCloseHours: p.AutoCloseHours,
+		SnoozedUntil:   p.SnoozedUntil,
+	}
+}
+
+func weekdayMask(days []int) (int, error) {
+	mask := 0
+	for _, d := range days {
+		if d < 0 || d > 6 {
+			return 0, errors.New("weekdays must be 0 (Sun) to 6 (Sat)")
+		}
+		mask |= 1 << d
+	}
+	return mask, nil
+}
+
+// sameDay は t が now と同じ日（now の Location）か
+func sameDay(t *time.Time, now time.Time) bool {
+	if t == nil {
+		return false
+	}
+	return startOfDay(t.In(now.Location())).Equal(startOfDay(now))
+}
+
+// daysBetween は t の日から now の日まで何日空いたか（now の Location の暦日で数える）
+func daysBetween(t, now time.Time) int {
+	a := startOfDay(t.In(now.Location()))
+	b := startOfDay(now)
+	return int(b.Sub(a).Hours()+12) / 24
+}
@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+type SummaryUsecase interface {
+	// 期間内のワークアウトを種目ごとに集計する（日付の区切りは from/to の Location に従う）
+	Period(ctx context.Context, userID string, from, to time.Time) (*models.PeriodSummary, error)
+	// now の日を含めて何日連続でトレーニングしているか（日付は now の Location に従う）。
+	// 今日まだやっていなければ昨日までの連続日数を返す。
+	Streak(ctx context.Context, userID string, now time.Time) (int, error)
+}
+
+type summaryUsecase struct {
+	workoutRepo  repository.WorkoutRepository
+	exerciseRepo repository.ExerciseRepository
+}
+
+func NewSummaryUsecase(workoutRepo repository.WorkoutRepository, exerciseRepo repository.ExerciseRepository) SummaryUsecase {
+	return &summaryUsecase{workoutRepo: workoutRepo, exerciseRepo: exerciseRepo}
+}
+
+func (u *summaryUsecase) Period(ctx context.Context, userID string, from, to time.Time) (*models.PeriodSummary, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	// ワークアウト数・日数・時間はカレンダーと同じ SQL の日ごとの集計から出す
+	days, err := u.workoutRepo.DailyStats(ctx, userID, from.Location().String(), from, to)
+	if err != nil {
+		return nil, err
+	}
+	out := &models.PeriodSummary{From: from, To: to, Days: len(days)}
+	for _, d := range days {
+		out.Workouts += d.Workouts
+		out.Duration += time.Duration(d.DurationSec * float64(time.Second))
+	}
+
+	sets, err := u.workoutRepo.ListSetsInPeriod(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	index := map[string]int{} // exerciseID → out.Exercises の位置
+	names := map[string]string{}
+	for _, s := range sets {
+		pos, ok := index[s.ExerciseID]
+		if !ok {
+			name, err := u.exerciseName(ctx, names, s.ExerciseID)
+			if err != nil {
+				return nil, err
+			}
+			out.Exercises = append(out.Exercises, models.ExerciseSummary{ExerciseID: s.ExerciseID, Name: name})
+			pos = len(out.Exercises) - 1
+			index[s.ExerciseID] = pos
+		}
+		ex := &out.Exercises[pos]
+		addSetSummary(ex, s)
+		if s.CountsAsSet() {
+			out.Sets++
+		}
+		v := setVolume(s)
+		ex.VolumeKg += v
+		out.VolumeKg += v
+	}
+	return out, nil
+}
+
+func (u *summaryUsecase) Streak(ctx context.Context, userID string, now time.Time) (int, error) {
+	if err := ensureUserID(userID); err != nil {
+		return 0, err
+	}
+	latest, longest, err := u.workoutRepo.StreakRuns(ctx, userID, now.Location().String())
+	if err != nil {
+		return 0, err
+	}
+	return streakSummary(latest, longest, now).Current, nil
+}
+
+func (u *summaryUsecase) exerciseName(ctx context.Context, cache map[string]string, exerciseID string) (string, error) {
+	if name, ok := cache[exerciseID]; ok {
+		return name, nil
+	}
+	ex, err := u.exerciseRepo.FindByID(ctx, exerciseID)
+	if err != nil {
+		return "", err
+	}
+	name := "（削除された種目）"
+	if ex != nil {
+		name = ex.Name
+	}
+	cache[exerciseID] = name
+	return name, nil
+}
+
+// addSetSummary は直前と同じ重量・回数のセットならまとめて数える
+func addSetSummary(ex *models.ExerciseSummary, s models.WorkoutSet) {
+	if n := len(ex.Sets); n > 0 {
+		last := &ex.Sets[n-1]
+		if equalIntPtr(last.Reps, s.Reps) && equalFloat32Ptr(last.WeightKg, s.WeightKg) {
+			last.Count++
+			return
+		}
+	}
+	ex.Sets = append(ex.Sets, models.SetSummary{Reps: s.Reps, WeightKg: s.WeightKg, Count: 1})
+}
+
+// setVolume は 重量 × 回数（ウォームアップは含めない）
+func setVolume(s models.WorkoutSet) float64 {
+	if s.IsWarmup || s.Reps == nil || s.WeightKg == nil {
+		return 0
+	}
+	return float64(*s.WeightKg) * float64(*s.Reps)
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalFloat32Ptr(a, b *float32) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+// statsRepo は SQL の集計結果をそのまま返す（ワークアウトごとにセットを取りにいけば埋め込みの nil で panic する）
+type statsRepo struct {
+	repository.WorkoutRepository
+	days   []repository.DailyStatRow
+	sets   []models.WorkoutSet
+	latest *repository.StreakRun
+	tz     []string
+	calls  int
+}
+
+func (r *statsRepo) DailyStats(ctx context.Context, userID, tz string, from, to time.Time) ([]repository.DailyStatRow, error) {
+	r.tz = append(r.tz, tz)
+	return r.days, nil
+}
+
+func (r *statsRepo) ListSetsInPeriod(ctx context.Context, userID string, from, to time.Time) ([]models.WorkoutSet, error) {
+	r.calls++
+	return r.sets, nil
+}
+
+func (r *statsRepo) StreakRuns(ctx context.Context, userID, tz string) (*repository.StreakRun, *repository.StreakRun, error) {
+	r.tz = append(r.tz, tz)
+	return r.latest, r.latest, nil
+}
+
+func TestSummaryPeriodUsesOneSetQuery(t *testing.T) {
+	weight := func(kg float32) *float32 { return &kg }
+	count := func(n int) *int { return &n }
+	repo := &statsRepo{
+		days: []repository.DailyStatRow{
+			{Workouts: 2, DurationSec: 3600},
+			{Workouts: 1, DurationSec: 1800},
+		},
+		sets: []models.WorkoutSet{
+			{WorkoutID: "w-1", ExerciseID: "e-1", WeightKg: weight(60), Reps: count(8)},
+			{WorkoutID: "w-1", ExerciseID: "e-1", WeightKg: weight(60), Reps: count(8)},
+			{WorkoutID: "w-2", ExerciseID: "e-2", WeightKg: weight(40), Reps: count(10), IsWarmup: true},
+			{WorkoutID: "w-3", ExerciseID: "e-1", WeightKg: weight(65), Reps: count(5)},
+		},
+	}
+	uc := NewSummaryUsecase(repo, memExerciseRepo{})
+	loc, _ := time.LoadLocation("America/New_York")
+	from := time.Date(2026, time.March, 2, 0, 0, 0, 0, loc)
+
+	out, err := uc.Period(context.Background(), txUserID, from, from.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("Period: %v", err)
+	}
+	if repo.calls != 1 || len(repo.tz) != 1 || repo.tz[0] != "America/New_York" {
+		t.Errorf("set queries = %d, tz = %v", repo.calls, repo.tz)
+	}
+	if out.Workouts != 3 || out.Days != 2 || out.Duration != 90*time.Minute {
+		t.Errorf("workouts = %d, days = %d, duration = %v", out.Workouts, out.Days, out.Duration)
+	}
+	if out.Sets != 4 || out.VolumeKg != 60*8*2+65*5 {
+		t.Errorf("sets = %d, volume = %v", out.Sets, out.VolumeKg)
+	}
+	if len(out.Exercises) != 2 || out.Exercises[0].ExerciseID != "e-1" || len(out.Exercises[0].Sets) != 2 || out.Exercises[0].Sets[0].Count != 2 {
+		t.Errorf("exercises = %+v", out.Exercises)
+	}
+}
+
+func TestSummaryStreak(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	now := time.Date(2026, time.March, 10, 7, 0, 0, 0, loc)
+	day := func(d int) time.Time { return time.Date(2026, time.March, d, 0, 0, 0, 0, time.UTC) }
+	cases := []struct {
+		name   string
+		latest *repository.StreakRun
+		want   int
+	}{
+		{name: "through today", latest: &repository.StreakRun{StartDay: day(7), EndDay: day(10), Days: 4}, want: 4},
+		{name: "through yesterday", latest: &repository.StreakRun{StartDay: day(8), EndDay: day(9), Days: 2}, want: 2},
+		{name: "broken", latest: &repository.StreakRun{StartDay: day(1), EndDay: day(8), Days: 8}, want: 0},
+		{name: "never trained"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &statsRepo{latest: tc.latest}
+			got, err := NewSummaryUsecase(repo, memExerciseRepo{}).Streak(context.Background(), txUserID, now)
+			if err != nil {
+				t.Fatalf("Streak: %v", err)
+			}
+			if got != tc.want || repo.tz[0] != "Asia/Tokyo" {
+				t.Errorf("streak = %d (tz %v), want %d", got, repo.tz, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+type TemplateUsecase interface {
+	List(ctx context.Context, userID string) ([]models.WorkoutTemplate, error)
+	Get(ctx context.Context, userID, id string) (*models.WorkoutTemplate, error)
+	Create(ctx context.Context, userID string, in models.CreateTemplateInput) (*models.WorkoutTemplate, error)
+	Update(ctx context.Context, userID, id string, in models.UpdateTemplateInput) (*models.WorkoutTemplate, error)
+	Delete(ctx context.Context, userID, id string) error
+	// Start はテンプレートからワークアウトを作り、目標どおりの未実施のセットを並べる
+	Start(ctx context.Context, userID, id string, isFromLine bool) (*models.WorkoutDetail, error)
+}
+
+const (
+	maxTemplateExercises = 30
+	maxTargetSets        = 20
+)
+
+type templateUsecase struct {
+	tx           repository.Transactor
+	repo         repository.TemplateRepository
+	exerciseRepo repository.ExerciseRepository
+	setRepo      repository.WorkoutSetRepository
+	workoutuc    WorkoutUsecase
+	audit        AuditUsecase
+}
+
+func NewTemplateUsecase(tx repository.Transactor, repo repository.TemplateRepository, exerciseRepo repository.ExerciseRepository, setRepo repository.WorkoutSetRepository, workoutuc WorkoutUsecase, audit AuditUsecase) TemplateUsecase {
+	return &templateUsecase{tx: tx, repo: repo, exerciseRepo: exerciseRepo, setRepo: setRepo, workoutuc: workoutuc, audit: audit}
+}
+
+func (u *templateUsecase) List(ctx context.Context, userID string) ([]models.WorkoutTemplate, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	return u.repo.ListByUser(ctx, userID)
+}
+
+func (u *templateUsecase) Get(ctx context.Context, userID, id string) (*models.WorkoutTemplate, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	t, err := u.repo.FindByIDAndUser(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return t, nil
+}
+
+func (u *templateUsecase) Create(ctx context.Context, userID string, in models.CreateTemplateInput) (*models.WorkoutTemplate, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	name, err := templateName(in.Name)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	exercises, err := u.buildTemplateExercises(ctx, userID, in.Exercises, now)
+	if err != nil {
+		return nil, err
+	}
+	t := &models.WorkoutTemplate{
+		UserID:    userID,
+		Name:      name,
+		Note:      trimNote(in.Note),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Exercises: exercises,
+	}
+	err = u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.repo.Create(ctx, t); err != nil {
+			return err
+		}
+		return u.audit.Record(ctx, userID, templateAudit(models.AuditCreate, nil, t))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (u *templateUsecase) Update(ctx context.Context, userID, id string, in models.UpdateTemplateInput) (*models.WorkoutTemplate, error) {
+	t, err := u.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *t
+	if in.Name != nil {
+		if t.Name, err = templateName(*in.Name); err != nil {
+			return nil, err
+		}
+	}
+	if in.Note != nil {
+		t.Note = trimNote(in.Note)
+	}
+	now := time.Now()
+	if in.Exercises != nil {
+		if t.Exercises, err = u.buildTemplateExercises(ctx, userID, *in.Exercises, now); err != nil {
+			return nil, err
+		}
+	}
+	t.UpdatedAt = now
+	err = u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.repo.Update(ctx, t, in.Exercises != nil); err != nil {
+			return err
+		}
+		return u.audit.Record(ctx, userID, templateAudit(models.AuditUpdate, &before, t))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (u *templateUsecase) Delete(ctx context.Context, userID, id string) error {
+	t, err := u.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	return u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.repo.Delete(ctx, id, userID); err != nil {
+			return err
+		}
+		return u.audit.Record(ctx, userID, templateAudit(models.AuditDelete, t, nil))
+	})
+}
+
+func (u *templateUsecase) Start(ctx context.Context, userID, id string, isFromLine bool) (*models.WorkoutDetail, error) {
+	t, err := u.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.Exercises) == 0 {
+		return nil, errors.New("template has no exercises")
+	}
+
+	now := time.Now()
+	name := t.Name
+	var workoutID string
+	// 途中までのワークアウトは残さない
+	err = u.tx.Transaction(ctx, func(ctx context.Context) error {
+		w, err := u.workoutuc.Create(ctx, userID, models.CreateWorkoutInput{StartedAt: now, Note: &name}, isFromLine)
+		if err != nil {
+			return err
+		}
+		workoutID = w.ID
+
+		index := 1
+		for _, te := range t.Exercises {
+			for i := 0; i < te.TargetSets; i++ {
+				ws := &models.WorkoutSet{
+					WorkoutID:  w.ID,
+					ExerciseID: te.ExerciseID,
+					SetIndex:   index,
+					Reps:       te.TargetReps,
+					WeightKg:   te.TargetWeightKg,
+					RPE:        te.TargetRPE,
+					RestSec:    te.RestSec,
+					IsPlanned:  true,
+					CreatedAt:  now,
+					UpdatedAt:  now,
+				}
+				if err := u.setRepo.Create(ctx, ws); err != nil {
+					return err
+				}
+				if err := u.audit.Record(ctx, userID, setAudit(models.AuditCreate, nil, ws)); err != nil {
+					return err
+				}
+				index++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u.workoutuc.GetDetail(ctx, userID, workoutID)
+}
+
+// internal helpers ----------------------------------------------------------
+
+func templateName(s string) (string, error) {
+	name := strings.TrimSpace(s)
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	if len([]rune(name)) > 64 {
+		return "", errors.New("name must be 64 characters or less")
+	}
+	return name, nil
+}
+
+func trimNote(note *string) *string {
+	if note == nil {
+		return nil
+	}
+	s := strings.TrimSpace(*note)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// buildTemplateExercises は入力を検証して並び順どおりの TemplateExercise にする
+func (u *templateUsecase) buildTemplateExercises(ctx context.Context, userID string, in []models.TemplateExerciseInput, now time.Time) ([]models.TemplateExercise, error) {
+	if len(in) == 0 {
+		return nil, errors.New("exercises is required")
+	}
+	if len(in) > maxTemplateExercises {
+		return nil, fmt.Errorf("exercises must be %d or less", maxTemplateExercises)
+	}
+	out := make([]models.TemplateExercise, 0, len(in))
+	for i, e := range in {
+		ex, err := u.exerciseRepo.FindByID(ctx, e.ExerciseID)
+		if err != nil {
+			return nil, err
+		}
+		if ex == nil || (ex.OwnerUserID != nil && *ex.OwnerUserID != userID) {
+			return nil, fmt.Errorf("exercises[%d]: exercise not found", i)
+		}
+		if e.TargetSets < 1 || e.TargetSets > maxTargetSets {
+			return nil, fmt.Errorf("exercises[%d]: targetSets must be between 1 and %d", i, maxTargetSets)
+		}
+		if e.TargetReps != nil && *e.TargetReps < 1 {
+			return nil, fmt.Errorf("exercises[%d]: targetReps must be positive", i)
+		}
+		if e.TargetWeightKg != nil && *e.TargetWeightKg < 0 {
+			return nil, fmt.Errorf("exercises[%d]: targetWeightKg must not be negative", i)
+		}
+		if e.TargetRPE != nil && (*e.TargetRPE < 0 || *e.TargetRPE > 10) {
+			return nil, fmt.Errorf("exercises[%d]: targetRpe must be between 0 and 10", i)
+		}
+		out = append(out, models.TemplateExercise{
+			ExerciseID:     e.ExerciseID,
+			Position:       i,
+			TargetSets:     e.TargetSets,
+			TargetReps:     e.TargetReps,
+			TargetWeightKg: e.TargetWeightKg,
+			TargetRPE:      e.TargetRPE,
+			RestSec:        e.RestSec,
+			Note:           trimNote(e.Note),
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+	return out, nil
+}
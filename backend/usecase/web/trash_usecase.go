@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+// ゴミ箱（消したワークアウト・セット・種目・体重記録）
+type TrashUsecase interface {
+	List(ctx context.Context, userID string, in TrashListInput) (TrashListOutput, error)
+	// Restore は type の id をゴミ箱から戻し、戻したもの（*models.Workout など）を返す
+	Restore(ctx context.Context, userID string, typ models.TrashType, id string) (any, error)
+	// Purge は TrashRetention を過ぎたものを完全に消し、消した件数を返す（スケジューラーから呼ぶ）
+	Purge(ctx context.Context, now time.Time) (int64, error)
+}
+
+const (
+	defaultTrashLimit = 50
+	maxTrashLimit     = 200
+)
+
+type TrashListInput struct {
+	Type   string // カンマ区切りで複数可。空ならすべて
+	Limit  int
+	Offset int
+}
+
+type TrashListOutput struct {
+	Items  []models.TrashItem `json:"items"`
+	Total  int64              `json:"total"`
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+}
+
+type trashUsecase struct {
+	tx          repository.Transactor
+	repo        repository.TrashRepository
+	workouts    WorkoutUsecase
+	sets        WorkoutSetUsecase
+	exercises   ExerciseUsecase
+	bodyMetrics BodyMetricUsecase
+	audit       AuditUsecase
+}
+
+func NewTrashUsecase(tx repository.Transactor, repo repository.TrashRepository, workouts WorkoutUsecase, sets WorkoutSetUsecase, exercises ExerciseUsecase, bodyMetrics BodyMetricUsecase, audit AuditUsecase) TrashUsecase {
+	return &trashUsecase{tx: tx, repo: repo, workouts: workouts, sets: sets, exercises: exercises, bodyMetrics: bodyMetrics, audit: audit}
+}
+
+func (u *trashUsecase) List(ctx context.Context, userID string, in TrashListInput) (TrashListOutput, error) {
+	if err := ensureUserID(userID); err != nil {
+		return TrashListOutput{}, err
+	}
+	f := repository.TrashListFilter{Limit: in.Limit, Offset: in.Offset}
+	for _, s := range strings.Split(in.Type, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		t := models.TrashType(s)
+		if !t.Valid() {
+			return TrashListOutput{}, fmt.Errorf("type must be one of workout, set, exercise, body_metric")
+		}
+		f.Types = append(f.Types, t)
+	}
+	if f.Limit <= 0 {
+		f.Limit = defaultTrashLimit
+	}
+	if f.Limit > maxTrashLimit {
+		f.Limit = maxTrashLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	items, total, err := u.repo.List(ctx, userID, f)
+	if err != nil {
+		return TrashListOutput{}, err
+	}
+	return TrashListOutput{Items: items, Total: total, Limit: f.Limit, Offset: f.Offset}, nil
+}
+
+func (u *trashUsecase) Restore(ctx context.Context, userID string, typ models.TrashType, id string) (any, error) {
+	switch typ {
+	case models.TrashWorkout:
+		return u.workouts.Restore(ctx, id, userID)
+	case models.TrashSet:
+		return u.sets.RestoreSet(ctx, userID, id)
+	case models.TrashExercise:
+		return u.exercises.Restore(ctx, userID, id)
+	case models.TrashBodyMetric:
+		return u.bodyMetrics.Restore(ctx, userID, id)
+	}
+	return nil, fmt.Errorf("type must be one of workout, set, exercise, body_metric")
+}
+
+func (u *trashUsecase) Purge(ctx context.Context, now time.Time) (int64, error) {
+	var n int64
+	err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+		rows, err := u.repo.Purge(ctx, now.Add(-models.TrashRetention))
+		if err != nil {
+			return err
+		}
+		n = int64(len(rows))
+		for userID, entries := range purgeAudit(rows) {
+			if err := u.audit.Record(ctx, userID, entries...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+var trashAuditEntity = map[models.TrashType]models.AuditEntity{
+	models.TrashWorkout:    models.AuditWorkout,
+	models.TrashSet:        models.AuditSet,
+	models.TrashExercise:   models.AuditExercise,
+	models.TrashBodyMetric: models.AuditBodyMetric,
+}
+
+// purgeAudit は完全に消したものを持ち主ごとの変更履歴にする。
+// ワークアウトと一緒にゴミ箱に入ったセットは、削除のときと同じくワークアウトの 1 件にまとめる
+func purgeAudit(rows []repository.PurgedRow) map[string][]AuditEntry {
+	workoutDeletedAt := map[string]time.Time{}
+	for _, r := range rows {
+		if r.Type == models.TrashWorkout {
+			workoutDeletedAt[r.ID] = r.DeletedAt
+		}
+	}
+	out := map[string][]AuditEntry{}
+	for _, r := range rows {
+		if r.UserID == "" {
+			continue
+		}
+		if r.Type == models.TrashSet && r.WorkoutID != nil {
+			if at, ok := workoutDeletedAt[*r.WorkoutID]; ok && at.Equal(r.DeletedAt) {
+				continue
+			}
+		}
+		out[r.UserID] = append(out[r.UserID], AuditEntry{
+			Entity:    trashAuditEntity[r.Type],
+			EntityID:  r.ID,
+			WorkoutID: r.WorkoutID,
+			Action:    models.AuditPurge,
+		})
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+// memTrashRepo は memDB のワークアウトとセットだけを完全に消す
+type memTrashRepo struct {
+	repository.TrashRepository
+	db *memDB
+}
+
+func (r memTrashRepo) Purge(ctx context.Context, before time.Time) ([]repository.PurgedRow, error) {
+	if err := r.db.write(ctx, "trash.Purge"); err != nil {
+		return nil, err
+	}
+	var out []repository.PurgedRow
+	for id, s := range r.db.sets {
+		if s.DeletedAt.Valid && s.DeletedAt.Time.Before(before) {
+			w := r.db.workouts[s.WorkoutID]
+			out = append(out, repository.PurgedRow{Type: models.TrashSet, ID: id, UserID: w.UserID, WorkoutID: &s.WorkoutID, DeletedAt: s.DeletedAt.Time})
+			delete(r.db.sets, id)
+		}
+	}
+	for id, w := range r.db.workouts {
+		if w.DeletedAt.Valid && w.DeletedAt.Time.Before(before) {
+			out = append(out, repository.PurgedRow{Type: models.TrashWorkout, ID: id, UserID: w.UserID, WorkoutID: &w.ID, DeletedAt: w.DeletedAt.Time})
+			delete(r.db.workouts, id)
+		}
+	}
+	return out, nil
+}
+
+// seedTrash はワークアウトごと消したものと、別のワークアウトから 1 セットだけ消したものを入れる
+func seedTrash(db *memDB, at time.Time) {
+	seedWorkout(db)
+	gone := gorm.DeletedAt{Time: at, Valid: true}
+	w := db.workouts[txWorkoutID]
+	w.DeletedAt = gone
+	db.workouts[txWorkoutID] = w
+	for id, s := range db.sets {
+		s.DeletedAt = gone
+		db.sets[id] = s
+	}
+	db.workouts["w-2"] = models.Workout{ID: "w-2", UserID: txUserID, Version: 1}
+	db.sets["lone"] = models.WorkoutSet{ID: "lone", WorkoutID: "w-2", SetIndex: 1, Version: 1, DeletedAt: gorm.DeletedAt{Time: at.Add(-time.Hour), Valid: true}}
+}
+
+func TestTrashPurgeRecordsAudit(t *testing.T) {
+	now := time.Now()
+	db := newMemDB()
+	seedTrash(db, now.Add(-models.TrashRetention-time.Hour))
+	uc := NewTrashUsecase(memTx{db}, memTrashRepo{db: db}, nil, nil, nil, nil, memAudit{db: db})
+
+	n, err := uc.Purge(context.Background(), now)
+	if err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("purged = %d, want 4", n)
+	}
+	// ワークアウトと一緒に消えたセットはワークアウトの 1 件にまとめる
+	got := map[string]models.AuditEntity{}
+	for _, e := range db.audits {
+		if e.Action != models.AuditPurge {
+			t.Errorf("action = %q, want purge", e.Action)
+		}
+		got[e.EntityID] = e.Entity
+	}
+	want := map[string]models.AuditEntity{txWorkoutID: models.AuditWorkout, "lone": models.AuditSet}
+	if len(got) != len(want) || got[txWorkoutID] != want[txWorkoutID] || got["lone"] != want["lone"] {
+		t.Errorf("audits = %v, want %v", got, want)
+	}
+	if len(db.outside) != 0 {
+		t.Errorf("writes outside the transaction: %v", db.outside)
+	}
+}
+
+func TestTrashPurgeRollsBackOnAuditFailure(t *testing.T) {
+	now := time.Now()
+	db := newMemDB()
+	seedTrash(db, now.Add(-models.TrashRetention-time.Hour))
+	boom := errors.New("injected failure")
+	db.fail["audit.Record"] = boom
+	uc := NewTrashUsecase(memTx{db}, memTrashRepo{db: db}, nil, nil, nil, nil, memAudit{db: db})
+
+	if _, err := uc.Purge(context.Background(), now); !errors.Is(err, boom) {
+		t.Fatalf("Purge err = %v, want %v", err, boom)
+	}
+	if len(db.workouts) != 2 || len(db.sets) != 3 {
+		t.Errorf("workouts = %d, sets = %d, want 2 and 3 kept", len(db.workouts), len(db.sets))
+	}
+}
@@ -0,0 +1,301 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+// トランザクションの途中で失敗したときに、何も残らないことを確かめる。
+// DB の代わりにメモリ上のテーブルを使い、memTx が失敗したら丸ごと書き戻す。
+// 書き込みはトランザクションの中でしか受け付けない（外で書くとテストが落ちる）
+
+type memTxKey struct{}
+
+type memDB struct {
+	workouts map[string]models.Workout
+	sets     map[string]models.WorkoutSet
+	audits   []AuditEntry
+	seq      int
+	// fail[name] があればその書き込みで失敗させる
+	fail map[string]error
+	// トランザクションの外で書き込んだもの
+	outside []string
+}
+
+func newMemDB() *memDB {
+	return &memDB{workouts: map[string]models.Workout{}, sets: map[string]models.WorkoutSet{}, fail: map[string]error{}}
+}
+
+func (db *memDB) snapshot() memDB {
+	c := *db
+	c.workouts = maps.Clone(db.workouts)
+	c.sets = maps.Clone(db.sets)
+	c.audits = append([]AuditEntry(nil), db.audits...)
+	return c
+}
+
+func (db *memDB) write(ctx context.Context, name string) error {
+	if ctx.Value(memTxKey{}) == nil {
+		db.outside = append(db.outside, name)
+	}
+	return db.fail[name]
+}
+
+func (db *memDB) liveSets(workoutID string) []models.WorkoutSet {
+	var out []models.WorkoutSet
+	for _, s := range db.sets {
+		if s.WorkoutID == workoutID && !s.DeletedAt.Valid {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+type memTx struct{ db *memDB }
+
+func (t memTx) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(memTxKey{}) != nil {
+		return fn(ctx)
+	}
+	snap := t.db.snapshot()
+	if err := fn(context.WithValue(ctx, memTxKey{}, true)); err != nil {
+		fail, outside := t.db.fail, t.db.outside
+		*t.db = snap
+		t.db.fail, t.db.outside = fail, outside
+		return err
+	}
+	return nil
+}
+
+// 使うメソッドだけ実装する（ほかを呼ぶと nil の埋め込みで panic する）
+
+type memWorkoutRepo struct {
+	repository.WorkoutRepository
+	db *memDB
+}
+
+func (r memWorkoutRepo) FindByIDAndUser(ctx context.Context, workoutID, userID string) (*models.Workout, error) {
+	w, ok := r.db.workouts[workoutID]
+	if !ok || w.UserID != userID || w.DeletedAt.Valid {
+		return nil, nil
+	}
+	return &w, nil
+}
+
+func (r memWorkoutRepo) ListSetsByWorkout(ctx context.Context, workoutID string) ([]models.WorkoutSet, error) {
+	return r.db.liveSets(workoutID), nil
+}
+
+func (r memWorkoutRepo) DeleteWorkoutByIDAndUser(ctx context.Context, workoutID, userID string, version int, at time.Time) error {
+	if err := r.db.write(ctx, "DeleteWorkoutByIDAndUser"); err != nil {
+		return err
+	}
+	w := r.db.workouts[workoutID]
+	if w.Version != version {
+		return repository.ErrVersionConflict
+	}
+	w.DeletedAt = gorm.DeletedAt{Time: at, Valid: true}
+	r.db.workouts[workoutID] = w
+	return nil
+}
+
+type memSetRepo struct {
+	repository.WorkoutSetRepository
+	db *memDB
+}
+
+func (r memSetRepo) DeleteByWorkoutID(ctx context.Context, workoutID string, at time.Time) error {
+	if err := r.db.write(ctx, "DeleteByWorkoutID"); err != nil {
+		return err
+	}
+	for _, s := range r.db.liveSets(workoutID) {
+		s.DeletedAt = gorm.DeletedAt{Time: at, Valid: true}
+		r.db.sets[s.ID] = s
+	}
+	return nil
+}
+
+func (r memSetRepo) LockWorkoutSets(ctx context.Context, workoutID string) error { return nil }
+
+func (r memSetRepo) NextSetIndex(ctx context.Context, workoutID string) (int, error) {
+	next := 1
+	for _, s := range r.db.liveSets(workoutID) {
+		next = max(next, s.SetIndex+1)
+	}
+	return next, nil
+}
+
+func (r memSetRepo) ShiftSetIndex(ctx context.Context, workoutID string, from, delta int) error {
+	if err := r.db.write(ctx, "ShiftSetIndex"); err != nil {
+		return err
+	}
+	for _, s := range r.db.liveSets(workoutID) {
+		if s.SetIndex >= from {
+			s.SetIndex += delta
+			r.db.sets[s.ID] = s
+		}
+	}
+	return nil
+}
+
+func (r memSetRepo) Create(ctx context.Context, ws *models.WorkoutSet) error {
+	if err := r.db.write(ctx, "Create"); err != nil {
+		return err
+	}
+	if ws.ID == "" {
+		r.db.seq++
+		ws.ID = fmt.Sprintf("set-%d", r.db.seq)
+	}
+	r.db.sets[ws.ID] = *ws
+	return nil
+}
+
+type memExerciseRepo struct {
+	repository.ExerciseRepository
+}
+
+func (memExerciseRepo) FindByID(ctx context.Context, id string) (*models.Exercise, error) {
+	return &models.Exercise{ID: id}, nil
+}
+
+type memAudit struct {
+	AuditUsecase
+	db *memDB
+}
+
+func (a memAudit) Record(ctx context.Context, userID string, entries ...AuditEntry) error {
+	if err := a.db.write(ctx, "audit.Record"); err != nil {
+		return err
+	}
+	a.db.audits = append(a.db.audits, entries...)
+	return nil
+}
+
+type noRecords struct{ RecordUsecase }
+
+func (noRecords) Recompute(ctx context.Context, userID, exerciseID string) ([]models.PersonalRecord, error) {
+	return nil, nil
+}
+
+// memRecords は自己ベストを作り直す書き込みだけを memDB に記録する（トランザクションの外で呼べばテストが落ちる）
+type memRecords struct {
+	RecordUsecase
+	db *memDB
+}
+
+func (r memRecords) Recompute(ctx context.Context, userID, exerciseID string) ([]models.PersonalRecord, error) {
+	return nil, r.db.write(ctx, "records.Recompute")
+}
+
+const (
+	txUserID    = "u-1"
+	txWorkoutID = "w-1"
+)
+
+// seedWorkout は 2 セットあるワークアウトを入れる
+func seedWorkout(db *memDB) {
+	db.workouts[txWorkoutID] = models.Workout{ID: txWorkoutID, UserID: txUserID, Version: 1}
+	for i := 1; i <= 2; i++ {
+		id := fmt.Sprintf("seed-%d", i)
+		db.sets[id] = models.WorkoutSet{ID: id, WorkoutID: txWorkoutID, ExerciseID: "e-1", SetIndex: i, Version: 1}
+	}
+}
+
+func TestWorkoutDeleteRollsBackOnFailure(t *testing.T) {
+	boom := errors.New("injected failure")
+	cases := []struct {
+		name   string
+		failAt string
+		want   error
+	}{
+		{name: "workout delete fails after sets were trashed", failAt: "DeleteWorkoutByIDAndUser", want: boom},
+		{name: "audit fails after both deletes", failAt: "audit.Record", want: boom},
+		{name: "records recompute fails after the audit", failAt: "records.Recompute", want: boom},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newMemDB()
+			seedWorkout(db)
+			db.fail[tc.failAt] = boom
+			uc := NewWorkoutUsecase(memTx{db}, memWorkoutRepo{db: db}, memSetRepo{db: db}, memRecords{db: db}, memAudit{db: db})
+
+			if err := uc.Delete(context.Background(), txWorkoutID, txUserID, nil); !errors.Is(err, tc.want) {
+				t.Fatalf("Delete err = %v, want %v", err, tc.want)
+			}
+			if w := db.workouts[txWorkoutID]; w.DeletedAt.Valid {
+				t.Errorf("workout was trashed")
+			}
+			if n := len(db.liveSets(txWorkoutID)); n != 2 {
+				t.Errorf("live sets = %d, want 2", n)
+			}
+			if len(db.audits) != 0 {
+				t.Errorf("audits = %+v, want none", db.audits)
+			}
+			if len(db.outside) != 0 {
+				t.Errorf("writes outside the transaction: %v", db.outside)
+			}
+		})
+	}
+}
+
+func TestWorkoutDeleteCommits(t *testing.T) {
+	db := newMemDB()
+	seedWorkout(db)
+	uc := NewWorkoutUsecase(memTx{db}, memWorkoutRepo{db: db}, memSetRepo{db: db}, memRecords{db: db}, memAudit{db: db})
+
+	if err := uc.Delete(context.Background(), txWorkoutID, txUserID, nil); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	w := db.workouts[txWorkoutID]
+	if !w.DeletedAt.Valid {
+		t.Fatal("workout was not trashed")
+	}
+	for _, s := range db.sets {
+		// 戻すときに見分けられるよう、セットはワークアウトと同じ時刻でゴミ箱に入る
+		if !s.DeletedAt.Valid || !s.DeletedAt.Time.Equal(w.DeletedAt.Time) {
+			t.Errorf("set %s deletedAt = %+v, want %v", s.ID, s.DeletedAt, w.DeletedAt.Time)
+		}
+	}
+	if len(db.audits) != 1 || len(db.outside) != 0 {
+		t.Errorf("audits = %d, outside = %v", len(db.audits), db.outside)
+	}
+}
+
+func TestAddSetRollsBackOnFailure(t *testing.T) {
+	boom := errors.New("injected failure")
+	for _, failAt := range []string{"Create", "audit.Record", "records.Recompute"} {
+		t.Run(failAt, func(t *testing.T) {
+			db := newMemDB()
+			seedWorkout(db)
+			db.fail[failAt] = boom
+			uc := NewWorkoutSetUsecase(memTx{db}, memWorkoutRepo{db: db}, memSetRepo{db: db}, memExerciseRepo{}, memRecords{db: db}, memAudit{db: db})
+
+			reps := 8
+			// 先頭に差し込むので、後ろのセットをずらしてから作る
+			in := models.WorkoutSetCreateInput{ExerciseID: "e-1", Reps: &reps, SetIndex: 1}
+			if _, err := uc.AddSet(context.Background(), txUserID, txWorkoutID, in, false); !errors.Is(err, boom) {
+				t.Fatalf("AddSet err = %v, want %v", err, boom)
+			}
+			if len(db.sets) != 2 {
+				t.Errorf("sets = %d, want 2", len(db.sets))
+			}
+			for i := 1; i <= 2; i++ {
+				if s := db.sets[fmt.Sprintf("seed-%d", i)]; s.SetIndex != i {
+					t.Errorf("%s setIndex = %d, want %d (shift was not rolled back)", s.ID, s.SetIndex, i)
+				}
+			}
+			if len(db.audits) != 0 || len(db.outside) != 0 {
+				t.Errorf("audits = %d, outside = %v", len(db.audits), db.outside)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+// タイムゾーン・表示言語・週の始まり・重さの単位
+type UserSettingsUsecase interface {
+	// 未設定なら既定値を返す
+	Get(ctx context.Context, userID string) (*models.UserSettings, error)
+	Save(ctx context.Context, userID string, in SaveUserSettingsInput) (*models.UserSettings, error)
+}
+
+// SaveUserSettingsInput は省略した項目を今の設定のままにする
+type SaveUserSettingsInput struct {
+	Timezone   *string            `json:"timezone,omitempty"`
+	Locale     *string            `json:"locale,omitempty"`
+	WeekStart  *int               `json:"weekStart,omitempty"`
+	WeightUnit *models.WeightUnit `json:"weightUnit,omitempty"`
+}
+
+type userSettingsUsecase struct {
+	repo repository.UserSettingsRepository
+}
+
+func NewUserSettingsUsecase(repo repository.UserSettingsRepository) UserSettingsUsecase {
+	return &userSettingsUsecase{repo: repo}
+}
+
+func (u *userSettingsUsecase) Get(ctx context.Context, userID string) (*models.UserSettings, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	s, err := u.repo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		s = models.DefaultUserSettings(userID)
+	}
+	return s, nil
+}
+
+func (u *userSettingsUsecase) Save(ctx context.Context, userID string, in SaveUserSettingsInput) (*models.UserSettings, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	s, err := u.repo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		s = models.DefaultUserSettings(userID)
+		s.CreatedAt = time.Now()
+	}
+
+	if in.Timezone != nil {
+		// "Local" はサーバーのタイムゾーンになるので受け付けない
+		if _, err := time.LoadLocation(*in.Timezone); err != nil || *in.Timezone == "" || *in.Timezone == "Local" {
+			return nil, errors.New("timezone must be an IANA time zone name (e.g. Asia/Tokyo)")
+		}
+		s.Timezone = *in.Timezone
+	}
+	if in.Locale != nil {
+		if !slices.Contains(models.SupportedLocales, *in.Locale) {
+			return nil, errors.New("locale must be ja-JP or en-US")
+		}
+		s.Locale = *in.Locale
+	}
+	if in.WeekStart != nil {
+		if *in.WeekStart < 0 || *in.WeekStart > 6 {
+			return nil, errors.New("weekStart must be 0 (Sun) to 6 (Sat)")
+		}
+		s.WeekStart = *in.WeekStart
+	}
+	if in.WeightUnit != nil {
+		if !in.WeightUnit.Valid() {
+			return nil, errors.New("weightUnit must be kg or lb")
+		}
+		s.WeightUnit = *in.WeightUnit
+	}
+	s.UpdatedAt = time.Now()
+
+	if err := u.repo.Upsert(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -13,18 +14,36 @@ import (
 
 type WorkoutSetUsecase interface {
 	AddSet(ctx context.Context, userID, workoutID string, in models.WorkoutSetCreateInput, isFromLine bool) (*models.WorkoutSet, error)
-	UpdateSet(ctx context.Context, userID, setID string, in models.WorkoutSetUpdateInput) (*models.WorkoutSet, error)
-	DeleteSet(ctx context.Context, userID, setID string) error
+	// version は読んだときの版（If-Match。nil なら確かめない）。違えば repository.ErrVersionConflict
+	UpdateSet(ctx context.Context, userID, setID string, version *int, in models.WorkoutSetUpdateInput) (*models.WorkoutSet, error)
+	// ゴミ箱に入れる（後ろのセットは詰める）。version は UpdateSet と同じ
+	DeleteSet(ctx context.Context, userID, setID string, version *int) error
+	// ゴミ箱から消す前の位置（set_index・グループ内の順番）に戻す。
+	// ワークアウトもゴミ箱にあるときは戻さずに ErrWorkoutInTrash を返す（先にワークアウトを戻す）
+	RestoreSet(ctx context.Context, userID, setID string) (*models.WorkoutSet, error)
+	// ワークアウトのセットを in の順に並べ直す（exerciseId があれば種目も付け替える）
+	ReorderSets(ctx context.Context, userID, workoutID string, in models.SetOrderInput) (*models.WorkoutDetail, error)
+	GetSet(ctx context.Context, userID, setID string) (*models.WorkoutSet, error)
+	// LINE から登録した最新のセット（取り消し・修正・もう1セット用）。無ければ nil, nil
+	LatestLineSet(ctx context.Context, userID string) (*models.WorkoutSet, error)
 }
 
+// ErrWorkoutInTrash はセットを戻そうとしたら、そのワークアウトもゴミ箱にあったとき
+var ErrWorkoutInTrash = errors.New("the workout is in the trash; restore the workout first")
+
 type workoutSetUsecase struct {
+	// 採番と並べ替えはワークアウトをロックした 1 つのトランザクションで行う
+	tx repository.Transactor
 	wr repository.WorkoutRepository
 	sr repository.WorkoutSetRepository
 	er repository.ExerciseRepository
+	// セットが変わるたびに自己ベストを作り直す
+	records RecordUsecase
+	audit   AuditUsecase
 }
 
-func NewWorkoutSetUsecase(wr repository.WorkoutRepository, sr repository.WorkoutSetRepository, er repository.ExerciseRepository) WorkoutSetUsecase {
-	return &workoutSetUsecase{wr: wr, sr: sr, er: er}
+func NewWorkoutSetUsecase(tx repository.Transactor, wr repository.WorkoutRepository, sr repository.WorkoutSetRepository, er repository.ExerciseRepository, records RecordUsecase, audit AuditUsecase) WorkoutSetUsecase {
+	return &workoutSetUsecase{tx: tx, wr: wr, sr: sr, er: er, records: records, audit: audit}
 }
 
 func (u *workoutSetUsecase) AddSet(ctx context.Context, userID, workoutID string, in models.WorkoutSetCreateInput, isFromLine bool) (*models.WorkoutSet, error) {
@@ -33,22 +52,39 @@ func (u *workoutSetUsecase) AddSet(ctx context.Context, userID, workoutID string
 
 		return nil, err
 	}
-	// 2) 種目存在チェック（外部キーで落とすでもOKだが、UXのため先に確認）
-	if _, err := u.er.FindByID(ctx, in.ExerciseID); err != nil {
-		
+	// 2) 種目存在チェック（ゴミ箱の種目・ほかのユーザーの独自種目には付けない）
+	ex, err := u.er.FindByID(ctx, in.ExerciseID)
+	if err != nil {
+		return nil, err
+	}
+	if ex == nil || (ex.OwnerUserID != nil && *ex.OwnerUserID != userID) {
+		return nil, fmt.Errorf("exercise not found: %w", gorm.ErrRecordNotFound)
+	}
+
+	weight, unit, err := weightInput(in.WeightKg, in.Weight, in.WeightUnit)
+	if err != nil {
 		return nil, err
-		
 	}
+	in.Weight, in.WeightUnit = weight, unit
 
 	now := time.Now()
+	// LINE からの記録は、テンプレートの未実施のセットがあればそれを埋める
+	if isFromLine && !in.IsPlanned {
+		planned, err := u.sr.FindFirstPlanned(ctx, workoutID, in.ExerciseID)
+		if err != nil {
+			return nil, err
+		}
+		if planned != nil {
+			return u.fillPlannedSet(ctx, userID, planned, in, now)
+		}
+	}
 	ws := &models.WorkoutSet{
 		WorkoutID:   workoutID,
 		ExerciseID:  in.ExerciseID,
-		SetIndex:    in.SetIndex, // 0/未指定なら repo 側で自動採番でも可
 		Reps:        in.Reps,
-		WeightKg:    in.WeightKg,
 		RPE:         in.RPE,
 		IsWarmup:    in.IsWarmup,
+		IsPlanned:   in.IsPlanned,
 		RestSec:     in.RestSec,
 		Note:        in.Note,
 		DurationSec: in.DurationSec,
@@ -57,33 +93,272 @@ func (u *workoutSetUsecase) AddSet(ctx context.Context, userID, workoutID string
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
-	if err := u.sr.Create(ctx, ws); err != nil {
+	ws.SetWeight(in.Weight, in.WeightUnit)
+	err = u.tx.Transaction(ctx, func(ctx context.Context) error {
+		group, err := u.placeInGroup(ctx, ws, in.GroupType, in.GroupID, in.GroupOrder)
+		if err != nil {
+			return err
+		}
+		group.apply(ws)
+		if ws.SetIndex, err = u.allocSetIndex(ctx, workoutID, in.SetIndex); err != nil {
+			return err
+		}
+		if err := u.sr.Create(ctx, ws); err != nil {
+			return err
+		}
+		if err := u.audit.Record(ctx, userID, setAudit(models.AuditCreate, nil, ws)); err != nil {
+			return err
+		}
+		if ws.IsPlanned {
+			return nil
+		}
+		return u.recomputeRecords(ctx, userID, ws)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return ws, nil
 }
 
-func (u *workoutSetUsecase) UpdateSet(ctx context.Context, userID, setID string, in models.WorkoutSetUpdateInput) (*models.WorkoutSet, error) {
+func (u *workoutSetUsecase) UpdateSet(ctx context.Context, userID, setID string, version *int, in models.WorkoutSetUpdateInput) (*models.WorkoutSet, error) {
 	ws, err := u.loadSetForUser(ctx, setID, userID)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(version, ws.Version); err != nil {
+		return nil, err
+	}
 
-	applyWorkoutSetPatch(ws, in)
+	before := *ws
+	if err := applyWorkoutSetPatch(ws, in); err != nil {
+		return nil, err
+	}
 	ws.UpdatedAt = time.Now()
-
-	if err := u.sr.Update(ctx, ws); err != nil {
+	err = u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.lockSet(ctx, ws); err != nil {
+			return err
+		}
+		before.SetIndex, before.GroupType, before.GroupID, before.GroupOrder = ws.SetIndex, ws.GroupType, ws.GroupID, ws.GroupOrder
+		prevGroup := ws.GroupID
+		if err := u.applyGroupPatch(ctx, ws, in); err != nil {
+			return err
+		}
+		// グループ内でずらしたときにこのセットも動いていれば version が上がっている
+		if err := u.reloadVersion(ctx, ws); err != nil {
+			return err
+		}
+		if err := u.sr.Update(ctx, ws); err != nil {
+			return err
+		}
+		// 抜けた・動かしたあとの穴を詰める
+		if prevGroup != nil {
+			if err := u.sr.RenumberGroup(ctx, *prevGroup); err != nil {
+				return err
+			}
+			if ws.GroupID != nil && *ws.GroupID == *prevGroup {
+				if cur, err := u.sr.FindByID(ctx, ws.ID); err == nil && cur != nil {
+					ws.GroupOrder = cur.GroupOrder
+				}
+			}
+		}
+		if in.SetIndex != nil {
+			if err := u.moveSet(ctx, ws, *in.SetIndex); err != nil {
+				return err
+			}
+		}
+		// 詰め直し・移動で上がった version を返す（ETag）
+		if err := u.reloadVersion(ctx, ws); err != nil {
+			return err
+		}
+		if err := u.audit.Record(ctx, userID, setAudit(models.AuditUpdate, &before, ws)); err != nil {
+			return err
+		}
+		return u.recomputeRecords(ctx, userID, ws)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return ws, nil
 }
 
-func (u *workoutSetUsecase) DeleteSet(ctx context.Context, userID, setID string) error {
-	if _, err := u.loadSetForUser(ctx, setID, userID); err != nil {
+func (u *workoutSetUsecase) DeleteSet(ctx context.Context, userID, setID string, version *int) error {
+	ws, err := u.loadSetForUser(ctx, setID, userID)
+	if err != nil {
 		return err
 	}
-	// 2) 削除
-	return u.sr.Delete(ctx, setID)
+	if err := checkVersion(version, ws.Version); err != nil {
+		return err
+	}
+	// 2) 削除して、後ろのセットの set_index とグループ内の順番を詰める
+	err = u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.lockSet(ctx, ws); err != nil {
+			return err
+		}
+		if err := u.sr.Delete(ctx, setID, ws.Version); err != nil {
+			return err
+		}
+		if ws.SetIndex > 0 {
+			if err := u.sr.ShiftSetIndex(ctx, ws.WorkoutID, ws.SetIndex+1, -1); err != nil {
+				return err
+			}
+		}
+		if ws.GroupID != nil {
+			if err := u.sr.RenumberGroup(ctx, *ws.GroupID); err != nil {
+				return err
+			}
+		}
+		if err := u.audit.Record(ctx, userID, setAudit(models.AuditDelete, ws, nil)); err != nil {
+			return err
+		}
+		return u.recomputeRecords(ctx, userID, ws)
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *workoutSetUsecase) RestoreSet(ctx context.Context, userID, setID string) (*models.WorkoutSet, error) {
+	ws, err := u.sr.FindDeletedByID(ctx, setID)
+	if err != nil {
+		return nil, err
+	}
+	if ws == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if _, err := u.ensureWorkoutOwned(ctx, ws.WorkoutID, userID); err != nil {
+		// ワークアウトもゴミ箱にあるなら、先にワークアウトを戻してもらう（一緒に消したセットはそれで戻る）
+		if trashed, ferr := u.wr.FindDeletedByIDAndUser(ctx, ws.WorkoutID, userID); ferr == nil && trashed != nil {
+			return nil, ErrWorkoutInTrash
+		}
+		return nil, err
+	}
+	err = u.tx.Transaction(ctx, func(ctx context.Context) error {
+		// 消したあとに増えたセットは後ろへずらして、元の位置に差し込む
+		if ws.SetIndex, err = u.allocSetIndex(ctx, ws.WorkoutID, ws.SetIndex); err != nil {
+			return err
+		}
+		if ws.GroupID != nil {
+			members, err := u.sr.ListGroup(ctx, ws.WorkoutID, *ws.GroupID)
+			if err != nil {
+				return err
+			}
+			if len(members) == 0 {
+				ws.GroupOrder = 1
+			} else if err := u.sr.ShiftGroupOrder(ctx, *ws.GroupID, ws.GroupOrder); err != nil {
+				return err
+			}
+		}
+		if err := u.sr.Restore(ctx, ws); err != nil {
+			return err
+		}
+		if ws.GroupID != nil {
+			if err := u.sr.RenumberGroup(ctx, *ws.GroupID); err != nil {
+				return err
+			}
+			if cur, err := u.sr.FindByID(ctx, ws.ID); err == nil && cur != nil {
+				ws.GroupOrder = cur.GroupOrder
+			}
+		}
+		if err := u.reloadVersion(ctx, ws); err != nil {
+			return err
+		}
+		if err := u.audit.Record(ctx, userID, setAudit(models.AuditRestore, nil, ws)); err != nil {
+			return err
+		}
+		ws.DeletedAt = gorm.DeletedAt{}
+		return u.recomputeRecords(ctx, userID, ws)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+func (u *workoutSetUsecase) ReorderSets(ctx context.Context, userID, workoutID string, in models.SetOrderInput) (*models.WorkoutDetail, error) {
+	w, err := u.ensureWorkoutOwned(ctx, workoutID, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range in.Sets {
+		if item.ExerciseID == nil {
+			continue
+		}
+		ex, err := u.er.FindByID(ctx, *item.ExerciseID)
+		if err != nil {
+			return nil, err
+		}
+		if ex == nil || (ex.OwnerUserID != nil && *ex.OwnerUserID != userID) {
+			return nil, fmt.Errorf("sets[%d]: exercise not found", i)
+		}
+	}
+
+	var sets []models.WorkoutSet
+	moved := map[string]bool{} // 付け替えた前後の種目（自己ベストを作り直す）
+	err = u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.sr.LockWorkoutSets(ctx, workoutID); err != nil {
+			return err
+		}
+		cur, err := u.wr.ListSetsByWorkout(ctx, workoutID)
+		if err != nil {
+			return err
+		}
+		if len(in.Sets) != len(cur) {
+			return errors.New("sets must list every set of the workout exactly once")
+		}
+		byID := make(map[string]models.WorkoutSet, len(cur))
+		for _, s := range cur {
+			byID[s.ID] = s
+		}
+		sets = make([]models.WorkoutSet, 0, len(cur))
+		var changes []AuditEntry
+		for i, item := range in.Sets {
+			s, ok := byID[item.ID]
+			if !ok {
+				return errors.New("sets must list every set of the workout exactly once")
+			}
+			delete(byID, item.ID)
+			before := s
+			if item.ExerciseID != nil && *item.ExerciseID != s.ExerciseID {
+				moved[s.ExerciseID], moved[*item.ExerciseID] = true, true
+				s.ExerciseID = *item.ExerciseID
+			}
+			s.SetIndex = i + 1
+			if before.SetIndex != s.SetIndex || before.ExerciseID != s.ExerciseID {
+				s.Version++ // SaveOrder が動かした・種目を付け替えたセットの version を上げる
+				after := s
+				changes = append(changes, setAudit(models.AuditUpdate, &before, &after))
+			}
+			sets = append(sets, s)
+		}
+		if err := u.sr.SaveOrder(ctx, workoutID, sets); err != nil {
+			return err
+		}
+		if err := u.audit.Record(ctx, userID, changes...); err != nil {
+			return err
+		}
+		for exerciseID := range moved {
+			if _, err := u.records.Recompute(ctx, userID, exerciseID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return models.NewWorkoutDetail(*w, sets), nil
+}
+
+func (u *workoutSetUsecase) GetSet(ctx context.Context, userID, setID string) (*models.WorkoutSet, error) {
+	return u.loadSetForUser(ctx, setID, userID)
+}
+
+func (u *workoutSetUsecase) LatestLineSet(ctx context.Context, userID string) (*models.WorkoutSet, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	return u.sr.FindLatestFromLineByUser(ctx, userID)
 }
 
 // internal helpers ----------------------------------------------------------
@@ -105,6 +380,132 @@ func (u *workoutSetUsecase) ensureWorkoutOwned(ctx context.Context, workoutID, u
 	return w, nil
 }
 
+// fillPlannedSet は未実施のセットに記録した値を入れる（登録日時も記録した時刻にする）。
+// in の重さは weightInput で解決済み
+func (u *workoutSetUsecase) fillPlannedSet(ctx context.Context, userID string, ws *models.WorkoutSet, in models.WorkoutSetCreateInput, now time.Time) (*models.WorkoutSet, error) {
+	before := *ws
+	ws.Reps = in.Reps
+	ws.SetWeight(in.Weight, in.WeightUnit)
+	ws.RPE = in.RPE
+	ws.IsWarmup = in.IsWarmup
+	ws.DurationSec = in.DurationSec
+	ws.DistanceM = in.DistanceM
+	if in.RestSec != nil {
+		ws.RestSec = in.RestSec
+	}
+	if in.Note != nil {
+		ws.Note = in.Note
+	}
+	ws.IsPlanned = false
+	ws.IsFromLine = true
+	ws.CreatedAt = now
+	ws.UpdatedAt = now
+	err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.sr.Update(ctx, ws); err != nil {
+			return err
+		}
+		if err := u.audit.Record(ctx, userID, setAudit(models.AuditUpdate, &before, ws)); err != nil {
+			return err
+		}
+		return u.recomputeRecords(ctx, userID, ws)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+// allocSetIndex はワークアウトをロックして新しいセットの set_index を決める。
+// want が 0 以下か最後より後ろならワークアウトの最後、それ以外は want 番目に差し込む（以降は 1 つ後ろへ）。
+// 呼び出し側のトランザクションの中で呼ぶ
+func (u *workoutSetUsecase) allocSetIndex(ctx context.Context, workoutID string, want int) (int, error) {
+	if err := u.sr.LockWorkoutSets(ctx, workoutID); err != nil {
+		return 0, err
+	}
+	next, err := u.sr.NextSetIndex(ctx, workoutID)
+	if err != nil {
+		return 0, err
+	}
+	if want <= 0 || want >= next {
+		return next, nil
+	}
+	if err := u.sr.ShiftSetIndex(ctx, workoutID, want, 1); err != nil {
+		return 0, err
+	}
+	return want, nil
+}
+
+// lockSet は ws のワークアウトをロックし、読んだあとにほかの経路で変わっていないか（version）を確かめて、並びを読み直す
+func (u *workoutSetUsecase) lockSet(ctx context.Context, ws *models.WorkoutSet) error {
+	if err := u.sr.LockWorkoutSets(ctx, ws.WorkoutID); err != nil {
+		return err
+	}
+	cur, err := u.sr.FindByID(ctx, ws.ID)
+	if err != nil {
+		return err
+	}
+	if cur == nil {
+		return errors.New("set not found")
+	}
+	// 読んでからロックするまでにほかの経路で変わっていたら、黙って上書き・削除しない
+	if cur.Version != ws.Version {
+		return repository.ErrVersionConflict
+	}
+	ws.SetIndex, ws.GroupType, ws.GroupID, ws.GroupOrder = cur.SetIndex, cur.GroupType, cur.GroupID, cur.GroupOrder
+	return nil
+}
+
+// reloadVersion は ws の今の version を読み直す。並びを書き換えると動いたセットの version も上がるので、
+// ロックしたトランザクションの中で自分が動かしたあとに呼ぶ
+func (u *workoutSetUsecase) reloadVersion(ctx context.Context, ws *models.WorkoutSet) error {
+	cur, err := u.sr.FindByID(ctx, ws.ID)
+	if err != nil {
+		return err
+	}
+	if cur == nil {
+		return errors.New("set not found")
+	}
+	ws.Version = cur.Version
+	return nil
+}
+
+// moveSet は ws をワークアウトの to 番目（範囲外なら最初か最後）へ動かし、1 から詰め直す。
+// ロックしたトランザクションの中で呼ぶ
+func (u *workoutSetUsecase) moveSet(ctx context.Context, ws *models.WorkoutSet, to int) error {
+	cur, err := u.wr.ListSetsByWorkout(ctx, ws.WorkoutID)
+	if err != nil {
+		return err
+	}
+	sets := make([]models.WorkoutSet, 0, len(cur))
+	for _, s := range cur {
+		if s.ID != ws.ID {
+			sets = append(sets, s)
+		}
+	}
+	pos := min(max(to, 1), len(sets)+1) - 1
+	sets = append(sets[:pos], append([]models.WorkoutSet{*ws}, sets[pos:]...)...)
+	for i := range sets {
+		sets[i].SetIndex = i + 1
+	}
+	ws.SetIndex = pos + 1
+	return u.sr.SaveOrder(ctx, ws.WorkoutID, sets)
+}
+
+// recomputeRecords は ws の種目の自己ベストを作り直し、ws で新しくできた記録を ws.Records に入れる。
+// セットを書き込んだトランザクションの中で呼ぶ（作り直せなければセットの保存ごと戻す）
+func (u *workoutSetUsecase) recomputeRecords(ctx context.Context, userID string, ws *models.WorkoutSet) error {
+	added, err := u.records.Recompute(ctx, userID, ws.ExerciseID)
+	if err != nil {
+		return err
+	}
+	for _, r := range added {
+		if (r.SetID != nil && *r.SetID == ws.ID) || (r.SetID == nil && r.WorkoutID == ws.WorkoutID) {
+			ws.Records = append(ws.Records, r)
+		}
+	}
+	return nil
+}
+
 func (u *workoutSetUsecase) loadSetForUser(ctx context.Context, setID, userID string) (*models.WorkoutSet, error) {
 	ws, err := u.sr.FindByID(ctx, setID)
 	if err != nil {
@@ -119,15 +520,141 @@ func (u *workoutSetUsecase) loadSetForUser(ctx context.Context, setID, userID st
 	return ws, nil
 }
 
-func applyWorkoutSetPatch(ws *models.WorkoutSet, in models.WorkoutSetUpdateInput) {
-	if in.SetIndex != nil {
-		ws.SetIndex = *in.SetIndex
+// groupPlacement はセットを入れるグループと順番
+type groupPlacement struct {
+	typ   models.SetGroupType
+	id    *string // straight なら nil
+	order int
+}
+
+func (g groupPlacement) apply(ws *models.WorkoutSet) {
+	ws.GroupType, ws.GroupID, ws.GroupOrder = g.typ, g.id, g.order
+}
+
+// placeInGroup は ws を groupID があればそのグループの order 番目（0 なら最後）、
+// 無ければ typ の新しいグループの最初（straight ならグループなし）に入れる。
+// 新しいグループの ID は ws の ID（保存前なら ここで払い出して ws.ID に入れる）
+func (u *workoutSetUsecase) placeInGroup(ctx context.Context, ws *models.WorkoutSet, typ models.SetGroupType, groupID *string, order int) (groupPlacement, error) {
+	if order < 0 {
+		return groupPlacement{}, errors.New("groupOrder must not be negative")
+	}
+	if groupID == nil {
+		switch {
+		case typ == "" || typ == models.SetGroupStraight:
+			return groupPlacement{typ: models.SetGroupStraight}, nil
+		case !typ.Valid():
+			return groupPlacement{}, errors.New("groupType must be one of straight, superset, circuit, drop, cluster")
+		}
+		if ws.ID == "" {
+			id, err := u.sr.NewID(ctx)
+			if err != nil {
+				return groupPlacement{}, err
+			}
+			ws.ID = id
+		}
+		id := ws.ID
+		return groupPlacement{typ: typ, id: &id, order: 1}, nil
+	}
+
+	members, err := u.sr.ListGroup(ctx, ws.WorkoutID, *groupID)
+	if err != nil {
+		return groupPlacement{}, err
+	}
+	if len(members) == 0 {
+		return groupPlacement{}, errors.New("group not found in this workout")
+	}
+	g := groupPlacement{typ: members[0].GroupType, id: groupID}
+	if typ != "" && typ != g.typ {
+		return groupPlacement{}, errors.New("groupType does not match the group")
+	}
+	last := members[len(members)-1].GroupOrder
+	if order == 0 || order > last {
+		g.order = last + 1
+		return g, nil
+	}
+	if err := u.sr.ShiftGroupOrder(ctx, *groupID, order); err != nil {
+		return groupPlacement{}, err
+	}
+	g.order = order
+	return g, nil
+}
+
+// applyGroupPatch はグループの変更を ws に入れる。
+// groupId が今と同じ（か無い）で種類も同じなら順番だけ、それ以外は placeInGroup で入れ直す
+func (u *workoutSetUsecase) applyGroupPatch(ctx context.Context, ws *models.WorkoutSet, in models.WorkoutSetUpdateInput) error {
+	if in.GroupType == nil && in.GroupID == nil && in.GroupOrder == nil {
+		return nil
+	}
+	var typ models.SetGroupType
+	if in.GroupType != nil {
+		typ = *in.GroupType
+	}
+	order := 0
+	if in.GroupOrder != nil {
+		order = *in.GroupOrder
+	}
+
+	sameGroup := in.GroupID == nil || (ws.GroupID != nil && *ws.GroupID == *in.GroupID)
+	if sameGroup && (typ == "" || typ == ws.GroupType) {
+		if in.GroupOrder == nil {
+			return nil
+		}
+		if ws.GroupID == nil {
+			return errors.New("set is not in a group")
+		}
+		if order <= 0 {
+			return errors.New("groupOrder must be positive")
+		}
+		// 後ろへ動かすときは order 番目のセットの後ろに入れる（前の穴は保存後に詰める）
+		if ws.GroupOrder < order {
+			order++
+		}
+		if err := u.sr.ShiftGroupOrder(ctx, *ws.GroupID, order); err != nil {
+			return err
+		}
+		ws.GroupOrder = order
+		return nil
+	}
+
+	g, err := u.placeInGroup(ctx, ws, typ, in.GroupID, order)
+	if err != nil {
+		return err
+	}
+	g.apply(ws)
+	return nil
+}
+
+// weightInput は weight + weightUnit（無ければ weightKg）を入力された重さと単位にする
+func weightInput(weightKg, weight *float32, unit models.WeightUnit) (*float32, models.WeightUnit, error) {
+	if weight == nil {
+		if weightKg == nil {
+			return nil, "", nil
+		}
+		return weightKg, models.WeightUnitKg, nil
+	}
+	if !unit.Valid() {
+		return nil, "", errors.New("weightUnit must be kg or lb")
+	}
+	if *weight < 0 {
+		return nil, "", errors.New("weight must not be negative")
+	}
+	return weight, unit, nil
+}
+
+func applyWorkoutSetPatch(ws *models.WorkoutSet, in models.WorkoutSetUpdateInput) error {
+	// 値を入れたら未実施のセットは実施済みにする（並べ替えだけなら変えない）
+	if in.Reps != nil || in.WeightKg != nil || in.Weight != nil || in.RPE != nil || in.DurationSec != nil || in.DistanceM != nil {
+		ws.IsPlanned = false
 	}
 	if in.Reps != nil {
 		ws.Reps = in.Reps
 	}
-	if in.WeightKg != nil {
-		ws.WeightKg = in.WeightKg
+	if in.WeightKg != nil || in.Weight != nil {
+		weight, unit, err := weightInput(in.WeightKg, in.Weight, in.WeightUnit)
+		if err != nil {
+			return err
+		}
+		ws.SetWeight(weight, unit)
 	}
 	if in.RPE != nil {
 		ws.RPE = in.RPE
@@ -147,4 +674,5 @@ func applyWorkoutSetPatch(ws *models.WorkoutSet, in models.WorkoutSetUpdateInput
 	if in.DistanceM != nil {
 		ws.DistanceM = in.DistanceM
 	}
+	return nil
 }
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+// exerciseTable は FindByID だけ答える種目表（ゴミ箱の種目は入れない = nil, nil）
+type exerciseTable struct {
+	repository.ExerciseRepository
+	rows map[string]models.Exercise
+}
+
+func (r exerciseTable) FindByID(ctx context.Context, id string) (*models.Exercise, error) {
+	ex, ok := r.rows[id]
+	if !ok {
+		return nil, nil
+	}
+	return &ex, nil
+}
+
+func (r memSetRepo) FindDeletedByID(ctx context.Context, id string) (*models.WorkoutSet, error) {
+	s, ok := r.db.sets[id]
+	if !ok || !s.DeletedAt.Valid {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (r memWorkoutRepo) FindDeletedByIDAndUser(ctx context.Context, workoutID, userID string) (*models.Workout, error) {
+	w, ok := r.db.workouts[workoutID]
+	if !ok || w.UserID != userID || !w.DeletedAt.Valid {
+		return nil, nil
+	}
+	return &w, nil
+}
+
+func TestAddSetRejectsInvisibleExercise(t *testing.T) {
+	other := "u-2"
+	mine := txUserID
+	exercises := exerciseTable{rows: map[string]models.Exercise{
+		"global":  {ID: "global"},
+		"mine":    {ID: "mine", OwnerUserID: &mine},
+		"private": {ID: "private", OwnerUserID: &other},
+	}}
+	cases := []struct {
+		exerciseID string
+		ok         bool
+	}{
+		{exerciseID: "global", ok: true},
+		{exerciseID: "mine", ok: true},
+		{exerciseID: "private"}, // ほかのユーザーの独自種目
+		{exerciseID: "trashed"}, // ゴミ箱の種目（FindByID では見えない）
+		{exerciseID: "no-such"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.exerciseID, func(t *testing.T) {
+			db := newMemDB()
+			seedWorkout(db)
+			uc := NewWorkoutSetUsecase(memTx{db}, memWorkoutRepo{db: db}, memSetRepo{db: db}, exercises, noRecords{}, memAudit{db: db})
+
+			reps := 5
+			_, err := uc.AddSet(context.Background(), txUserID, txWorkoutID, models.WorkoutSetCreateInput{ExerciseID: tc.exerciseID, Reps: &reps}, false)
+			if tc.ok {
+				if err != nil {
+					t.Fatalf("AddSet: %v", err)
+				}
+				return
+			}
+			if !IsNotFound(err) {
+				t.Fatalf("AddSet err = %v, want not found", err)
+			}
+			if len(db.sets) != 2 || len(db.audits) != 0 {
+				t.Errorf("sets = %d, audits = %d, want nothing written", len(db.sets), len(db.audits))
+			}
+		})
+	}
+}
+
+func TestRestoreSetInTrashedWorkout(t *testing.T) {
+	db := newMemDB()
+	seedWorkout(db)
+	// 先にセットを 1 件消し、あとでワークアウトごと消した
+	s := db.sets["seed-1"]
+	s.DeletedAt = gorm.DeletedAt{Time: time.Now().Add(-time.Hour), Valid: true}
+	db.sets[s.ID] = s
+	w := db.workouts[txWorkoutID]
+	w.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	db.workouts[w.ID] = w
+	uc := NewWorkoutSetUsecase(memTx{db}, memWorkoutRepo{db: db}, memSetRepo{db: db}, memExerciseRepo{}, noRecords{}, memAudit{db: db})
+
+	if _, err := uc.RestoreSet(context.Background(), txUserID, "seed-1"); !errors.Is(err, ErrWorkoutInTrash) {
+		t.Fatalf("RestoreSet err = %v, want ErrWorkoutInTrash", err)
+	}
+	// ほかのユーザーには、ゴミ箱にあることも教えない
+	if _, err := uc.RestoreSet(context.Background(), "u-2", "seed-1"); err == nil || errors.Is(err, ErrWorkoutInTrash) {
+		t.Errorf("RestoreSet by another user err = %v, want not found or forbidden", err)
+	}
+	if !db.sets["seed-1"].DeletedAt.Valid || len(db.audits) != 0 {
+		t.Errorf("set was restored or audited: %+v / %v", db.sets["seed-1"], db.audits)
+	}
+}
+
+func (r memSetRepo) FindByID(ctx context.Context, id string) (*models.WorkoutSet, error) {
+	s, ok := r.db.sets[id]
+	if !ok || s.DeletedAt.Valid {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (r memSetRepo) Update(ctx context.Context, ws *models.WorkoutSet) error {
+	if err := r.db.write(ctx, "Update"); err != nil {
+		return err
+	}
+	if r.db.sets[ws.ID].Version != ws.Version {
+		return repository.ErrVersionConflict
+	}
+	ws.Version++
+	r.db.sets[ws.ID] = *ws
+	return nil
+}
+
+// SaveOrder はリポジトリの SQL と同じく、set_index か種目が変わったセットだけ version を上げる
+func (r memSetRepo) SaveOrder(ctx context.Context, workoutID string, sets []models.WorkoutSet) error {
+	if err := r.db.write(ctx, "SaveOrder"); err != nil {
+		return err
+	}
+	for _, s := range sets {
+		cur := r.db.sets[s.ID]
+		if cur.SetIndex != s.SetIndex || cur.ExerciseID != s.ExerciseID {
+			cur.SetIndex, cur.ExerciseID = s.SetIndex, s.ExerciseID
+			cur.Version++
+			r.db.sets[s.ID] = cur
+		}
+	}
+	return nil
+}
+
+func TestReorderInvalidatesETag(t *testing.T) {
+	db := newMemDB()
+	seedWorkout(db)
+	uc := NewWorkoutSetUsecase(memTx{db}, memWorkoutRepo{db: db}, memSetRepo{db: db}, memExerciseRepo{}, noRecords{}, memAudit{db: db})
+	ctx := context.Background()
+
+	// GET /api/workout_sets/seed-2 の ETag
+	got, err := uc.GetSet(ctx, txUserID, "seed-2")
+	if err != nil {
+		t.Fatalf("GetSet: %v", err)
+	}
+	etag := got.Version
+
+	// ほかの画面（か LINE）で並べ替える
+	out, err := uc.ReorderSets(ctx, txUserID, txWorkoutID, models.SetOrderInput{Sets: []models.SetOrderItem{{ID: "seed-2"}, {ID: "seed-1"}}})
+	if err != nil {
+		t.Fatalf("ReorderSets: %v", err)
+	}
+	for _, s := range out.Sets {
+		if s.Version != db.sets[s.ID].Version {
+			t.Errorf("ReorderSets returned version %d for %s, stored %d", s.Version, s.ID, db.sets[s.ID].Version)
+		}
+	}
+
+	// 古い ETag の PATCH は If-Match が合わない（コントローラーが 412 にする）
+	reps := 3
+	if _, err := uc.UpdateSet(ctx, txUserID, "seed-2", &etag, models.WorkoutSetUpdateInput{Reps: &reps}); !IsVersionConflict(err) {
+		t.Fatalf("UpdateSet with stale ETag err = %v, want version conflict", err)
+	}
+	if s := db.sets["seed-2"]; s.Reps != nil || s.SetIndex != 1 {
+		t.Errorf("stale update was written: %+v", s)
+	}
+
+	// 取り直した ETag なら通り、返す version は保存したものと同じ
+	fresh, err := uc.GetSet(ctx, txUserID, "seed-2")
+	if err != nil {
+		t.Fatalf("GetSet: %v", err)
+	}
+	updated, err := uc.UpdateSet(ctx, txUserID, "seed-2", &fresh.Version, models.WorkoutSetUpdateInput{Reps: &reps})
+	if err != nil {
+		t.Fatalf("UpdateSet with fresh ETag: %v", err)
+	}
+	if updated.Version != db.sets["seed-2"].Version || updated.Version <= fresh.Version {
+		t.Errorf("updated version = %d, stored %d, fresh %d", updated.Version, db.sets["seed-2"].Version, fresh.Version)
+	}
+}
@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirasu21/Logbook/backend/models"
+	repository "github.com/sirasu21/Logbook/backend/repository/web"
+)
+
+// 「前回と同じメニュー」で新しいワークアウトを作る
+type WorkoutCloneUsecase interface {
+	// Clone は workoutID の種目とセットを予定のセットとして並べた新しいワークアウトを作る。
+	// 途中で失敗したら何も残さない
+	Clone(ctx context.Context, userID, workoutID string, in models.CloneWorkoutInput, isFromLine bool) (*models.WorkoutDetail, error)
+	// LatestFinished は最後に終了したワークアウト（無ければ nil）
+	LatestFinished(ctx context.Context, userID string) (*models.Workout, error)
+}
+
+const (
+	defaultWeightStepKg = 2.5
+	// lb で記録したセットはプレートに合わせて 5lb ずつ
+	defaultWeightStepLb = 5
+	defaultRepStep      = 1
+	// LatestFinished で遡るワークアウト数
+	latestFinishedLookup = 10
+)
+
+type workoutCloneUsecase struct {
+	tx           repository.Transactor
+	workoutuc    WorkoutUsecase
+	workoutSetuc WorkoutSetUsecase
+}
+
+func NewWorkoutCloneUsecase(tx repository.Transactor, workoutuc WorkoutUsecase, workoutSetuc WorkoutSetUsecase) WorkoutCloneUsecase {
+	return &workoutCloneUsecase{tx: tx, workoutuc: workoutuc, workoutSetuc: workoutSetuc}
+}
+
+func (u *workoutCloneUsecase) Clone(ctx context.Context, userID, workoutID string, in models.CloneWorkoutInput, isFromLine bool) (*models.WorkoutDetail, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	rule, err := newProgressionRule(in)
+	if err != nil {
+		return nil, err
+	}
+	startedAt := time.Now()
+	if in.StartedAt != nil {
+		startedAt = *in.StartedAt
+	}
+
+	var out *models.WorkoutDetail
+	err = u.tx.Transaction(ctx, func(ctx context.Context) error {
+		src, err := u.workoutuc.GetDetail(ctx, userID, workoutID)
+		if err != nil {
+			return err
+		}
+		if len(src.Sets) == 0 {
+			return errors.New("workout has no sets")
+		}
+		w, err := u.workoutuc.Create(ctx, userID, models.CreateWorkoutInput{StartedAt: startedAt, Note: src.Workout.Note}, isFromLine)
+		if err != nil {
+			return err
+		}
+		groups := map[string]string{} // 前回の GroupID → 複製した GroupID
+		for i, s := range src.Sets {
+			// 入力された単位のまま複製する（kg に直すと lb の値がずれる）
+			weight, unit := s.EnteredWeight()
+			set := models.WorkoutSetCreateInput{
+				ExerciseID:  s.ExerciseID,
+				SetIndex:    i + 1,
+				Reps:        s.Reps,
+				Weight:      weight,
+				WeightUnit:  unit,
+				RPE:         s.RPE,
+				IsWarmup:    s.IsWarmup,
+				IsPlanned:   true,
+				RestSec:     s.RestSec,
+				DurationSec: s.DurationSec,
+				DistanceM:   s.DistanceM,
+			}
+			// グループは最初のセットで作り、続きのセットはそこに入れる
+			if s.GroupID != nil {
+				set.GroupType = s.GroupType
+				if id, ok := groups[*s.GroupID]; ok {
+					set.GroupID = &id
+				}
+			}
+			rule.apply(&set, s)
+			// 予定のセットは LINE の「取り消し」「もう1セット」の対象にしない（テンプレートと同じ）
+			added, err := u.workoutSetuc.AddSet(ctx, userID, w.ID, set, false)
+			if err != nil {
+				return err
+			}
+			if s.GroupID != nil && added.GroupID != nil {
+				groups[*s.GroupID] = *added.GroupID
+			}
+		}
+		out, err = u.workoutuc.GetDetail(ctx, userID, w.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (u *workoutCloneUsecase) LatestFinished(ctx context.Context, userID string) (*models.Workout, error) {
+	items, _, err := u.workoutuc.ListByUser(ctx, userID, WorkoutListFilter{Limit: latestFinishedLookup})
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if items[i].EndedAt != nil {
+			return &items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// progressionRule は前回のセットからどれだけ進めるか
+type progressionRule struct {
+	kind         models.Progression
+	weightStep   float32 // kg
+	weightStepLb float32
+	repStep      int
+	targetReps   *int
+}
+
+func newProgressionRule(in models.CloneWorkoutInput) (progressionRule, error) {
+	r := progressionRule{kind: in.Progression, weightStep: defaultWeightStepKg, weightStepLb: defaultWeightStepLb, repStep: defaultRepStep, targetReps: in.TargetReps}
+	switch in.Progression {
+	case models.ProgressionNone, models.ProgressionWeight, models.ProgressionReps:
+	default:
+		return r, errors.New("progression must be one of: weight, reps")
+	}
+	if in.WeightStepKg != nil {
+		if *in.WeightStepKg <= 0 || *in.WeightStepKg > 50 {
+			return r, errors.New("weightStepKg must be between 0 and 50")
+		}
+		r.weightStep = *in.WeightStepKg
+		r.weightStepLb = float32(models.FromKg(float64(*in.WeightStepKg), models.WeightUnitLb))
+	}
+	if in.RepStep != nil {
+		if *in.RepStep <= 0 || *in.RepStep > 10 {
+			return r, errors.New("repStep must be between 1 and 10")
+		}
+		r.repStep = *in.RepStep
+	}
+	if in.TargetReps != nil && *in.TargetReps <= 0 {
+		return r, errors.New("targetReps must be positive")
+	}
+	return r, nil
+}
+
+// apply は目標回数に届いた本番セット（ウォームアップ・未実施は除く）だけ進める
+func (r progressionRule) apply(set *models.WorkoutSetCreateInput, prev models.WorkoutSet) {
+	if r.kind == models.ProgressionNone || prev.IsWarmup || prev.IsPlanned || prev.Reps == nil {
+		return
+	}
+	if r.targetReps != nil && *prev.Reps < *r.targetReps {
+		return
+	}
+	switch r.kind {
+	case models.ProgressionWeight:
+		if set.Weight != nil {
+			w := *set.Weight + r.weightStep
+			if set.WeightUnit == models.WeightUnitLb {
+				w = *set.Weight + r.weightStepLb
+			}
+			set.Weight = &w
+		}
+	case models.ProgressionReps:
+		reps := *prev.Reps + r.repStep
+		set.Reps = &reps
+	}
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirasu21/Logbook/backend/models"
+)
+
+// FindLatestFromLineByUser はリポジトリの SQL と同じく、予定のセットを除いた LINE のセットの最新
+func (r memSetRepo) FindLatestFromLineByUser(ctx context.Context, userID string) (*models.WorkoutSet, error) {
+	var latest *models.WorkoutSet
+	for _, s := range r.db.sets {
+		if r.db.workouts[s.WorkoutID].UserID != userID || !s.IsFromLine || s.IsPlanned || s.DeletedAt.Valid {
+			continue
+		}
+		if latest == nil || s.CreatedAt.After(latest.CreatedAt) {
+			latest = &s
+		}
+	}
+	return latest, nil
+}
+
+// memWorkouts は Clone が使う WorkoutUsecase のメソッドだけを memDB で動かす
+type memWorkouts struct {
+	WorkoutUsecase
+	db *memDB
+}
+
+func (u memWorkouts) Create(ctx context.Context, userID string, in models.CreateWorkoutInput, isFromLine bool) (*models.Workout, error) {
+	w := models.Workout{ID: "w-clone", UserID: userID, StartedAt: in.StartedAt, Version: 1}
+	u.db.workouts[w.ID] = w
+	return &w, nil
+}
+
+func (u memWorkouts) GetDetail(ctx context.Context, userID, workoutID string) (*models.WorkoutDetail, error) {
+	return &models.WorkoutDetail{Workout: u.db.workouts[workoutID], Sets: u.db.liveSets(workoutID)}, nil
+}
+
+func TestCloneFromLineKeepsLatestLineSet(t *testing.T) {
+	db := newMemDB()
+	seedWorkout(db)
+	// 前回 LINE で実際に記録したセット
+	done := db.sets["seed-2"]
+	done.IsFromLine, done.CreatedAt = true, time.Now().Add(-24*time.Hour)
+	db.sets[done.ID] = done
+
+	sets := NewWorkoutSetUsecase(memTx{db}, memWorkoutRepo{db: db}, memSetRepo{db: db}, memExerciseRepo{}, noRecords{}, memAudit{db: db})
+	clone := NewWorkoutCloneUsecase(memTx{db}, memWorkouts{db: db}, sets)
+
+	out, err := clone.Clone(context.Background(), txUserID, txWorkoutID, models.CloneWorkoutInput{}, true)
+	if err != nil {
+		t.Fatalf("Clone: %v", err)
+	}
+	if len(out.Sets) != 2 {
+		t.Fatalf("cloned sets = %d, want 2", len(out.Sets))
+	}
+	for _, s := range out.Sets {
+		if !s.IsPlanned || s.IsFromLine {
+			t.Errorf("set %s isPlanned = %v, isFromLine = %v, want planned and not from LINE", s.ID, s.IsPlanned, s.IsFromLine)
+		}
+	}
+
+	latest, err := sets.LatestLineSet(context.Background(), txUserID)
+	if err != nil {
+		t.Fatalf("LatestLineSet: %v", err)
+	}
+	if latest == nil || latest.ID != done.ID {
+		t.Errorf("LatestLineSet = %+v, want %s (the set the user performed)", latest, done.ID)
+	}
+}
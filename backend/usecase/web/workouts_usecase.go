@@ -11,16 +11,30 @@ import (
 	"gorm.io/gorm"
 )
 
+// End / Update / Delete の version は呼び出し側が読んだときの版（If-Match）。
+// nil なら確かめない。今の版と違えば repository.ErrVersionConflict を返す
 type WorkoutUsecase interface {
 	Create(ctx context.Context, userID string, in models.CreateWorkoutInput, isFromLine bool) (*models.Workout, error)
-	End(ctx context.Context, workoutID string, userID string, endedAt time.Time) (*models.Workout, error)
+	End(ctx context.Context, workoutID string, userID string, version *int, endedAt time.Time) (*models.Workout, error)
 	ListByUser(ctx context.Context, userID string, f WorkoutListFilter) ([]models.Workout, int, error)
 	GetDetail(ctx context.Context, userID string, workoutID string) (*models.WorkoutDetail, error)
-	Update(ctx context.Context, workoutID, userID string, in models.UpdateWorkoutInput) (*models.Workout, error)
-	Delete(ctx context.Context, workoutID, userID string) error
+	Update(ctx context.Context, workoutID, userID string, version *int, in models.UpdateWorkoutInput) (*models.Workout, error)
+	// ゴミ箱に入れる（セットも一緒に）
+	Delete(ctx context.Context, workoutID, userID string, version *int) error
+	// ゴミ箱から戻す（一緒に消したセットも戻る）
+	Restore(ctx context.Context, workoutID, userID string) (*models.Workout, error)
 	GetLatestLineWorkoutID(ctx context.Context, userID string, onlyOpen bool) (string, error)
+	// LINE で開始して終了していないもの（なければ nil）
+	OpenLineWorkout(ctx context.Context, userID string) (*models.Workout, error)
+	// 最後のセットの時刻で終了する（セットがなければ開始時刻）
+	Close(ctx context.Context, workoutID, userID string) (*models.Workout, error)
+	// 放置されたワークアウトを最後のセットの時刻で終了し、終了したものを返す
+	CloseAbandoned(ctx context.Context, now time.Time) ([]models.AbandonedWorkout, error)
 }
 
+// DefaultAutoCloseHours はリマインド設定がないユーザーの自動終了までの時間
+const DefaultAutoCloseHours = 6
+
 type WorkoutListFilter struct {
 	From   *time.Time
 	To     *time.Time
@@ -29,12 +43,15 @@ type WorkoutListFilter struct {
 }
 
 type workoutUsecase struct {
+	tx      repository.Transactor
 	repo    repository.WorkoutRepository
 	setRepo repository.WorkoutSetRepository
+	records RecordUsecase
+	audit   AuditUsecase
 }
 
-func NewWorkoutUsecase(repo repository.WorkoutRepository, setRepo repository.WorkoutSetRepository) WorkoutUsecase {
-	return &workoutUsecase{repo: repo, setRepo: setRepo}
+func NewWorkoutUsecase(tx repository.Transactor, repo repository.WorkoutRepository, setRepo repository.WorkoutSetRepository, records RecordUsecase, audit AuditUsecase) WorkoutUsecase {
+	return &workoutUsecase{tx: tx, repo: repo, setRepo: setRepo, records: records, audit: audit}
 }
 
 func (u *workoutUsecase) Create(ctx context.Context, userID string, in models.CreateWorkoutInput, isFromLine bool) (*models.Workout, error) {
@@ -54,23 +71,43 @@ func (u *workoutUsecase) Create(ctx context.Context, userID string, in models.Cr
 		Note:      in.Note,
 		IsFromLine:isFromLine ,
 	}
-	if err := u.repo.Create(ctx, w); err != nil {
+	err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.repo.Create(ctx, w); err != nil {
+			return err
+		}
+		return u.audit.Record(ctx, userID, workoutAudit(models.AuditCreate, nil, w))
+	})
+	if err != nil {
 		return nil, err
 	}
 	return w, nil
 }
 
 // backend/usecase/workout_usecase.go
-func (u *workoutUsecase) End(ctx context.Context, workoutID string, userID string, endedAt time.Time) (*models.Workout, error) {
+func (u *workoutUsecase) End(ctx context.Context, workoutID string, userID string, version *int, endedAt time.Time) (*models.Workout, error) {
 	// 1) 本人のレコードか確認
 	if err := ensureUserID(userID); err != nil {
 		return nil, err
 	}
-	if _, err := u.repo.FindByIDForUser(ctx, workoutID, userID); err != nil {
+	var w *models.Workout
+	err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+		before, err := u.repo.FindByIDForUser(ctx, workoutID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(version, before.Version); err != nil {
+			return err
+		}
+		// 2) 更新
+		if w, err = u.repo.UpdateEndedAt(ctx, workoutID, before.Version, endedAt); err != nil {
+			return err
+		}
+		return u.audit.Record(ctx, userID, workoutAudit(models.AuditUpdate, before, w))
+	})
+	if err != nil {
 		return nil, err
 	}
-	// 2) 更新
-	return u.repo.UpdateEndedAt(ctx, workoutID, endedAt)
+	return w, nil
 }
 
 func (u *workoutUsecase) ListByUser(ctx context.Context, userID string, f WorkoutListFilter) ([]models.Workout, int, error) {
@@ -89,28 +126,102 @@ func (u *workoutUsecase) GetDetail(ctx context.Context, userID string, workoutID
 		return nil, err
 	}
 
-	return &models.WorkoutDetail{
-		Workout: *w,
-		Sets:    sets,
-	}, nil
+	return models.NewWorkoutDetail(*w, sets), nil
 }
 
-func (u *workoutUsecase) Update(ctx context.Context, workoutID, userID string, in models.UpdateWorkoutInput) (*models.Workout, error) {
+func (u *workoutUsecase) Update(ctx context.Context, workoutID, userID string, version *int, in models.UpdateWorkoutInput) (*models.Workout, error) {
 	if err := ensureUserID(userID); err != nil {
 		return nil, err
 	}
 	updates := collectWorkoutUpdates(in)
-	return u.repo.UpdateWorkoutByIDAndUser(ctx, workoutID, userID, updates)
+	var w *models.Workout
+	err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+		before, err := u.repo.FindByIDAndUser(ctx, workoutID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(version, before.Version); err != nil {
+			return err
+		}
+		if w, err = u.repo.UpdateWorkoutByIDAndUser(ctx, workoutID, userID, before.Version, updates); err != nil {
+			return err
+		}
+		if err := u.audit.Record(ctx, userID, workoutAudit(models.AuditUpdate, before, w)); err != nil {
+			return err
+		}
+		// 開始日時が変わるとセットの順番が変わるので自己ベストを作り直す
+		if in.StartedAt == nil {
+			return nil
+		}
+		sets, err := u.repo.ListSetsByWorkout(ctx, workoutID)
+		if err != nil {
+			return err
+		}
+		return u.recomputeRecords(ctx, userID, sets)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
 }
 
-func (u *workoutUsecase) Delete(ctx context.Context, workoutID, userID string) error {
-	if _, err := u.ensureWorkout(ctx, workoutID, userID); err != nil {
+func (u *workoutUsecase) Delete(ctx context.Context, workoutID, userID string, version *int) error {
+	var sets []models.WorkoutSet
+	// セットだけ消えてワークアウトが残る、ということがないように
+	err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+		w, err := u.ensureWorkout(ctx, workoutID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(version, w.Version); err != nil {
+			return err
+		}
+		if sets, err = u.repo.ListSetsByWorkout(ctx, workoutID); err != nil {
+			return err
+		}
+		// 戻すときに一緒に消したセットを見分けられるよう、同じ時刻でゴミ箱に入れる
+		at := time.Now()
+		if err := u.setRepo.DeleteByWorkoutID(ctx, workoutID, at); err != nil {
+			return err
+		}
+		if err := u.repo.DeleteWorkoutByIDAndUser(ctx, workoutID, userID, w.Version, at); err != nil {
+			return err
+		}
+		// セットはワークアウトと一緒に戻るので、ワークアウトの 1 件だけ記録する
+		if err := u.audit.Record(ctx, userID, workoutAudit(models.AuditDelete, w, nil)); err != nil {
+			return err
+		}
+		return u.recomputeRecords(ctx, userID, sets)
+	})
+	if err != nil {
 		return err
 	}
-	if err := u.setRepo.DeleteByWorkoutID(ctx, workoutID); err != nil {
-		return err
+	return nil
+}
+
+func (u *workoutUsecase) Restore(ctx context.Context, workoutID, userID string) (*models.Workout, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
 	}
-	return u.repo.DeleteWorkoutByIDAndUser(ctx, workoutID, userID)
+	var w *models.Workout
+	err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		if w, err = u.repo.RestoreWorkout(ctx, workoutID, userID); err != nil {
+			return err
+		}
+		if err := u.audit.Record(ctx, userID, workoutAudit(models.AuditRestore, nil, w)); err != nil {
+			return err
+		}
+		sets, err := u.repo.ListSetsByWorkout(ctx, workoutID)
+		if err != nil {
+			return err
+		}
+		return u.recomputeRecords(ctx, userID, sets)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
 }
 
 // 共通 NotFound 判定
@@ -119,6 +230,11 @@ func IsNotFound(err error) bool {
 	return errors.Is(err, gorm.ErrRecordNotFound)
 }
 
+// IsVersionConflict は読んだあとにほかの経路で変わっていたとき（If-Match が合わないときも）
+func IsVersionConflict(err error) bool {
+	return errors.Is(err, repository.ErrVersionConflict)
+}
+
 // internal helpers ----------------------------------------------------------
 
 func ensureUserID(userID string) error {
@@ -128,6 +244,14 @@ func ensureUserID(userID string) error {
 	return nil
 }
 
+// checkVersion は呼び出し側が読んだ版（nil なら確かめない）と今の版を比べる
+func checkVersion(version *int, current int) error {
+	if version != nil && *version != current {
+		return repository.ErrVersionConflict
+	}
+	return nil
+}
+
 func (u *workoutUsecase) ensureWorkout(ctx context.Context, workoutID, userID string) (*models.Workout, error) {
 	if err := ensureUserID(userID); err != nil {
 		return nil, err
@@ -142,6 +266,22 @@ func (u *workoutUsecase) ensureWorkout(ctx context.Context, workoutID, userID st
 	return w, nil
 }
 
+// recomputeRecords は sets に含まれる種目の自己ベストを作り直す。
+// ワークアウトを書き込んだトランザクションの中で呼ぶ
+func (u *workoutUsecase) recomputeRecords(ctx context.Context, userID string, sets []models.WorkoutSet) error {
+	done := map[string]bool{}
+	for _, s := range sets {
+		if done[s.ExerciseID] || s.IsPlanned {
+			continue
+		}
+		done[s.ExerciseID] = true
+		if _, err := u.records.Recompute(ctx, userID, s.ExerciseID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func collectWorkoutUpdates(in models.UpdateWorkoutInput) map[string]any {
 	updates := make(map[string]any)
 	if in.StartedAt != nil {
@@ -173,4 +313,83 @@ func (u *workoutUsecase) GetLatestLineWorkoutID(ctx context.Context, userID stri
 		return "", errors.New("not found")
 	}
 	return w.ID, nil
-}
\ No newline at end of file
+}
+
+func (u *workoutUsecase) OpenLineWorkout(ctx context.Context, userID string) (*models.Workout, error) {
+	if err := ensureUserID(userID); err != nil {
+		return nil, err
+	}
+	return u.repo.FindLatestFromLineByUser(ctx, userID, true)
+}
+
+func (u *workoutUsecase) Close(ctx context.Context, workoutID, userID string) (*models.Workout, error) {
+	w, err := u.ensureWorkout(ctx, workoutID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if w.EndedAt != nil {
+		return w, nil
+	}
+	last, err := u.repo.LastSetAt(ctx, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	var closed *models.Workout
+	err = u.tx.Transaction(ctx, func(ctx context.Context) error {
+		ok, err := u.repo.CloseIfOpen(ctx, workoutID, closingTime(w.StartedAt, last))
+		if err != nil {
+			return err
+		}
+		if closed, err = u.repo.FindByIDAndUser(ctx, workoutID, userID); err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		return u.audit.Record(ctx, userID, workoutAudit(models.AuditUpdate, w, closed))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return closed, nil
+}
+
+func (u *workoutUsecase) CloseAbandoned(ctx context.Context, now time.Time) ([]models.AbandonedWorkout, error) {
+	rows, err := u.repo.ListAbandoned(ctx, now, DefaultAutoCloseHours)
+	if err != nil {
+		return nil, err
+	}
+	var closed []models.AbandonedWorkout
+	for _, w := range rows {
+		endedAt := closingTime(w.StartedAt, w.LastSetAt)
+		before := w.Workout
+		var ok bool
+		err := u.tx.Transaction(ctx, func(ctx context.Context) error {
+			// 判定と更新の間に本人が終了していたら何もしない
+			var err error
+			if ok, err = u.repo.CloseIfOpen(ctx, w.ID, endedAt); err != nil || !ok {
+				return err
+			}
+			after := before
+			after.EndedAt = &endedAt
+			return u.audit.Record(ctx, w.UserID, workoutAudit(models.AuditUpdate, &before, &after))
+		})
+		if err != nil {
+			return closed, err
+		}
+		if !ok {
+			continue
+		}
+		w.EndedAt = &endedAt
+		closed = append(closed, w)
+	}
+	return closed, nil
+}
+
+// closingTime は放置されたワークアウトの終了時刻（掃除した時刻ではなく最後のセットの時刻）
+func closingTime(startedAt time.Time, lastSetAt *time.Time) time.Time {
+	if lastSetAt != nil && lastSetAt.After(startedAt) {
+		return *lastSetAt
+	}
+	return startedAt
+}
@@ -0,0 +1,265 @@
+// Package worker は Redis Streams に積まれた LINE イベントを処理するワーカープール。
+package worker
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	repositoryLine "github.com/sirasu21/Logbook/backend/repository/LINE"
+)
+
+// Handler は 1 イベント分の処理。エラーを返すと ACK せずに残し、バックオフ後に引き取り直す
+// （そのユーザーの後続イベントは、やり直しが片付くまで待たせる）
+type Handler func(ctx context.Context, payload []byte) error
+
+type Options struct {
+	Concurrency int           // シャード数（= 同時に処理するユーザー数の上限）
+	MaxAttempts int           // これだけ失敗したら dead letter
+	BaseBackoff time.Duration // 1 回目のリトライ待ち。以降 2 倍ずつ
+	ClaimIdle   time.Duration // 他のワーカーがこれ以上 ACK していないメッセージは落ちたとみなして引き取る
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Concurrency: 8,
+		MaxAttempts: 5,
+		BaseBackoff: 2 * time.Second,
+		ClaimIdle:   time.Minute,
+	}
+}
+
+// 新しいメッセージを待つ時間（リトライの確認もこの間隔になる）
+const readBlock = 2 * time.Second
+
+// 1 回に見る ACK 待ちメッセージの数
+const pendingScan = 1000
+
+// OptionsFromEnv は LINE_WORKER_CONCURRENCY / LINE_WORKER_MAX_ATTEMPTS で既定値を上書きする
+func OptionsFromEnv() Options {
+	opts := DefaultOptions()
+	if n, err := strconv.Atoi(os.Getenv("LINE_WORKER_CONCURRENCY")); err == nil && n > 0 {
+		opts.Concurrency = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("LINE_WORKER_MAX_ATTEMPTS")); err == nil && n > 0 {
+		opts.MaxAttempts = n
+	}
+	return opts
+}
+
+type Pool struct {
+	queue    repositoryLine.LineQueueRepository
+	handle   Handler
+	opts     Options
+	consumer string
+
+	mu       sync.Mutex
+	inflight map[string]struct{} // シャードに渡してから処理し終えるまでのメッセージID
+}
+
+func NewPool(queue repositoryLine.LineQueueRepository, handle Handler, opts Options) *Pool {
+	host, _ := os.Hostname()
+	return &Pool{
+		queue:    queue,
+		handle:   handle,
+		opts:     opts,
+		consumer: host + "-" + strconv.Itoa(os.Getpid()),
+		inflight: map[string]struct{}{},
+	}
+}
+
+// Run は ctx がキャンセルされるまでイベントを読み続ける。
+// 同じ UserID のイベントは必ず同じシャードに入るので、ユーザー単位で順番に処理される。
+// 失敗したイベントはシャードを止めずにバックオフ後にやり直し、その間に届いた同じユーザーのイベントは
+// 後ろに並べて待たせる（ほかのユーザーのイベントは待たせない）。
+func (p *Pool) Run(ctx context.Context) error {
+	if err := p.queue.EnsureGroup(ctx); err != nil {
+		return err
+	}
+
+	shards := make([]chan repositoryLine.QueuedEvent, p.opts.Concurrency)
+	var wg sync.WaitGroup
+	for i := range shards {
+		shards[i] = make(chan repositoryLine.QueuedEvent, 16)
+		wg.Add(1)
+		go func(ch <-chan repositoryLine.QueuedEvent) {
+			defer wg.Done()
+			sh := newShard()
+			for ev := range ch {
+				p.dispatch(ctx, sh, ev)
+			}
+		}(shards[i])
+	}
+	defer func() {
+		for _, ch := range shards {
+			close(ch)
+		}
+		wg.Wait()
+	}()
+
+	log.Printf("LINE worker started / consumer=%s / concurrency=%d", p.consumer, p.opts.Concurrency)
+	lastRetry := time.Time{}
+	for ctx.Err() == nil {
+		var events []repositoryLine.QueuedEvent
+
+		if time.Since(lastRetry) >= p.opts.BaseBackoff {
+			retries, err := p.claimRetries(ctx)
+			if err != nil {
+				log.Printf("❌ LINE worker: claim retries failed / err=%v", err)
+			}
+			events = append(events, retries...)
+			lastRetry = time.Now()
+		}
+
+		fresh, err := p.queue.Read(ctx, p.consumer, 50, readBlock)
+		if err != nil {
+			log.Printf("❌ LINE worker: read failed / err=%v", err)
+			sleep(ctx, time.Second)
+		}
+		events = append(events, fresh...)
+
+		for _, ev := range events {
+			p.track(ev.ID)
+			select {
+			case shards[shardOf(ev.UserID, len(shards))] <- ev:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// shard は 1 つのシャードのユーザーごとの待ち行列（シャードの goroutine だけが触る）
+type shard struct {
+	// やり直し待ちのイベントがあるユーザー → そのイベントID
+	blocked map[string]string
+	// blocked のユーザーに届いた後続のイベント（届いた順）。ACK していないので処理中として扱う
+	parked map[string][]repositoryLine.QueuedEvent
+}
+
+func newShard() *shard {
+	return &shard{blocked: map[string]string{}, parked: map[string][]repositoryLine.QueuedEvent{}}
+}
+
+// dispatch は ev を処理する。同じユーザーの前のイベントがやり直し待ちなら、ev はその後ろに並べておき、
+// 前のイベントが片付いた（成功したか dead letter に移した）ら並んでいたものを順に処理する
+func (p *Pool) dispatch(ctx context.Context, sh *shard, ev repositoryLine.QueuedEvent) {
+	if id, ok := sh.blocked[ev.UserID]; ok && id != ev.ID {
+		sh.parked[ev.UserID] = append(sh.parked[ev.UserID], ev)
+		return
+	}
+	queue := append([]repositoryLine.QueuedEvent{ev}, sh.parked[ev.UserID]...)
+	delete(sh.blocked, ev.UserID)
+	delete(sh.parked, ev.UserID)
+	for i, e := range queue {
+		if !p.process(ctx, e) {
+			sh.blocked[e.UserID] = e.ID
+			if rest := queue[i+1:]; len(rest) > 0 {
+				sh.parked[e.UserID] = rest
+			}
+			return
+		}
+	}
+}
+
+// claimRetries はリトライの時期が来た ACK 待ちメッセージを引き取る。
+// このプールで処理中のものは触らず、他のワーカーのものは ClaimIdle を過ぎてから（落ちたとみなして）引き取る
+func (p *Pool) claimRetries(ctx context.Context) ([]repositoryLine.QueuedEvent, error) {
+	pending, err := p.queue.Pending(ctx, pendingScan)
+	if err != nil {
+		return nil, err
+	}
+	var due []repositoryLine.PendingEvent
+	for _, pe := range pending {
+		wait := p.backoff(pe.Deliveries)
+		if pe.Consumer != p.consumer && wait < p.opts.ClaimIdle {
+			wait = p.opts.ClaimIdle
+		}
+		if pe.Idle < wait || p.isInflight(pe.ID) {
+			continue
+		}
+		due = append(due, pe)
+	}
+	return p.queue.Claim(ctx, p.consumer, p.opts.BaseBackoff, due)
+}
+
+// backoff は deliveries 回配られたメッセージを次に処理するまでの待ち
+func (p *Pool) backoff(deliveries int64) time.Duration {
+	if deliveries < 1 {
+		deliveries = 1
+	}
+	if deliveries > 16 {
+		deliveries = 16
+	}
+	return p.opts.BaseBackoff << (deliveries - 1)
+}
+
+// process は 1 イベントを 1 回だけ処理する。成功したら ACK し、失敗したら ACK せずに残す
+// （claimRetries がバックオフ後に引き取り直す）。MaxAttempts 回目も失敗したら dead letter に移して ACK する。
+// 片付いた（ACK した）かを返す
+func (p *Pool) process(ctx context.Context, ev repositoryLine.QueuedEvent) bool {
+	defer p.untrack(ev.ID)
+
+	if err := p.handle(ctx, ev.Payload); err != nil {
+		if ctx.Err() != nil {
+			// シャットダウン中は ACK せず、次に起動したワーカーに引き取らせる
+			return false
+		}
+		if ev.Attempts < int64(p.opts.MaxAttempts) {
+			log.Printf("LINE worker: attempt %d/%d failed, retry later / id=%s / err=%v", ev.Attempts, p.opts.MaxAttempts, ev.ID, err)
+			return false
+		}
+		log.Printf("❌ LINE worker: attempt %d/%d failed, dead letter / id=%s / err=%v", ev.Attempts, p.opts.MaxAttempts, ev.ID, err)
+		if dlErr := p.queue.DeadLetter(ctx, ev, err.Error()); dlErr != nil {
+			log.Printf("❌ LINE worker: dead letter failed / id=%s / err=%v", ev.ID, dlErr)
+			return false
+		}
+	}
+	if err := p.queue.Ack(ctx, ev.ID); err != nil {
+		// 処理は済んでいるので後続は待たせない（引き取り直されても Handler 側で重複を弾く）
+		log.Printf("❌ LINE worker: ack failed / id=%s / err=%v", ev.ID, err)
+	}
+	return true
+}
+
+func (p *Pool) track(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inflight[id] = struct{}{}
+}
+
+func (p *Pool) untrack(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.inflight, id)
+}
+
+func (p *Pool) isInflight(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.inflight[id]
+	return ok
+}
+
+func shardOf(key string, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// sleep は d 待つ。途中で ctx が終わったら false
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,187 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	repositoryLine "github.com/sirasu21/Logbook/backend/repository/LINE"
+)
+
+// fakeQueue は ACK・dead letter・引き取りを記録するだけのキュー
+type fakeQueue struct {
+	pending []repositoryLine.PendingEvent
+	claimed []string
+	acked   []string
+	dead    []string
+}
+
+func (q *fakeQueue) Enqueue(ctx context.Context, userID string, payload []byte) error { return nil }
+func (q *fakeQueue) EnsureGroup(ctx context.Context) error                            { return nil }
+func (q *fakeQueue) Read(ctx context.Context, consumer string, count int64, block time.Duration) ([]repositoryLine.QueuedEvent, error) {
+	return nil, nil
+}
+func (q *fakeQueue) Pending(ctx context.Context, count int64) ([]repositoryLine.PendingEvent, error) {
+	return q.pending, nil
+}
+func (q *fakeQueue) Claim(ctx context.Context, consumer string, minIdle time.Duration, pending []repositoryLine.PendingEvent) ([]repositoryLine.QueuedEvent, error) {
+	var out []repositoryLine.QueuedEvent
+	for _, p := range pending {
+		q.claimed = append(q.claimed, p.ID)
+		out = append(out, repositoryLine.QueuedEvent{ID: p.ID, Attempts: p.Deliveries + 1})
+	}
+	return out, nil
+}
+func (q *fakeQueue) Ack(ctx context.Context, id string) error {
+	q.acked = append(q.acked, id)
+	return nil
+}
+func (q *fakeQueue) DeadLetter(ctx context.Context, ev repositoryLine.QueuedEvent, reason string) error {
+	q.dead = append(q.dead, ev.ID)
+	return nil
+}
+
+func newTestPool(q *fakeQueue, handle Handler) *Pool {
+	p := NewPool(q, handle, DefaultOptions())
+	p.consumer = "me"
+	return p
+}
+
+func TestProcess(t *testing.T) {
+	boom := errors.New("boom")
+	cases := []struct {
+		name      string
+		attempts  int64
+		err       error
+		wantAcked bool
+		wantDead  bool
+	}{
+		{name: "success is acked", attempts: 1, wantAcked: true},
+		{name: "failure is left pending for retry", attempts: 1, err: boom},
+		{name: "last attempt goes to dead letter", attempts: 5, err: boom, wantAcked: true, wantDead: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			q := &fakeQueue{}
+			calls := 0
+			p := newTestPool(q, func(context.Context, []byte) error { calls++; return tc.err })
+			p.track("1-0")
+
+			p.process(context.Background(), repositoryLine.QueuedEvent{ID: "1-0", Attempts: tc.attempts})
+
+			if calls != 1 {
+				t.Fatalf("handler calls = %d, want 1 (no inline retries)", calls)
+			}
+			if got := len(q.acked) == 1; got != tc.wantAcked {
+				t.Errorf("acked = %v, want %v", q.acked, tc.wantAcked)
+			}
+			if got := len(q.dead) == 1; got != tc.wantDead {
+				t.Errorf("dead = %v, want %v", q.dead, tc.wantDead)
+			}
+			if p.isInflight("1-0") {
+				t.Errorf("event is still tracked as in flight")
+			}
+		})
+	}
+}
+
+func TestClaimRetries(t *testing.T) {
+	q := &fakeQueue{pending: []repositoryLine.PendingEvent{
+		{ID: "1-0", Consumer: "me", Idle: 3 * time.Second, Deliveries: 1},     // 失敗して 1 回目の待ちが過ぎた
+		{ID: "2-0", Consumer: "me", Idle: 3 * time.Second, Deliveries: 2},     // 2 回目の待ち（4 秒）がまだ
+		{ID: "3-0", Consumer: "me", Idle: time.Hour, Deliveries: 1},           // このプールで処理中
+		{ID: "4-0", Consumer: "other", Idle: 10 * time.Second, Deliveries: 1}, // 他のワーカーが処理中かもしれない
+		{ID: "5-0", Consumer: "other", Idle: 2 * time.Minute, Deliveries: 1},  // 他のワーカーが落ちた
+	}}
+	p := newTestPool(q, nil)
+	p.track("3-0")
+
+	events, err := p.claimRetries(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1-0", "5-0"}
+	if len(events) != len(want) {
+		t.Fatalf("claimed = %v, want %v", q.claimed, want)
+	}
+	for i, ev := range events {
+		if ev.ID != want[i] {
+			t.Errorf("claimed[%d] = %s, want %s", i, ev.ID, want[i])
+		}
+		if ev.Attempts != 2 {
+			t.Errorf("attempts of %s = %d, want 2", ev.ID, ev.Attempts)
+		}
+	}
+}
+
+func TestDispatchKeepsUserOrderAcrossRetry(t *testing.T) {
+	q := &fakeQueue{}
+	var handled []string
+	failed := false
+	p := newTestPool(q, func(_ context.Context, payload []byte) error {
+		// 「開始」（1-0）は 1 回目だけ失敗する
+		if string(payload) == "1-0" && !failed {
+			failed = true
+			return errors.New("db down")
+		}
+		handled = append(handled, string(payload))
+		return nil
+	})
+	sh := newShard()
+	ev := func(id, user string, attempts int64) repositoryLine.QueuedEvent {
+		p.track(id)
+		return repositoryLine.QueuedEvent{ID: id, UserID: user, Payload: []byte(id), Attempts: attempts}
+	}
+
+	p.dispatch(context.Background(), sh, ev("1-0", "alice", 1))
+	p.dispatch(context.Background(), sh, ev("2-0", "alice", 1)) // 1-0 のやり直しを待つ
+	p.dispatch(context.Background(), sh, ev("3-0", "bob", 1))   // 別のユーザーは待たない
+
+	if want := []string{"3-0"}; !slices.Equal(handled, want) {
+		t.Fatalf("handled before retry = %v, want %v", handled, want)
+	}
+	if !p.isInflight("2-0") {
+		t.Error("parked event is not tracked as in flight (claimRetries could pick it up)")
+	}
+
+	// バックオフ後に claimRetries が 1-0 を引き取り直す
+	p.dispatch(context.Background(), sh, ev("1-0", "alice", 2))
+
+	if want := []string{"3-0", "1-0", "2-0"}; !slices.Equal(handled, want) {
+		t.Errorf("handled = %v, want %v", handled, want)
+	}
+	if want := []string{"3-0", "1-0", "2-0"}; !slices.Equal(q.acked, want) {
+		t.Errorf("acked = %v, want %v", q.acked, want)
+	}
+	if len(sh.blocked) != 0 || len(sh.parked) != 0 {
+		t.Errorf("shard still holds blocked = %v, parked = %v", sh.blocked, sh.parked)
+	}
+}
+
+func TestDispatchReleasesParkedAfterDeadLetter(t *testing.T) {
+	q := &fakeQueue{}
+	p := newTestPool(q, func(_ context.Context, payload []byte) error {
+		if string(payload) == "1-0" {
+			return errors.New("bad payload")
+		}
+		return nil
+	})
+	sh := newShard()
+	last := int64(p.opts.MaxAttempts)
+
+	p.dispatch(context.Background(), sh, repositoryLine.QueuedEvent{ID: "1-0", UserID: "alice", Payload: []byte("1-0"), Attempts: last - 1})
+	p.dispatch(context.Background(), sh, repositoryLine.QueuedEvent{ID: "2-0", UserID: "alice", Payload: []byte("2-0"), Attempts: 1})
+	if len(q.acked) != 0 {
+		t.Fatalf("acked = %v before the failed event was settled", q.acked)
+	}
+	p.dispatch(context.Background(), sh, repositoryLine.QueuedEvent{ID: "1-0", UserID: "alice", Payload: []byte("1-0"), Attempts: last})
+
+	if want := []string{"1-0"}; !slices.Equal(q.dead, want) {
+		t.Errorf("dead = %v, want %v", q.dead, want)
+	}
+	if want := []string{"1-0", "2-0"}; !slices.Equal(q.acked, want) {
+		t.Errorf("acked = %v, want %v", q.acked, want)
+	}
+}